@@ -25,6 +25,8 @@
 // nextCrawl zset: package id, Unix time for next crawl
 // newCrawl set: new paths to crawl
 // badCrawl set: paths that returned error when crawling.
+// changes:<path> list: gob encoded doc.APIDiff, most recent first
+// source:<path> hash: file name to snappy compressed gob encoded doc.SourceFile
 
 // Package database manages storage for GoPkgDoc.
 package database
@@ -70,13 +72,15 @@ type Database struct {
 // for the HTML template. It implements the search.FieldLoadSaver interface
 // to customize the Rank function in the search index.
 type Package struct {
-	Name        string  `json:"name,omitempty"`
-	Path        string  `json:"path"`
-	ImportCount int     `json:"import_count"`
-	Synopsis    string  `json:"synopsis,omitempty"`
-	Fork        bool    `json:"fork,omitempty"`
-	Stars       int     `json:"stars,omitempty"`
-	Score       float64 `json:"score,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Path        string   `json:"path"`
+	ImportCount int      `json:"import_count"`
+	Synopsis    string   `json:"synopsis,omitempty"`
+	Fork        bool     `json:"fork,omitempty"`
+	Stars       int      `json:"stars,omitempty"`
+	Score       float64  `json:"score,omitempty"`
+	Licenses    []string `json:"licenses,omitempty"` // SPDX identifiers
+	Deprecated  bool     `json:"deprecated,omitempty"`
 }
 
 type byPath []Package
@@ -253,6 +257,14 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 	}
 	terms := documentTerms(pdoc, score)
 
+	// The sources are stored separately from the package.
+	sources := pdoc.Sources
+	if sources != nil {
+		pdocNew := *pdoc
+		pdoc = &pdocNew
+		pdoc.Sources = nil
+	}
+
 	var gobBuf bytes.Buffer
 	if err := gob.NewEncoder(&gobBuf).Encode(pdoc); err != nil {
 		return err
@@ -302,6 +314,23 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 		return err
 	}
 
+	if err := putSources(c, pdoc.ImportPath, sources); err != nil {
+		return err
+	}
+
+	// The API is compared only when the source changed. Rebuilding a package
+	// for a new doc.PackageVersion changes only the etag prefix.
+	if old != nil && sourceEtag(old.Etag) != sourceEtag(pdoc.Etag) &&
+		old.Name != "" && pdoc.Name != "" &&
+		!old.Truncated && !pdoc.Truncated {
+		if changes := doc.DiffAPI(old, pdoc); len(changes) > 0 {
+			d := &doc.APIDiff{Updated: time.Now().UTC(), Etag: pdoc.Etag, Changes: changes}
+			if err := putAPIDiff(c, pdoc.ImportPath, d); err != nil {
+				return err
+			}
+		}
+	}
+
 	id, n, err := pkgIDAndImportCount(c, pdoc.ImportPath)
 	if err != nil {
 		return err
@@ -612,6 +641,150 @@ func (db *Database) GetDoc(ctx context.Context, path string) (*doc.Package, time
 	return db.getDoc(ctx, c, path)
 }
 
+// PutVersion adds the documentation for a specific version of a package to
+// the database. Versioned documentation is stored per (path, version) and is
+// not indexed for search.
+func (db *Database) PutVersion(ctx context.Context, pdoc *doc.Package) error {
+	if pdoc.Version == "" {
+		return errors.New("database: PutVersion called without a version")
+	}
+	c := db.Pool.Get()
+	defer c.Close()
+
+	// Sources are only stored for the default version of a package.
+	if pdoc.Sources != nil {
+		pdocNew := *pdoc
+		pdocNew.Sources = nil
+		pdocNew.Files = make([]*doc.File, len(pdoc.Files))
+		for i, f := range pdoc.Files {
+			fileNew := *f
+			fileNew.Source = false
+			pdocNew.Files[i] = &fileNew
+		}
+		pdoc = &pdocNew
+	}
+
+	var gobBuf bytes.Buffer
+	if err := gob.NewEncoder(&gobBuf).Encode(pdoc); err != nil {
+		return err
+	}
+	_, err := c.Do("HSET", "versions:"+pdoc.ImportPath, pdoc.Version, snappy.Encode(nil, gobBuf.Bytes()))
+	return err
+}
+
+// GetVersion gets the documentation for the given version of a package. If
+// the version is not in the database, GetVersion returns nil.
+func (db *Database) GetVersion(ctx context.Context, path, version string) (*doc.Package, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	p, err := redis.Bytes(c.Do("HGET", "versions:"+path, version))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p, err = snappy.Decode(nil, p)
+	if err != nil {
+		return nil, err
+	}
+	var pdoc doc.Package
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&pdoc); err != nil {
+		return nil, err
+	}
+	return &pdoc, nil
+}
+
+// maxAPIDiffs is the number of API diffs kept for a package.
+const maxAPIDiffs = 50
+
+// sourceEtag returns the part of a package etag which identifies the source
+// of the package, without the doc.PackageVersion prefix.
+func sourceEtag(etag string) string {
+	if i := strings.IndexByte(etag, '-'); i >= 0 {
+		return etag[i+1:]
+	}
+	return etag
+}
+
+func putAPIDiff(c redis.Conn, path string, d *doc.APIDiff) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(d); err != nil {
+		return err
+	}
+	if _, err := c.Do("LPUSH", "changes:"+path, buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := c.Do("LTRIM", "changes:"+path, 0, maxAPIDiffs-1)
+	return err
+}
+
+// APIDiffs returns the changes to the API of a package found by the recent
+// crawls, most recent first.
+func (db *Database) APIDiffs(path string) ([]*doc.APIDiff, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	values, err := redis.ByteSlices(c.Do("LRANGE", "changes:"+path, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	diffs := make([]*doc.APIDiff, 0, len(values))
+	for _, p := range values {
+		var d doc.APIDiff
+		if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&d); err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, &d)
+	}
+	return diffs, nil
+}
+
+// putSources replaces the sources of the package at path. The sources are
+// deleted if sources is empty.
+func putSources(c redis.Conn, path string, sources []*doc.SourceFile) error {
+	args := []interface{}{"source:" + path}
+	for _, f := range sources {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(f); err != nil {
+			return err
+		}
+		args = append(args, f.Name, snappy.Encode(nil, buf.Bytes()))
+	}
+	// Replace the sources in a transaction so that readers never see a
+	// partial set of files.
+	c.Send("MULTI")
+	c.Send("DEL", "source:"+path)
+	if len(sources) > 0 {
+		c.Send("HMSET", args...)
+	}
+	_, err := c.Do("EXEC")
+	return err
+}
+
+// GetSource returns the annotated source of the Go file name of the package
+// at path. If the file is not in the database, GetSource returns nil.
+func (db *Database) GetSource(path, name string) (*doc.SourceFile, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	p, err := redis.Bytes(c.Do("HGET", "source:"+path, name))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p, err = snappy.Decode(nil, p)
+	if err != nil {
+		return nil, err
+	}
+	var f doc.SourceFile
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
 var deleteScript = redis.NewScript(0, `
     local path = ARGV[1]
 
@@ -628,6 +801,9 @@ var deleteScript = redis.NewScript(0, `
     redis.call('SREM', 'newCrawl', path)
     redis.call('ZREM', 'popular', id)
     redis.call('DEL', 'pkg:' .. id)
+    redis.call('DEL', 'versions:' .. path)
+    redis.call('DEL', 'changes:' .. path)
+    redis.call('DEL', 'source:' .. path)
     return redis.call('HDEL', 'ids', path)
 `)
 
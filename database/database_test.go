@@ -193,6 +193,179 @@ func TestPutGet(t *testing.T) {
 	}
 }
 
+func TestPutGetVersion(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	pdoc := &doc.Package{
+		ImportPath: "github.com/user/repo",
+		Name:       "repo",
+		Synopsis:   "old",
+		Version:    "v1.0.0",
+	}
+	if err := db.PutVersion(ctx, pdoc); err != nil {
+		t.Fatalf("db.PutVersion() returned error %v", err)
+	}
+
+	got, err := db.GetVersion(ctx, "github.com/user/repo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("db.GetVersion() returned error %v", err)
+	}
+	if !cmp.Equal(got, pdoc) {
+		t.Errorf("db.GetVersion() = %+v, want %+v", got, pdoc)
+	}
+
+	got, err = db.GetVersion(ctx, "github.com/user/repo", "v2.0.0")
+	if got != nil || err != nil {
+		t.Errorf("db.GetVersion(missing version) = %v, %v, want nil, nil", got, err)
+	}
+
+	if err := db.PutVersion(ctx, &doc.Package{ImportPath: "github.com/user/repo"}); err == nil {
+		t.Error("db.PutVersion() without a version did not return an error")
+	}
+
+	// Sources are not stored with versions.
+	pdoc.Version = "v1.1.0"
+	pdoc.Files = []*doc.File{{Name: "repo.go", Source: true}}
+	pdoc.Sources = []*doc.SourceFile{{Name: "repo.go"}}
+	if err := db.PutVersion(ctx, pdoc); err != nil {
+		t.Fatalf("db.PutVersion() returned error %v", err)
+	}
+	if !pdoc.Files[0].Source || pdoc.Sources == nil {
+		t.Error("db.PutVersion() modified the package")
+	}
+	got, err = db.GetVersion(ctx, "github.com/user/repo", "v1.1.0")
+	if err != nil {
+		t.Fatalf("db.GetVersion() returned error %v", err)
+	}
+	if got.Sources != nil || len(got.Files) != 1 || got.Files[0].Source {
+		t.Errorf("db.GetVersion() returned sources %v and files %+v, want no sources", got.Sources, got.Files)
+	}
+}
+
+func TestPutAPIDiffs(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	pdoc := &doc.Package{
+		ImportPath: "github.com/user/repo",
+		Name:       "repo",
+		Etag:       "1",
+		Funcs:      []*doc.Func{{Name: "F", Decl: doc.Code{Text: "func F(s string)"}}},
+	}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	pdoc.Etag = "2"
+	pdoc.Funcs = []*doc.Func{{Name: "F", Decl: doc.Code{Text: "func F(s string, n int)"}}}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+
+	diffs, err := db.APIDiffs("github.com/user/repo")
+	if err != nil {
+		t.Fatalf("db.APIDiffs() returned error %v", err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("db.APIDiffs() returned %d diffs, want 1", len(diffs))
+	}
+	want := []doc.APIChange{{Kind: doc.ModifiedChange, Name: "F", Old: "func F(string)", New: "func F(string, int)"}}
+	if diffs[0].Etag != "2" || !cmp.Equal(diffs[0].Changes, want) {
+		t.Errorf("db.APIDiffs() = %+v, want changes %+v with etag 2", diffs[0], want)
+	}
+
+	// Rebuilding the same source for a new package version changes only the
+	// etag prefix and does not record a diff.
+	pdoc.Etag = doc.PackageVersion + "-2"
+	pdoc.Funcs = []*doc.Func{{Name: "F", Decl: doc.Code{Text: "func F(s string, n int) error"}}}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	if diffs, err := db.APIDiffs("github.com/user/repo"); err != nil || len(diffs) != 1 {
+		t.Errorf("db.APIDiffs() after new package version returned %d diffs, %v; want 1 diff", len(diffs), err)
+	}
+}
+
+func TestSourceEtag(t *testing.T) {
+	for _, tt := range []struct{ etag, want string }{
+		{"9-abc", "abc"},
+		{"8-v1.0.0-pre", "v1.0.0-pre"},
+		{"abc", "abc"},
+		{"", ""},
+	} {
+		if got := sourceEtag(tt.etag); got != tt.want {
+			t.Errorf("sourceEtag(%q) = %q, want %q", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestPutSources(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	src := &doc.SourceFile{Name: "repo.go", Code: doc.Code{Text: "package repo\n"}}
+	pdoc := &doc.Package{
+		ImportPath: "github.com/user/repo",
+		Name:       "repo",
+		Files:      []*doc.File{{Name: "repo.go", Source: true}},
+		Sources:    []*doc.SourceFile{src},
+	}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+
+	stored, _, err := db.GetDoc(ctx, "github.com/user/repo")
+	if err != nil {
+		t.Fatalf("db.GetDoc() returned error %v", err)
+	}
+	if stored.Sources != nil || !stored.Files[0].Source {
+		t.Errorf("stored package has sources %v and file source %v, want nil and true", stored.Sources, stored.Files[0].Source)
+	}
+	got, err := db.GetSource("github.com/user/repo", "repo.go")
+	if err != nil {
+		t.Fatalf("db.GetSource() returned error %v", err)
+	}
+	if !cmp.Equal(got, src) {
+		t.Errorf("db.GetSource() = %+v, want %+v", got, src)
+	}
+
+	if got, err := db.GetSource("github.com/user/repo", "other.go"); err != nil || got != nil {
+		t.Errorf("db.GetSource(other.go) = %v, %v, want nil, nil", got, err)
+	}
+
+	// Storing new sources replaces the stored sources.
+	other := &doc.SourceFile{Name: "other.go", Code: doc.Code{Text: "package repo\n"}}
+	pdoc.Files = []*doc.File{{Name: "other.go", Source: true}}
+	pdoc.Sources = []*doc.SourceFile{other}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	if got, err := db.GetSource("github.com/user/repo", "repo.go"); err != nil || got != nil {
+		t.Errorf("db.GetSource(repo.go) = %v, %v after replacing sources, want nil, nil", got, err)
+	}
+	if got, err := db.GetSource("github.com/user/repo", "other.go"); err != nil || !cmp.Equal(got, other) {
+		t.Errorf("db.GetSource(other.go) = %+v, %v, want %+v, nil", got, err, other)
+	}
+
+	// Storing a package without sources deletes the stored sources.
+	pdoc.Files[0].Source = false
+	pdoc.Sources = nil
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	if got, err := db.GetSource("github.com/user/repo", "other.go"); err != nil || got != nil {
+		t.Errorf("db.GetSource(other.go) = %v, %v after storing package without sources, want nil, nil", got, err)
+	}
+	c := db.Pool.Get()
+	defer c.Close()
+	if n, err := redis.Int(c.Do("EXISTS", "source:github.com/user/repo")); err != nil || n != 0 {
+		t.Errorf("EXISTS source:github.com/user/repo = %d, %v, want 0, nil", n, err)
+	}
+}
+
 const epsilon = 0.000001
 
 func TestPopular(t *testing.T) {
@@ -200,6 +200,10 @@ func documentScore(pdoc *doc.Package) float64 {
 			// Penalty for no documentation.
 			r *= 0.95
 		}
+		if pdoc.Deprecated != "" {
+			// Penalty for deprecated packages.
+			r *= 0.5
+		}
 		if path.Base(pdoc.ImportPath) != pdoc.Name {
 			// Penalty for last element of path != package name.
 			r *= 0.9
@@ -100,6 +100,21 @@ func TestDocTerms(t *testing.T) {
 	}
 }
 
+func TestDeprecatedScore(t *testing.T) {
+	pdoc := &doc.Package{
+		ImportPath:  "github.com/user/repo/dir",
+		ProjectRoot: "github.com/user/repo",
+		Name:        "dir",
+		Doc:         "Package dir does things.",
+		Funcs:       []*doc.Func{{}},
+	}
+	score := documentScore(pdoc)
+	pdoc.Deprecated = "Deprecated: Use github.com/user/repo/dir2."
+	if s := documentScore(pdoc); s <= 0 || s >= score {
+		t.Errorf("documentScore(deprecated) = %v, want less than %v", s, score)
+	}
+}
+
 var vendorPatTests = []struct {
 	path  string
 	match bool
@@ -19,6 +19,7 @@ import (
 	"google.golang.org/appengine/search"
 
 	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
 )
 
 func (p *Package) Load(fields []search.Field, meta *search.DocumentMetadata) error {
@@ -48,14 +49,21 @@ func (p *Package) Load(fields []search.Field, meta *search.DocumentMetadata) err
 			if v, ok := f.Value.(float64); ok {
 				p.Score = v
 			}
+		case "Licenses":
+			if v, ok := f.Value.(search.Atom); ok {
+				p.Licenses = strings.Fields(string(v))
+			}
 		}
 	}
 	if p.Path == "" {
 		return errors.New("Invalid document: missing Path field")
 	}
 	for _, f := range meta.Facets {
-		if f.Name == "Fork" {
+		switch f.Name {
+		case "Fork":
 			p.Fork = f.Value.(search.Atom) == "true"
+		case "Deprecated":
+			p.Deprecated = f.Value.(search.Atom) == "true"
 		}
 	}
 	return nil
@@ -69,6 +77,7 @@ func (p *Package) Save() ([]search.Field, *search.DocumentMetadata, error) {
 		{Name: "Score", Value: p.Score},
 		{Name: "ImportCount", Value: float64(p.ImportCount)},
 		{Name: "Stars", Value: float64(p.Stars)},
+		{Name: "Licenses", Value: search.Atom(strings.Join(p.Licenses, " "))},
 	}
 	fork := fmt.Sprint(p.Fork) // "true" or "false"
 	meta := &search.DocumentMetadata{
@@ -79,6 +88,7 @@ func (p *Package) Save() ([]search.Field, *search.DocumentMetadata, error) {
 		Rank: int(math.Max(1, 1000*p.Score*math.Log(math.E+float64(p.ImportCount)))),
 		Facets: []search.Facet{
 			{Name: "Fork", Value: search.Atom(fork)},
+			{Name: "Deprecated", Value: search.Atom(fmt.Sprint(p.Deprecated))},
 		},
 	}
 	return fields, meta, nil
@@ -115,6 +125,8 @@ func putIndex(c context.Context, pdoc *doc.Package, id string, score float64, im
 		pkg.Synopsis = pdoc.Synopsis
 		pkg.Stars = pdoc.Stars
 		pkg.Fork = pdoc.Fork
+		pkg.Licenses = gosrc.LicenseTypes(pdoc.Licenses)
+		pkg.Deprecated = pdoc.Deprecated != ""
 	}
 	if score >= 0 {
 		pkg.Score = score
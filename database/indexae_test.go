@@ -8,6 +8,7 @@ package database
 
 import (
 	"math"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -15,6 +16,7 @@ import (
 	"google.golang.org/appengine/search"
 
 	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
 )
 
 var pdoc = &doc.Package{
@@ -23,6 +25,8 @@ var pdoc = &doc.Package{
 	Synopsis:   "This is a test package.",
 	Fork:       true,
 	Stars:      10,
+	Licenses:   []*gosrc.License{{Path: "LICENSE", Types: []string{"MIT"}}},
+	Deprecated: "Deprecated: Use github.com/golang/test2.",
 }
 
 func TestPutIndexWithEmptyId(t *testing.T) {
@@ -78,8 +82,10 @@ func TestPutIndexNewPackageAndUpdate(t *testing.T) {
 		Fork:        true,
 		Stars:       10,
 		Score:       0.99,
+		Licenses:    []string{"MIT"},
+		Deprecated:  true,
 	}
-	if got != wanted {
+	if !reflect.DeepEqual(got, wanted) {
 		t.Errorf("PutIndex got %v, want %v", got, wanted)
 	}
 
@@ -91,7 +97,7 @@ func TestPutIndexNewPackageAndUpdate(t *testing.T) {
 		t.Fatal(err)
 	}
 	wanted.ImportCount = 2
-	if got != wanted {
+	if !reflect.DeepEqual(got, wanted) {
 		t.Errorf("PutIndex got %v, want %v", got, wanted)
 	}
 }
@@ -0,0 +1,132 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// The analyzers below are run with the analyzers of golang.org/x/tools.
+
+var builtinsAnalyzer = &analysis.Analyzer{
+	Name: "builtins",
+	Doc:  "check for declarations which shadow predeclared identifiers",
+	Run:  runBuiltins,
+}
+
+func runBuiltins(pass *analysis.Pass) (interface{}, error) {
+	if pass.Pkg.Path() == "builtin" {
+		return nil, nil
+	}
+	var ids []*ast.Ident
+	for id, obj := range pass.TypesInfo.Defs {
+		switch obj := obj.(type) {
+		case nil, *types.Label, *types.PkgName:
+			continue
+		case *types.Var:
+			if obj.IsField() {
+				continue
+			}
+		case *types.Func:
+			if obj.Type().(*types.Signature).Recv() != nil {
+				continue
+			}
+		}
+		if types.Universe.Lookup(id.Name) != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Pos() < ids[j].Pos() })
+	for _, id := range ids {
+		pass.Reportf(id.Pos(), "declaration of %q shadows predeclared identifier", id.Name)
+	}
+	return nil, nil
+}
+
+var importPathAnalyzer = &analysis.Analyzer{
+	Name: "importpath",
+	Doc:  "check for import paths which cannot be fetched",
+	Run:  runImportPath,
+}
+
+func runImportPath(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		for _, is := range file.Imports {
+			importPath, _ := strconv.Unquote(is.Path.Value)
+			if !gosrc.IsValidPath(importPath) &&
+				!strings.HasPrefix(importPath, "exp/") &&
+				!strings.HasPrefix(importPath, "appengine") {
+				pass.Reportf(is.Pos(), "unrecognized import path %q", importPath)
+			}
+		}
+	}
+	return nil, nil
+}
+
+var go1Analyzer = &analysis.Analyzer{
+	Name: "go1",
+	Doc:  "check for uses of standard library declarations removed by Go 1",
+	Run:  runGo1,
+}
+
+// This list of deprecated exports is used to find code that has not been
+// updated for Go 1.
+var deprecatedExports = map[string][]string{
+	"bytes":         {"Add"},
+	"crypto/aes":    {"Cipher"},
+	"crypto/hmac":   {"NewSHA1", "NewSHA256"},
+	"crypto/rand":   {"Seed"},
+	"encoding/json": {"MarshalForHTML"},
+	"encoding/xml":  {"Marshaler", "NewParser", "Parser"},
+	"html":          {"NewTokenizer", "Parse"},
+	"image":         {"Color", "NRGBAColor", "RGBAColor"},
+	"io":            {"Copyn"},
+	"log":           {"Exitf"},
+	"math":          {"Fabs", "Fmax", "Fmod"},
+	"os":            {"Envs", "Error", "Getenverror", "NewError", "Time", "UnixSignal", "Wait"},
+	"reflect":       {"MapValue", "Typeof"},
+	"runtime":       {"UpdateMemStats"},
+	"strconv":       {"Atob", "Atof32", "Atof64", "AtofN", "Atoi64", "Atoui", "Atoui64", "Btoui64", "Ftoa64", "Itoa64", "Uitoa", "Uitoa64"},
+	"time":          {"LocalTime", "Nanoseconds", "NanosecondsToLocalTime", "Seconds", "SecondsToLocalTime", "SecondsToUTC"},
+	"unicode/utf8":  {"NewString"},
+}
+
+func runGo1(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			pkgName, ok := pass.TypesInfo.Uses[x].(*types.PkgName)
+			if !ok {
+				return true
+			}
+			importPath := pkgName.Imported().Path()
+			for _, export := range deprecatedExports[importPath] {
+				if export == sel.Sel.Name {
+					pass.Reportf(sel.Pos(), "%q.%s not found", importPath, export)
+					return false
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,354 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/printf"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// Diagnostic is a possible problem found in the source of a package by an
+// analysis.
+type Diagnostic struct {
+	Pos      Pos
+	Analysis string // name of the analyzer
+	Message  string
+}
+
+// allAnalyzers are the analyzers which can be run on a package.
+var allAnalyzers = []*analysis.Analyzer{
+	printf.Analyzer,
+	composite.Analyzer,
+	builtinsAnalyzer,
+	buildtag.Analyzer,
+	importPathAnalyzer,
+	go1Analyzer,
+}
+
+// analyzers are the analyzers run when building package documents.
+var analyzers = allAnalyzers
+
+// AnalysisNames returns the names of the analyses which can be run on
+// packages.
+func AnalysisNames() []string {
+	names := make([]string, len(allAnalyzers))
+	for i, a := range allAnalyzers {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// SetAnalyses sets the analyses run when building package documents by
+// name. See AnalysisNames for the supported analyses.
+func SetAnalyses(names ...string) error {
+	var as []*analysis.Analyzer
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("duplicate analysis %q", name)
+		}
+		seen[name] = true
+		var found *analysis.Analyzer
+		for _, a := range allAnalyzers {
+			if a.Name == name {
+				found = a
+				break
+			}
+		}
+		if found == nil {
+			return fmt.Errorf("unknown analysis %q", name)
+		}
+		as = append(as, found)
+	}
+	analyzers = as
+	return nil
+}
+
+// analyzePackage type-checks the files of apkg and runs the analyzers on
+// them. The findings are recorded as diagnostics of pkg. Only the packages
+// of the standard library are imported, so the uses of other packages are
+// not type-checked and the analyzers skip them.
+func (b *builder) analyzePackage(pkg *Package, apkg *ast.Package) {
+	if len(analyzers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(apkg.Files))
+	for name := range apkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]*ast.File, len(names))
+	for i, name := range names {
+		files[i] = apkg.Files[name]
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{
+		Importer:    analysisImporter{},
+		FakeImportC: true,
+		Error:       func(error) {},
+		Sizes:       types.SizesFor("gc", "amd64"),
+	}
+	tpkg, _ := conf.Check(pkg.ImportPath, b.fset, files, info)
+
+	r := &analysisRun{
+		pass: analysis.Pass{
+			Fset:       b.fset,
+			Files:      files,
+			Pkg:        tpkg,
+			TypesInfo:  info,
+			TypesSizes: conf.Sizes,
+			ReadFile: func(name string) ([]byte, error) {
+				if src, ok := b.srcs[name]; ok {
+					return src.data, nil
+				}
+				return nil, os.ErrNotExist
+			},
+		},
+		results: make(map[*analysis.Analyzer]*analysisResult),
+		facts:   make(map[analysisFactKey]analysis.Fact),
+	}
+	for _, a := range analyzers {
+		if _, err := r.run(a); err != nil {
+			pkg.Diagnostics = append(pkg.Diagnostics, &Diagnostic{
+				Analysis: a.Name,
+				Message:  fmt.Sprintf("analysis failed: %v", err),
+			})
+		}
+	}
+	for _, d := range r.diagnostics {
+		pkg.Diagnostics = append(pkg.Diagnostics, &Diagnostic{
+			Pos:      b.position(posNode(d.Pos)),
+			Analysis: d.analyzer.Name,
+			Message:  d.Message,
+		})
+	}
+	sort.SliceStable(pkg.Diagnostics, func(i, j int) bool {
+		p, q := pkg.Diagnostics[i].Pos, pkg.Diagnostics[j].Pos
+		if p.File != q.File {
+			return p.File < q.File
+		}
+		return p.Line < q.Line
+	})
+}
+
+// analysisRun runs analyzers and the analyzers they require on a package.
+type analysisRun struct {
+	pass        analysis.Pass // common fields of the passes
+	results     map[*analysis.Analyzer]*analysisResult
+	facts       map[analysisFactKey]analysis.Fact
+	diagnostics []analysisDiagnostic
+}
+
+type analysisResult struct {
+	value interface{}
+	err   error
+}
+
+// analysisFactKey is the key of a fact about an object or a package. The
+// facts of the imported packages are not known.
+type analysisFactKey struct {
+	obj types.Object
+	pkg *types.Package
+	typ reflect.Type
+}
+
+type analysisDiagnostic struct {
+	analysis.Diagnostic
+	analyzer *analysis.Analyzer
+}
+
+func (r *analysisRun) run(a *analysis.Analyzer) (interface{}, error) {
+	if res, ok := r.results[a]; ok {
+		return res.value, res.err
+	}
+	res := &analysisResult{}
+	r.results[a] = res
+
+	pass := r.pass
+	pass.Analyzer = a
+	pass.ResultOf = make(map[*analysis.Analyzer]interface{})
+	for _, req := range a.Requires {
+		v, err := r.run(req)
+		if err != nil {
+			res.err = fmt.Errorf("%s: %v", req.Name, err)
+			return nil, res.err
+		}
+		pass.ResultOf[req] = v
+	}
+	pass.Report = func(d analysis.Diagnostic) {
+		r.diagnostics = append(r.diagnostics, analysisDiagnostic{d, a})
+	}
+	pass.ImportObjectFact = func(obj types.Object, fact analysis.Fact) bool {
+		return r.importFact(analysisFactKey{obj: obj, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ExportObjectFact = func(obj types.Object, fact analysis.Fact) {
+		r.facts[analysisFactKey{obj: obj, typ: reflect.TypeOf(fact)}] = fact
+	}
+	pass.ImportPackageFact = func(pkg *types.Package, fact analysis.Fact) bool {
+		return r.importFact(analysisFactKey{pkg: pkg, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ExportPackageFact = func(fact analysis.Fact) {
+		r.facts[analysisFactKey{pkg: pass.Pkg, typ: reflect.TypeOf(fact)}] = fact
+	}
+	pass.AllObjectFacts = func() []analysis.ObjectFact {
+		var facts []analysis.ObjectFact
+		for k, fact := range r.facts {
+			if k.obj != nil && hasFactType(a, k.typ) {
+				facts = append(facts, analysis.ObjectFact{Object: k.obj, Fact: fact})
+			}
+		}
+		return facts
+	}
+	pass.AllPackageFacts = func() []analysis.PackageFact {
+		var facts []analysis.PackageFact
+		for k, fact := range r.facts {
+			if k.pkg != nil && hasFactType(a, k.typ) {
+				facts = append(facts, analysis.PackageFact{Package: k.pkg, Fact: fact})
+			}
+		}
+		return facts
+	}
+	res.value, res.err = a.Run(&pass)
+	return res.value, res.err
+}
+
+// importFact copies the fact with key k to fact and returns true if the
+// fact is known.
+func (r *analysisRun) importFact(k analysisFactKey, fact analysis.Fact) bool {
+	f, ok := r.facts[k]
+	if ok {
+		reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(f).Elem())
+	}
+	return ok
+}
+
+func hasFactType(a *analysis.Analyzer, t reflect.Type) bool {
+	for _, f := range a.FactTypes {
+		if reflect.TypeOf(f) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// analysisImporter imports the packages of the standard library for the
+// analyzed packages.
+type analysisImporter struct{}
+
+func (analysisImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if !gosrc.IsGoRepoPath(path) {
+		return nil, errors.New("package not found")
+	}
+	stdPackages.mu.Lock()
+	defer stdPackages.mu.Unlock()
+	return stdPackages.importPackage(path, "")
+}
+
+// stdPackages are the packages of the standard library type-checked from
+// the source in GOROOT.
+var stdPackages = newStdImporter()
+
+// stdImporter type-checks the packages of the standard library without their
+// function bodies and cgo files. The packages are cached.
+type stdImporter struct {
+	ctxt build.Context
+	fset *token.FileSet
+
+	mu   sync.Mutex
+	pkgs map[string]*types.Package
+	errs map[string]error
+}
+
+func newStdImporter() *stdImporter {
+	ctxt := build.Default
+	ctxt.GOOS = "linux"
+	ctxt.GOARCH = "amd64"
+	ctxt.CgoEnabled = false
+	return &stdImporter{
+		ctxt: ctxt,
+		fset: token.NewFileSet(),
+		pkgs: make(map[string]*types.Package),
+		errs: make(map[string]error),
+	}
+}
+
+// importPackage imports the package with path from the directory srcDir.
+// The caller holds imp.mu.
+func (imp *stdImporter) importPackage(path, srcDir string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bpkg, err := imp.ctxt.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !bpkg.Goroot {
+		return nil, fmt.Errorf("package %s not in the standard library", path)
+	}
+	if tpkg, ok := imp.pkgs[bpkg.ImportPath]; ok {
+		return tpkg, imp.errs[bpkg.ImportPath]
+	}
+
+	var files []*ast.File
+	for _, name := range bpkg.GoFiles {
+		file, err := parser.ParseFile(imp.fset, filepath.Join(bpkg.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			imp.pkgs[bpkg.ImportPath], imp.errs[bpkg.ImportPath] = nil, err
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{
+		Importer:         stdImporterFrom{imp, bpkg.Dir},
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Error:            func(error) {},
+		Sizes:            types.SizesFor("gc", imp.ctxt.GOARCH),
+	}
+	tpkg, _ := conf.Check(bpkg.ImportPath, imp.fset, files, nil)
+	imp.pkgs[bpkg.ImportPath] = tpkg
+	return tpkg, nil
+}
+
+// stdImporterFrom imports the packages imported by the standard library
+// package in dir.
+type stdImporterFrom struct {
+	imp *stdImporter
+	dir string
+}
+
+func (i stdImporterFrom) Import(path string) (*types.Package, error) {
+	return i.imp.importPackage(path, i.dir)
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const analysisSrc = `// +build !plan9
+// Package p has problems.
+package p
+
+import (
+	"fmt"
+	"go/token"
+	"image"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"example.com/q"
+)
+
+// +build linux
+
+var (
+	_ = image.Point{1, 2}
+	_ = token.Position{"p.go", 0, 1, 1}
+	_ = token.Position{Filename: "p.go"}
+	_ = sort.StringSlice{"b", "a"}
+	_ = q.Values{"a", 1}
+)
+
+type T struct{ len int }
+
+func (T) string() {}
+
+func logf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func F(len int) error {
+	fmt.Printf("%d %s", 1)
+	fmt.Printf("%d%%", 1)
+	fmt.Printf("%[2]d %[1]d", 1, 2)
+	fmt.Printf("%*d", 3, 4)
+	fmt.Printf("%s", len)
+	fmt.Fprintf(os.Stdout, "%d\n", 1, 2)
+	fmt.Println("value %d", 1)
+	log.Println("done\n")
+	logf("%d", "x")
+	q.Printf("%d")
+	string := strconv.Atoi64
+	_ = string
+	return fmt.Errorf("100%")
+}
+`
+
+func TestAnalyzePackage(t *testing.T) {
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(analysisSrc)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Diagnostic
+	for _, d := range pkg.Diagnostics {
+		got = append(got, Diagnostic{Pos: Pos{Line: d.Pos.Line}, Analysis: d.Analysis, Message: d.Message})
+	}
+	want := []Diagnostic{
+		{Pos{Line: 1}, "buildtag", "misplaced +build comment"},
+		{Pos{Line: 17}, "buildtag", "misplaced +build comment"},
+		{Pos{Line: 21}, "composites", "go/token.Position struct literal uses unkeyed fields"},
+		{Pos{Line: 35}, "builtins", `declaration of "len" shadows predeclared identifier`},
+		{Pos{Line: 36}, "printf", "fmt.Printf format %s reads arg #2, but call has 1 arg"},
+		{Pos{Line: 40}, "printf", "fmt.Printf format %s has arg len of wrong type int"},
+		{Pos{Line: 41}, "printf", "fmt.Fprintf call needs 1 arg but has 2 args"},
+		{Pos{Line: 42}, "printf", "fmt.Println call has possible Printf formatting directive %d"},
+		{Pos{Line: 43}, "printf", "log.Println arg list ends with redundant newline"},
+		{Pos{Line: 44}, "printf", `example.com/p.logf format %d has arg "x" of wrong type string`},
+		{Pos{Line: 46}, "builtins", `declaration of "string" shadows predeclared identifier`},
+		{Pos{Line: 46}, "go1", `"strconv".Atoi64 not found`},
+		{Pos{Line: 48}, "printf", "fmt.Errorf format % is missing verb at end of string"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diagnostics:")
+		for _, d := range got {
+			t.Errorf("got  %+v", d)
+		}
+		for _, d := range want {
+			t.Errorf("want %+v", d)
+		}
+	}
+}
+
+func TestSetAnalyses(t *testing.T) {
+	defer func(as []*analysis.Analyzer) { analyzers = as }(analyzers)
+	if err := SetAnalyses("printf", "nosuch"); err == nil {
+		t.Error("SetAnalyses with unknown analysis returned nil error")
+	}
+	if err := SetAnalyses("printf", "printf"); err == nil {
+		t.Error("SetAnalyses with duplicate analysis returned nil error")
+	}
+	if err := SetAnalyses("printf"); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(analysisSrc)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range pkg.Diagnostics {
+		if d.Analysis != "printf" {
+			t.Errorf("diagnostic of disabled analysis: %+v", d)
+		}
+	}
+}
+
+func TestAnalyzers(t *testing.T) {
+	if err := analysis.Validate(allAnalyzers); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ChangeKind int16
+
+const (
+	// The declaration New was added.
+	AddedChange ChangeKind = iota
+
+	// The declaration Old was removed.
+	RemovedChange
+
+	// The declaration Old was changed to New.
+	ModifiedChange
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case AddedChange:
+		return "added"
+	case RemovedChange:
+		return "removed"
+	case ModifiedChange:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// APIChange is a change to an exported declaration of a package.
+type APIChange struct {
+	Kind ChangeKind
+
+	// Name is the name of the function, type, constant or variable, or
+	// Type.Name for a method, a struct field or an interface method.
+	Name string
+
+	// Old and New are the declaration before and after the change with the
+	// names of parameters and results removed.
+	Old, New string
+
+	// Compatible is true if code written against the old API continues to
+	// compile with the new API.
+	Compatible bool
+}
+
+// APIDiff is the list of changes to the API of a package found by a crawl.
+type APIDiff struct {
+	Updated time.Time
+	Etag    string
+	Changes []APIChange
+}
+
+// apiDecl is an exported declaration in the API of a package.
+type apiDecl struct {
+	text string
+
+	// inInterface is true for the methods and embedded types of an interface.
+	inInterface bool
+}
+
+// DiffAPI returns the changes to the exported API from old to new sorted by
+// name. Changes to the members of an added or removed type are not reported.
+func DiffAPI(old, new *Package) []APIChange {
+	oldAPI := packageAPI(old)
+	newAPI := packageAPI(new)
+
+	var changes []APIChange
+	for name, o := range oldAPI {
+		n, ok := newAPI[name]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Kind: RemovedChange, Name: name, Old: o.text})
+		case n.text != o.text:
+			changes = append(changes, APIChange{Kind: ModifiedChange, Name: name, Old: o.text, New: n.text})
+		}
+	}
+	for name, n := range newAPI {
+		if _, ok := oldAPI[name]; !ok {
+			// Adding a method to an interface breaks the implementations
+			// of the interface outside of the package.
+			changes = append(changes, APIChange{Kind: AddedChange, Name: name, New: n.text, Compatible: !n.inInterface})
+		}
+	}
+
+	// Drop the changes to the members of added and removed types.
+	typeChanged := make(map[string]bool)
+	for _, c := range changes {
+		if c.Kind != ModifiedChange && !strings.Contains(c.Name, ".") {
+			typeChanged[c.Name] = true
+		}
+	}
+	i := 0
+	for _, c := range changes {
+		if j := strings.Index(c.Name, "."); j >= 0 && typeChanged[c.Name[:j]] {
+			continue
+		}
+		changes[i] = c
+		i++
+	}
+	changes = changes[:i]
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// packageAPI returns the exported declarations of pkg by name.
+func packageAPI(pkg *Package) map[string]apiDecl {
+	api := make(map[string]apiDecl)
+	values := func(values []*Value) {
+		for _, v := range values {
+			addValueAPI(api, v.Decl.Text)
+		}
+	}
+	funcs := func(funcs []*Func) {
+		for _, f := range funcs {
+			addFuncAPI(api, f.Decl.Text)
+		}
+	}
+	values(pkg.Consts)
+	values(pkg.Vars)
+	funcs(pkg.Funcs)
+	for _, t := range pkg.Types {
+		addTypeAPI(api, t.Decl.Text)
+		values(t.Consts)
+		values(t.Vars)
+		funcs(t.Funcs)
+		funcs(t.Methods)
+	}
+	return api
+}
+
+// parseDecl parses the declaration text printed by printDecl.
+func parseDecl(text string) ast.Decl {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+text, 0)
+	if err != nil || len(f.Decls) == 0 {
+		return nil
+	}
+	return f.Decls[0]
+}
+
+func addValueAPI(api map[string]apiDecl, text string) {
+	d, ok := parseDecl(text).(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	// In a constant declaration, a spec without a type and values repeats
+	// the previous type and values.
+	var typ ast.Expr
+	var values []ast.Expr
+	for iota, spec := range d.Specs {
+		s, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if d.Tok == token.VAR || s.Type != nil || len(s.Values) > 0 {
+			typ, values = s.Type, s.Values
+		}
+		for i, name := range s.Names {
+			if !name.IsExported() {
+				continue
+			}
+			var buf bytes.Buffer
+			buf.WriteString(d.Tok.String() + " " + name.Name)
+			if typ != nil {
+				buf.WriteString(" " + types.ExprString(typ))
+			}
+			if d.Tok == token.CONST && i < len(values) {
+				v := types.ExprString(values[i])
+				buf.WriteString(" = " + v)
+				if usesIota(values[i]) {
+					buf.WriteString(" (iota = " + strconv.Itoa(iota) + ")")
+				}
+			}
+			api[name.Name] = apiDecl{text: buf.String()}
+		}
+	}
+}
+
+func usesIota(x ast.Expr) bool {
+	found := false
+	ast.Inspect(x, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func addFuncAPI(api map[string]apiDecl, text string) {
+	d, ok := parseDecl(text).(*ast.FuncDecl)
+	if !ok || !d.Name.IsExported() {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("func ")
+	name := d.Name.Name
+	if d.Recv != nil && len(d.Recv.List) > 0 {
+		recv := types.ExprString(d.Recv.List[0].Type)
+		buf.WriteString("(" + recv + ") ")
+		name = recvType(recv) + "." + name
+	}
+	buf.WriteString(d.Name.Name)
+	writeTypeParams(&buf, d.Type.TypeParams)
+	writeSignature(&buf, d.Type)
+	api[name] = apiDecl{text: buf.String()}
+}
+
+func addTypeAPI(api map[string]apiDecl, text string) {
+	d, ok := parseDecl(text).(*ast.GenDecl)
+	if !ok || len(d.Specs) == 0 {
+		return
+	}
+	s, ok := d.Specs[0].(*ast.TypeSpec)
+	if !ok || !s.Name.IsExported() {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("type " + s.Name.Name)
+	writeTypeParams(&buf, s.TypeParams)
+	if s.Assign.IsValid() {
+		buf.WriteString(" =")
+	}
+	buf.WriteString(" ")
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		// The fields are members of the type.
+		buf.WriteString("struct")
+		for _, f := range t.Fields.List {
+			typ := types.ExprString(f.Type)
+			names := f.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{embeddedName(f.Type)}
+			}
+			for _, n := range names {
+				if n != nil && n.IsExported() {
+					api[s.Name.Name+"."+n.Name] = apiDecl{text: "field " + s.Name.Name + "." + n.Name + " " + typ}
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		buf.WriteString("interface")
+		for _, f := range t.Methods.List {
+			if len(f.Names) == 0 {
+				// Embedded interface or type constraint.
+				typ := types.ExprString(f.Type)
+				api[s.Name.Name+"."+typ] = apiDecl{text: "embedded " + typ + " in " + s.Name.Name, inInterface: true}
+				continue
+			}
+			ft, ok := f.Type.(*ast.FuncType)
+			if !ok {
+				continue
+			}
+			for _, n := range f.Names {
+				var mbuf bytes.Buffer
+				mbuf.WriteString("method " + s.Name.Name + "." + n.Name)
+				writeSignature(&mbuf, ft)
+				api[s.Name.Name+"."+n.Name] = apiDecl{text: mbuf.String(), inInterface: true}
+			}
+		}
+	default:
+		buf.WriteString(types.ExprString(s.Type))
+	}
+	api[s.Name.Name] = apiDecl{text: buf.String()}
+}
+
+// embeddedName returns the field name of the embedded type x.
+func embeddedName(x ast.Expr) *ast.Ident {
+	for {
+		switch t := x.(type) {
+		case *ast.Ident:
+			return t
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.SelectorExpr:
+			return t.Sel
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.IndexListExpr:
+			x = t.X
+		default:
+			return nil
+		}
+	}
+}
+
+func writeTypeParams(buf *bytes.Buffer, list *ast.FieldList) {
+	if list == nil || len(list.List) == 0 {
+		return
+	}
+	buf.WriteString("[")
+	for i, f := range list.List {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		for j, n := range f.Names {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(n.Name)
+		}
+		buf.WriteString(" " + types.ExprString(f.Type))
+	}
+	buf.WriteString("]")
+}
+
+// writeSignature writes the parameters and results of t without their names,
+// so that renaming a parameter is not reported as a change.
+func writeSignature(buf *bytes.Buffer, t *ast.FuncType) {
+	buf.WriteString("(")
+	writeFieldTypes(buf, t.Params)
+	buf.WriteString(")")
+	if t.Results == nil || len(t.Results.List) == 0 {
+		return
+	}
+	if len(t.Results.List) == 1 && len(t.Results.List[0].Names) <= 1 {
+		buf.WriteString(" ")
+		writeFieldTypes(buf, t.Results)
+		return
+	}
+	buf.WriteString(" (")
+	writeFieldTypes(buf, t.Results)
+	buf.WriteString(")")
+}
+
+func writeFieldTypes(buf *bytes.Buffer, list *ast.FieldList) {
+	if list == nil {
+		return
+	}
+	sep := ""
+	for _, f := range list.List {
+		typ := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			buf.WriteString(sep + typ)
+			sep = ", "
+		}
+	}
+}
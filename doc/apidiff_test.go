@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const apiDiffOld = `package p
+
+const (
+	A = iota
+	B
+)
+
+const Max = 10
+
+var V int
+
+func F(a, b int) error { return nil }
+
+func Gone() {}
+
+type S struct {
+	X, Y int
+	Old  string
+	z    bool
+}
+
+func (s S) M(n int) {}
+
+type I interface {
+	Read(p []byte) (int, error)
+}
+
+type R struct{}
+
+func (R) M() {}
+`
+
+const apiDiffNew = `package p
+
+const (
+	B = iota
+	A
+)
+
+const Max = 10
+
+var V int64
+
+func F(x, y int) error { return nil }
+
+func New() *S { return nil }
+
+type S struct {
+	X, Y int
+	Z    float64
+	z    bool
+}
+
+func (s *S) M(n int) {}
+
+type I interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type T[E any] struct {
+	E E
+}
+
+func (T[E]) Get() E { var e E; return e }
+`
+
+func TestDiffAPI(t *testing.T) {
+	pkg := func(src string) *Package {
+		pkg, err := newPackage(&gosrc.Directory{
+			ImportPath: "example.com/p",
+			Files:      []*gosrc.File{{Name: "p.go", Data: []byte(src)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	got := DiffAPI(pkg(apiDiffOld), pkg(apiDiffNew))
+	want := []APIChange{
+		{Kind: ModifiedChange, Name: "A", Old: "const A = iota (iota = 0)", New: "const A = iota (iota = 1)"},
+		{Kind: ModifiedChange, Name: "B", Old: "const B = iota (iota = 1)", New: "const B = iota (iota = 0)"},
+		{Kind: RemovedChange, Name: "Gone", Old: "func Gone()"},
+		{Kind: AddedChange, Name: "I.Close", New: "method I.Close() error", Compatible: false},
+		{Kind: AddedChange, Name: "New", New: "func New() *S", Compatible: true},
+		{Kind: RemovedChange, Name: "R", Old: "type R struct"},
+		{Kind: ModifiedChange, Name: "S.M", Old: "func (S) M(int)", New: "func (*S) M(int)"},
+		{Kind: RemovedChange, Name: "S.Old", Old: "field S.Old string"},
+		{Kind: AddedChange, Name: "S.Z", New: "field S.Z float64", Compatible: true},
+		{Kind: AddedChange, Name: "T", New: "type T[E any] struct", Compatible: true},
+		{Kind: ModifiedChange, Name: "V", Old: "var V int", New: "var V int64"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffAPI:")
+		for _, c := range got {
+			t.Errorf("got  %+v", c)
+		}
+		for _, c := range want {
+			t.Errorf("want %+v", c)
+		}
+	}
+}
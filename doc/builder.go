@@ -12,11 +12,13 @@ import (
 	"go/ast"
 	"go/build"
 	"go/doc"
+	"go/doc/comment"
 	"go/format"
 	"go/parser"
 	"go/token"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -149,21 +151,66 @@ type builder struct {
 	fset     *token.FileSet
 	examples []*doc.Example
 	buf      []byte // scratch space for printNode method.
+
+	// Platforms providing each *doc.Value, *doc.Func and *doc.Type, and the
+	// number of platforms, when the declarations of several environments
+	// are merged.
+	platforms    map[interface{}][]string
+	numPlatforms int
+
+	// Declarations of each merged *doc.Func and *doc.Type on the platforms,
+	// and the constants and variables which declare a name documented by
+	// an earlier declaration.
+	platformDecls map[interface{}][]*platformDecl
+	redeclared    map[*doc.Value]bool
+
+	// parser parses doc comments and resolves their doc links.
+	parser *comment.Parser
+
+	// since is the Go version which added each declaration of a standard
+	// package. See gosrc.Directory.Since.
+	since map[string]string
+}
+
+// comment parses the doc comment text. It returns nil if the package has
+// not been parsed.
+func (b *builder) comment(text string) *Comment {
+	if b.parser == nil {
+		return nil
+	}
+	return parseComment(b.parser, text)
 }
 
 type Value struct {
-	Decl Code
-	Pos  Pos
-	Doc  string
+	Names     []string
+	Decl      Code
+	Pos       Pos
+	Doc       string
+	Comment   *Comment // Doc parsed into blocks.
+	Platforms []string // Platforms providing any of Names, nil if all do.
+	Since     string   // Go version which added the value, "" if Go 1.0 or not known.
+
+	// Deprecated paragraph of Doc, "" if the value is not deprecated.
+	Deprecated string
 }
 
 func (b *builder) values(vdocs []*doc.Value) []*Value {
 	var result []*Value
 	for _, d := range vdocs {
+		decl := b.printDecl(d.Decl)
+		if b.redeclared[d] {
+			// The names are anchored at their first declaration.
+			decl = withoutAnchors(decl)
+		}
 		result = append(result, &Value{
-			Decl: b.printDecl(d.Decl),
-			Pos:  b.position(d.Decl),
-			Doc:  d.Doc,
+			Names:      d.Names,
+			Decl:       decl,
+			Pos:        b.position(d.Decl),
+			Doc:        d.Doc,
+			Comment:    b.comment(d.Doc),
+			Platforms:  b.platformsOf(d),
+			Since:      b.valueSince(d),
+			Deprecated: deprecation(d.Doc),
 		})
 	}
 	return result
@@ -249,36 +296,61 @@ func (b *builder) getExamples(name string) []*Example {
 	return docs
 }
 
+// PlatformDecl is the declaration of a function or type on some of the
+// platforms providing it.
+type PlatformDecl struct {
+	Decl      Code
+	Pos       Pos
+	Platforms []string
+}
+
 type Func struct {
 	Decl     Code
 	Pos      Pos
 	Doc      string
+	Comment  *Comment // Doc parsed into blocks.
 	Name     string
 	Recv     string // Actual receiver "T" or "*T".
 	Orig     string // Original receiver "T" or "*T". This can be different from Recv due to embedding.
 	Examples []*Example
+
+	// Platforms providing the function, nil if all do.
+	Platforms []string
+
+	// Declarations of the function on the platforms providing it, nil if
+	// they are all the same.
+	PlatformDecls []*PlatformDecl
+
+	// Go version which added the function, "" if Go 1.0 or not known.
+	Since string
+
+	// Deprecated paragraph of Doc, "" if the function is not deprecated.
+	Deprecated string
 }
 
 func (b *builder) funcs(fdocs []*doc.Func) []*Func {
 	var result []*Func
 	for _, d := range fdocs {
-		var exampleName string
-		switch {
-		case d.Recv == "":
-			exampleName = d.Name
-		case d.Recv[0] == '*':
-			exampleName = d.Recv[1:] + "_" + d.Name
-		default:
-			exampleName = d.Recv + "_" + d.Name
+		exampleName, sinceName := d.Name, d.Name
+		if d.Recv != "" {
+			// Examples of methods are named after the receiver type
+			// without the type parameters of a generic type.
+			exampleName = recvType(d.Recv) + "_" + d.Name
+			sinceName = recvType(d.Recv) + "." + d.Name
 		}
 		result = append(result, &Func{
-			Decl:     b.printDecl(d.Decl),
-			Pos:      b.position(d.Decl),
-			Doc:      d.Doc,
-			Name:     d.Name,
-			Recv:     d.Recv,
-			Orig:     d.Orig,
-			Examples: b.getExamples(exampleName),
+			Decl:          b.printDecl(d.Decl),
+			Pos:           b.position(d.Decl),
+			Doc:           d.Doc,
+			Comment:       b.comment(d.Doc),
+			Name:          d.Name,
+			Recv:          d.Recv,
+			Orig:          d.Orig,
+			Examples:      b.getExamples(exampleName),
+			Platforms:     b.platformsOf(d),
+			PlatformDecls: b.declsOf(d),
+			Since:         b.since[sinceName],
+			Deprecated:    deprecation(d.Doc),
 		})
 	}
 	return result
@@ -286,6 +358,7 @@ func (b *builder) funcs(fdocs []*doc.Func) []*Func {
 
 type Type struct {
 	Doc      string
+	Comment  *Comment // Doc parsed into blocks.
 	Name     string
 	Decl     Code
 	Pos      Pos
@@ -294,21 +367,174 @@ type Type struct {
 	Funcs    []*Func
 	Methods  []*Func
 	Examples []*Example
+
+	// Platforms providing the type, nil if all do.
+	Platforms []string
+
+	// Declarations of the type on the platforms providing it, nil if they
+	// are all the same.
+	PlatformDecls []*PlatformDecl
+
+	// Go version which added the type, "" if Go 1.0 or not known.
+	Since string
+
+	// Deprecated paragraph of Doc, "" if the type is not deprecated.
+	Deprecated string
+
+	// Fields and interface methods which were added after the type or are
+	// deprecated.
+	Fields []*Field
+
+	// Types embedded in the type.
+	Embedded []*Embedded
+
+	// Fields and methods promoted from the embedded types, by embedded
+	// type in order of depth. See Promote.
+	Promoted []*Promoted
+
+	// Interfaces of this package and well-known standard library
+	// interfaces implemented by the type.
+	Implements []*TypeRef
+
+	// Types of this package which implement the interface type.
+	Implementers []*TypeRef
+}
+
+// Field is an exported struct field or interface method.
+type Field struct {
+	Name       string
+	Since      string // Go version which added the field.
+	Deprecated string // Deprecated paragraph of the field's comment.
+}
+
+// fieldList returns the struct fields or interface methods of the type d, or
+// nil if d is not a struct or interface type.
+func fieldList(d *doc.Type) *ast.FieldList {
+	for _, spec := range d.Decl.Specs {
+		s, ok := spec.(*ast.TypeSpec)
+		if !ok || s.Name.Name != d.Name {
+			continue
+		}
+		switch t := s.Type.(type) {
+		case *ast.StructType:
+			return t.Fields
+		case *ast.InterfaceType:
+			return t.Methods
+		}
+	}
+	return nil
+}
+
+// fields returns the exported fields and interface methods of the type d
+// which were added after the type or are deprecated.
+func (b *builder) fields(d *doc.Type) []*Field {
+	list := fieldList(d)
+	if list == nil {
+		return nil
+	}
+	typeSince := b.since[d.Name]
+	var fields []*Field
+	for _, f := range list.List {
+		deprecated := deprecation(f.Doc.Text())
+		if deprecated == "" {
+			deprecated = deprecation(f.Comment.Text())
+		}
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{embeddedName(f.Type)}
+		}
+		for _, n := range names {
+			if n == nil || !n.IsExported() {
+				continue
+			}
+			since := b.since[d.Name+"."+n.Name]
+			if since != "" && typeSince != "" && !versionLess(typeSince, since) {
+				since = ""
+			}
+			if since != "" || deprecated != "" {
+				fields = append(fields, &Field{Name: n.Name, Since: since, Deprecated: deprecated})
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// Embedded is a named type embedded in a struct or interface type.
+type Embedded struct {
+	ImportPath string // package declaring the type, "" for this package
+	Name       string
+}
+
+// embedded returns the exported types embedded in the type d.
+func embedded(d *doc.Type) []*Embedded {
+	list := fieldList(d)
+	if list == nil {
+		return nil
+	}
+	var result []*Embedded
+	for _, f := range list.List {
+		if len(f.Names) > 0 {
+			continue
+		}
+		if e := embeddedType(f.Type); e != nil && ast.IsExported(e.Name) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func embeddedType(x ast.Expr) *Embedded {
+	for {
+		switch t := x.(type) {
+		case *ast.Ident:
+			return &Embedded{Name: t.Name}
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.IndexListExpr:
+			x = t.X
+		case *ast.SelectorExpr:
+			pkg, _ := t.X.(*ast.Ident)
+			if pkg == nil || pkg.Obj == nil || pkg.Obj.Kind != ast.Pkg {
+				return nil
+			}
+			spec, _ := pkg.Obj.Decl.(*ast.ImportSpec)
+			if spec == nil {
+				return nil
+			}
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil
+			}
+			return &Embedded{ImportPath: importPath, Name: t.Sel.Name}
+		default:
+			return nil
+		}
+	}
 }
 
 func (b *builder) types(tdocs []*doc.Type) []*Type {
 	var result []*Type
 	for _, d := range tdocs {
 		result = append(result, &Type{
-			Doc:      d.Doc,
-			Name:     d.Name,
-			Decl:     b.printDecl(d.Decl),
-			Pos:      b.position(d.Decl),
-			Consts:   b.values(d.Consts),
-			Vars:     b.values(d.Vars),
-			Funcs:    b.funcs(d.Funcs),
-			Methods:  b.funcs(d.Methods),
-			Examples: b.getExamples(d.Name),
+			Doc:           d.Doc,
+			Comment:       b.comment(d.Doc),
+			Name:          d.Name,
+			Decl:          b.printDecl(d.Decl),
+			Pos:           b.position(d.Decl),
+			Consts:        b.values(d.Consts),
+			Vars:          b.values(d.Vars),
+			Funcs:         b.funcs(d.Funcs),
+			Methods:       b.funcs(d.Methods),
+			Examples:      b.getExamples(d.Name),
+			Platforms:     b.platformsOf(d),
+			PlatformDecls: b.declsOf(d),
+			Since:         b.since[d.Name],
+			Deprecated:    deprecation(d.Doc),
+			Fields:        b.fields(d),
+			Embedded:      embedded(d),
 		})
 	}
 	return result
@@ -357,6 +583,10 @@ func simpleImporter(imports map[string]*ast.Object, path string) (*ast.Object, e
 type File struct {
 	Name string
 	URL  string
+
+	// True if the annotated source of the file is stored with the package.
+	// See Package.Sources.
+	Source bool
 }
 
 type Pos struct {
@@ -373,7 +603,7 @@ type source struct {
 }
 
 // PackageVersion is modified when previously stored packages are invalid.
-const PackageVersion = "8"
+const PackageVersion = "9"
 
 type Package struct {
 	// The import path for this package.
@@ -391,9 +621,16 @@ type Package struct {
 	// Errors found when fetching or parsing this package.
 	Errors []string
 
+	// Possible problems found in the source of this package by analyses.
+	Diagnostics []*Diagnostic
+
 	// Packages referenced in README files.
 	References []string
 
+	// README file of the directory rendered as sanitized HTML, or "" if the
+	// directory has no README file.
+	Readme string
+
 	// Version control system: git, hg, bzr, ...
 	VCS string
 
@@ -414,6 +651,24 @@ type Package struct {
 	// The tag is "" if there is no meaningful cache validation for the VCS.
 	Etag string
 
+	// The version of this documentation, or "" for the default branch.
+	Version string
+
+	// Known versions of the package, newest first.
+	Versions []string
+
+	// Path of the module containing the package and the Go version declared
+	// in its go.mod file. Both are "" if the package is not in a module.
+	ModulePath string
+	GoVersion  string
+
+	// License files that apply to the package.
+	Licenses []*gosrc.License
+
+	// True if the licenses of the package were checked and do not permit
+	// showing its source, such as the code of examples.
+	NotRedistributable bool
+
 	// Subdirectories, possibly containing Go code.
 	Subdirectories []string
 
@@ -425,6 +680,12 @@ type Package struct {
 	Synopsis string
 	Doc      string
 
+	// Doc parsed into blocks.
+	Comment *Comment
+
+	// Deprecated paragraph of Doc, "" if the package is not deprecated.
+	Deprecated string
+
 	// Format this package as a command.
 	IsCmd bool
 
@@ -434,6 +695,10 @@ type Package struct {
 	// Environment
 	GOOS, GOARCH string
 
+	// Platforms the package builds for as GOOS/GOARCH, primary environment
+	// first, or nil if the package was only built for GOOS/GOARCH.
+	Platforms []string
+
 	// Top-level declarations.
 	Consts []*Value
 	Funcs  []*Func
@@ -455,6 +720,11 @@ type Package struct {
 	SourceSize     int
 	TestSourceSize int
 
+	// Annotated source of the Go files with File.Source set. The sources
+	// are stored separately from the package and are nil in stored
+	// packages.
+	Sources []*SourceFile
+
 	// Imports
 	Imports      []string
 	TestImports  []string
@@ -470,8 +740,7 @@ var goEnvs = []struct{ GOOS, GOARCH string }{
 }
 
 // SetDefaultGOOS sets given GOOS value as default one to use when building
-// package documents. SetDefaultGOOS has no effect on some windows-only
-// packages.
+// package documents. Declarations for other platforms are still included.
 func SetDefaultGOOS(goos string) {
 	if goos == "" {
 		return
@@ -493,15 +762,6 @@ func SetDefaultGOOS(goos string) {
 	goEnvs[0], goEnvs[i] = goEnvs[i], goEnvs[0]
 }
 
-var windowsOnlyPackages = map[string]bool{
-	"internal/syscall/windows":                     true,
-	"internal/syscall/windows/registry":            true,
-	"golang.org/x/exp/shiny/driver/internal/win32": true,
-	"golang.org/x/exp/shiny/driver/windriver":      true,
-	"golang.org/x/sys/windows":                     true,
-	"golang.org/x/sys/windows/registry":            true,
-}
-
 func newPackage(dir *gosrc.Directory) (*Package, error) {
 
 	pkg := &Package{
@@ -513,21 +773,28 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		ProjectURL:     dir.ProjectURL,
 		BrowseURL:      dir.BrowseURL,
 		Etag:           PackageVersion + "-" + dir.Etag,
+		Version:        dir.Version,
+		ModulePath:     dir.ModulePath,
+		GoVersion:      dir.GoVersion,
 		VCS:            dir.VCS,
 		Status:         dir.Status,
 		Subdirectories: dir.Subdirectories,
 		Fork:           dir.Fork,
 		Stars:          dir.Stars,
+		Errors:         dir.Errors,
+		Licenses:       dir.Licenses,
 	}
+	pkg.NotRedistributable = dir.Licenses != nil && !gosrc.IsRedistributable(dir.Licenses)
 
 	var b builder
 	b.srcs = make(map[string]*source)
+	b.since = dir.Since
 	references := make(map[string]bool)
 	for _, file := range dir.Files {
 		if strings.HasSuffix(file.Name, ".go") {
 			gosrc.OverwriteLineComments(file.Data)
 			b.srcs[file.Name] = &source{name: file.Name, browseURL: file.BrowseURL, data: file.Data}
-		} else {
+		} else if file.Name != "go.mod" && !gosrc.IsLicenseFile(file.Name) {
 			addReferences(references, file.Data)
 		}
 	}
@@ -535,6 +802,7 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 	for r := range references {
 		pkg.References = append(pkg.References, r)
 	}
+	pkg.Readme = renderReadme(dir.Files, dir.BrowseURL)
 
 	if len(b.srcs) == 0 {
 		return pkg, nil
@@ -553,62 +821,88 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		Compiler:    "gc",
 	}
 
-	var err error
-	var bpkg *build.Package
-
+	// Import the package for each environment. The documentation is built
+	// for the first environment with Go files, the primary environment, and
+	// the declarations of the other environments are merged into it.
+	var envs []*buildEnv
 	for _, env := range goEnvs {
-		// Some packages should be always displayed as GOOS=windows (see issue #16509 for details).
-		// TODO: remove this once issue #16509 is resolved.
-		if windowsOnlyPackages[dir.ImportPath] && env.GOOS != "windows" {
-			continue
-		}
-
 		ctxt.GOOS = env.GOOS
 		ctxt.GOARCH = env.GOARCH
-		bpkg, err = dir.Import(&ctxt, build.ImportComment)
-		if _, ok := err.(*build.NoGoError); !ok {
-			break
+		bpkg, err := dir.Import(&ctxt, build.ImportComment)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); !ok && len(envs) == 0 {
+				pkg.Errors = append(pkg.Errors, err.Error())
+				return pkg, nil
+			}
+			continue
 		}
+		envs = append(envs, &buildEnv{goos: env.GOOS, goarch: env.GOARCH, bpkg: bpkg})
 	}
-	if err != nil {
-		if _, ok := err.(*build.NoGoError); !ok {
-			pkg.Errors = append(pkg.Errors, err.Error())
-		}
+	if len(envs) == 0 {
 		return pkg, nil
 	}
-
-	// Use information we have by now (import comment and resolved GitHub path)
-	// to redirect to a canonical import path, when it's possible to do so reliably.
-	err = gosrc.MaybeRedirect(dir.ImportPath, bpkg.ImportComment, dir.ResolvedGitHubPath)
+	bpkg := envs[0].bpkg
+
+	// Use information we have by now (module path, import comment and resolved
+	// GitHub path) to redirect to a canonical import path, when it's possible to
+	// do so reliably. The module path takes precedence over the import comment
+	// as it does for the go command.
+	canonicalPath := bpkg.ImportComment
+	if dir.ModuleImportPath != "" {
+		canonicalPath = dir.ModuleImportPath
+	}
+	err := gosrc.MaybeRedirect(dir.ImportPath, canonicalPath, dir.ResolvedGitHubPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse the Go files
+	// Parse the Go files. The files are parsed for each environment because
+	// building the documentation modifies the syntax trees.
 
-	files := make(map[string]*ast.File)
-	names := append(bpkg.GoFiles, bpkg.CgoFiles...)
-	sort.Strings(names)
+	names := envUnion(envs, func(p *build.Package) []string { return append(p.GoFiles, p.CgoFiles...) })
 	pkg.Files = make([]*File, len(names))
 	for i, name := range names {
-		file, err := parser.ParseFile(b.fset, name, b.srcs[name].data, parser.ParseComments)
-		if err != nil {
-			pkg.Errors = append(pkg.Errors, err.Error())
-		} else {
-			files[name] = file
-		}
 		src := b.srcs[name]
 		src.index = i
 		pkg.Files[i] = &File{Name: name, URL: src.browseURL}
 		pkg.SourceSize += len(src.data)
 	}
 
-	apkg, _ := ast.NewPackage(b.fset, files, simpleImporter, nil)
+	mode := doc.Mode(0)
+	if pkg.ImportPath == "builtin" {
+		mode |= doc.AllDecls
+	}
+
+	parsed := make(map[string]bool)
+	for i, env := range envs {
+		files := make(map[string]*ast.File)
+		for _, name := range append(append([]string(nil), env.bpkg.GoFiles...), env.bpkg.CgoFiles...) {
+			file, err := parser.ParseFile(b.fset, name, b.srcs[name].data, parser.ParseComments)
+			if err != nil {
+				if !parsed[name] {
+					pkg.Errors = append(pkg.Errors, err.Error())
+				}
+			} else {
+				files[name] = file
+			}
+			parsed[name] = true
+		}
+		apkg, _ := ast.NewPackage(b.fset, files, simpleImporter, nil)
+		if i == 0 {
+			b.analyzePackage(pkg, apkg)
+		}
+		if !pkg.NotRedistributable {
+			b.addSources(pkg, apkg)
+		}
+		env.dpkg = doc.New(apkg, pkg.ImportPath, mode)
+		if pkg.ImportPath == "builtin" {
+			removeAssociations(env.dpkg)
+		}
+	}
 
 	// Find examples in the test files.
 
-	names = append(bpkg.TestGoFiles, bpkg.XTestGoFiles...)
-	sort.Strings(names)
+	names = envUnion(envs, func(p *build.Package) []string { return append(p.TestGoFiles, p.XTestGoFiles...) })
 	pkg.TestFiles = make([]*File, len(names))
 	for i, name := range names {
 		file, err := parser.ParseFile(b.fset, name, b.srcs[name].data, parser.ParseComments)
@@ -621,37 +915,40 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		pkg.TestSourceSize += len(b.srcs[name].data)
 	}
 
-	b.vetPackage(pkg, apkg)
-
-	mode := doc.Mode(0)
-	if pkg.ImportPath == "builtin" {
-		mode |= doc.AllDecls
-	}
-
-	dpkg := doc.New(apkg, pkg.ImportPath, mode)
-
-	if pkg.ImportPath == "builtin" {
-		removeAssociations(dpkg)
-	}
+	dpkg := envs[0].dpkg
+	b.mergePlatforms(dpkg, envs)
 
 	pkg.Name = dpkg.Name
 	pkg.Doc = strings.TrimRight(dpkg.Doc, " \t\n\r")
+	b.parser = dpkg.Parser()
+	pkg.Comment = b.comment(pkg.Doc)
 	pkg.Synopsis = synopsis(pkg.Doc)
+	pkg.Deprecated = deprecation(pkg.Doc)
 
 	pkg.Examples = b.getExamples("")
 	pkg.IsCmd = bpkg.IsCommand()
-	pkg.GOOS = ctxt.GOOS
-	pkg.GOARCH = ctxt.GOARCH
+	pkg.GOOS = envs[0].goos
+	pkg.GOARCH = envs[0].goarch
+	if len(envs) > 1 {
+		for _, env := range envs {
+			pkg.Platforms = append(pkg.Platforms, env.platform())
+		}
+	}
 
 	pkg.Consts = b.values(dpkg.Consts)
 	pkg.Funcs = b.funcs(dpkg.Funcs)
 	pkg.Types = b.types(dpkg.Types)
+	implementations(pkg, dpkg.Types)
 	pkg.Vars = b.values(dpkg.Vars)
 	pkg.Notes = b.notes(dpkg.Notes)
 
-	pkg.Imports = bpkg.Imports
-	pkg.TestImports = bpkg.TestImports
-	pkg.XTestImports = bpkg.XTestImports
+	// Members promoted from embedded types of other packages are added by
+	// Promote when the stored documentation of the packages is available.
+	(&promoter{pkg: pkg}).promoteTypes()
+
+	pkg.Imports = envUnion(envs, func(p *build.Package) []string { return p.Imports })
+	pkg.TestImports = envUnion(envs, func(p *build.Package) []string { return p.TestImports })
+	pkg.XTestImports = envUnion(envs, func(p *build.Package) []string { return p.XTestImports })
 
 	return pkg, nil
 }
@@ -8,7 +8,11 @@ package doc
 
 import (
 	"go/ast"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/golang/gddo/gosrc"
 )
 
 var badSynopsis = []string{
@@ -112,3 +116,362 @@ func TestSimpleImporter(t *testing.T) {
 		}
 	}
 }
+
+func TestNewPackageModule(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath:       "github.com/user/repo/pkg",
+		ProjectRoot:      "github.com/user/repo",
+		ModulePath:       "example.com/repo",
+		ModuleImportPath: "example.com/repo/pkg",
+		GoVersion:        "1.16",
+		Files: []*gosrc.File{
+			{Name: "pkg.go", Data: []byte("package pkg")},
+			{Name: "go.mod", Data: []byte("module example.com/repo\n\nrequire github.com/other/dep v1.0.0\n")},
+		},
+	}
+	_, err := newPackage(dir)
+	if e, ok := err.(gosrc.NotFoundError); !ok || e.Redirect != "example.com/repo/pkg" {
+		t.Fatalf("newPackage returned error %v, want redirect to module import path", err)
+	}
+
+	dir.ImportPath = "example.com/repo/pkg"
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatalf("newPackage returned error %v", err)
+	}
+	if pkg.ModulePath != "example.com/repo" || pkg.GoVersion != "1.16" {
+		t.Errorf("newPackage set module %q, go version %q; want example.com/repo, 1.16", pkg.ModulePath, pkg.GoVersion)
+	}
+	if len(pkg.References) != 0 {
+		t.Errorf("newPackage added references %v from go.mod", pkg.References)
+	}
+}
+
+func TestNewPackagePlatforms(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n\n// F is everywhere.\nfunc F() {}\n\n// T is everywhere.\ntype T int\n")},
+			{Name: "p_linux.go", Data: []byte("package p\n\nconst Sep = '/'\n\nfunc Linux() {}\n\nfunc (T) M() {}\n")},
+			{Name: "p_darwin.go", Data: []byte("package p\n\nconst Sep = '/'\n\nfunc (T) M() {}\n")},
+			{Name: "p_windows.go", Data: []byte("package p\n\nconst Sep = '\\\\'\n\nfunc Windows() {}\n")},
+		},
+	}
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.GOOS != "linux" || len(pkg.Platforms) != 5 || pkg.Platforms[0] != "linux/amd64" {
+		t.Errorf("GOOS = %s, Platforms = %v; want linux first of 5", pkg.GOOS, pkg.Platforms)
+	}
+	if len(pkg.Files) != 4 {
+		t.Errorf("len(Files) = %d, want 4", len(pkg.Files))
+	}
+	funcs := make(map[string][]string)
+	for _, f := range pkg.Funcs {
+		funcs[f.Name] = f.Platforms
+	}
+	want := map[string][]string{
+		"F":       nil,
+		"Linux":   {"linux/amd64", "linux/js"},
+		"Windows": {"windows/amd64"},
+	}
+	if !reflect.DeepEqual(funcs, want) {
+		t.Errorf("funcs = %v, want %v", funcs, want)
+	}
+	// Sep has a different value on windows, so both declarations are kept.
+	consts := make(map[string][]string)
+	for _, c := range pkg.Consts {
+		consts[c.Decl.Text] = c.Platforms
+	}
+	wantConsts := map[string][]string{
+		"const Sep = '/'":    {"linux/amd64", "darwin/amd64", "linux/js"},
+		"const Sep = '\\\\'": {"windows/amd64"},
+	}
+	if !reflect.DeepEqual(consts, wantConsts) || !strings.Contains(pkg.Consts[0].Decl.Text, "'/'") {
+		t.Errorf("consts = %v, want %v with the linux declaration first", consts, wantConsts)
+	}
+	if len(pkg.Types) != 1 || pkg.Types[0].Platforms != nil || len(pkg.Types[0].Methods) != 1 ||
+		!reflect.DeepEqual(pkg.Types[0].Methods[0].Platforms, []string{"linux/amd64", "darwin/amd64", "linux/js"}) {
+		t.Errorf("Types = %+v, want T with method M on linux and darwin", pkg.Types)
+	}
+
+	// The declarations of the primary platform come first.
+	defer func(envs []struct{ GOOS, GOARCH string }) { goEnvs = envs }(append(goEnvs[:0:0], goEnvs...))
+	SetDefaultGOOS("windows")
+	pkg, err = newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.GOOS != "windows" || len(pkg.Consts) != 2 || pkg.Consts[0].Decl.Text != "const Sep = '\\\\'" ||
+		!reflect.DeepEqual(pkg.Consts[0].Platforms, []string{"windows/amd64"}) {
+		t.Errorf("GOOS = %s, Consts = %+v; want the windows declaration of Sep first", pkg.GOOS, pkg.Consts)
+	}
+}
+
+func TestNewPackagePlatformGroups(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n")},
+			{Name: "p_linux.go", Data: []byte("package p\n\nconst (\n\tA = 1\n\tB = 2\n)\n")},
+			{Name: "p_windows.go", Data: []byte("package p\n\nconst (\n\tA = 1\n\tC = 3\n)\n")},
+		},
+	}
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A is documented once, in the group of the primary platform.
+	type value struct{ Names, Platforms []string }
+	var got []value
+	for _, c := range pkg.Consts {
+		got = append(got, value{c.Names, c.Platforms})
+	}
+	want := []value{
+		{[]string{"A", "B"}, []string{"linux/amd64", "windows/amd64", "linux/js"}},
+		{[]string{"C"}, []string{"windows/amd64"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("consts = %+v, want %+v", got, want)
+	} else if text := pkg.Consts[1].Decl.Text; strings.Contains(text, "A") || !strings.Contains(text, "C = 3") {
+		t.Errorf("windows declaration = %q, want only C", text)
+	}
+}
+
+func TestNewPackagePlatformDecls(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n\n// F is everywhere.\nfunc F() {}\n")},
+			{Name: "p_linux.go", Data: []byte("package p\n\ntype T struct{ Fd int }\n\nfunc G(fd int) {}\n\nconst Sep = '/'\n")},
+			{Name: "p_darwin.go", Data: []byte("package p\n\ntype T struct{ Fd int }\n\nfunc G(fd int) {}\n\nconst Sep = '/'\n")},
+			{Name: "p_windows.go", Data: []byte("package p\n\ntype T struct{ Handle uintptr }\n\nfunc G(h uintptr) {}\n\nconst Sep = '\\\\'\n")},
+		},
+	}
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type decl struct {
+		Text      string
+		Platforms []string
+		Anchors   int
+	}
+	decls := func(pds []*PlatformDecl) []decl {
+		var result []decl
+		for _, pd := range pds {
+			d := decl{Text: pd.Decl.Text, Platforms: pd.Platforms}
+			for _, a := range pd.Decl.Annotations {
+				if a.Kind == AnchorAnnotation {
+					d.Anchors++
+				}
+			}
+			result = append(result, d)
+		}
+		return result
+	}
+	unix := []string{"linux/amd64", "darwin/amd64", "linux/js"}
+
+	// A function or type with different declarations is documented once,
+	// with the declaration of each platform. Only the declaration of the
+	// primary platform has anchors.
+	if len(pkg.Types) != 1 || pkg.Types[0].Name != "T" {
+		t.Fatalf("Types = %+v, want T once", pkg.Types)
+	}
+	typ := pkg.Types[0]
+	want := []decl{
+		{"type T struct{ Fd int }", unix, 1},
+		{"type T struct{ Handle uintptr }", []string{"windows/amd64"}, 0},
+	}
+	if got := decls(typ.PlatformDecls); !reflect.DeepEqual(got, want) {
+		t.Errorf("T: declarations = %+v, want %+v", got, want)
+	}
+	if p := []string{"linux/amd64", "darwin/amd64", "windows/amd64", "linux/js"}; !reflect.DeepEqual(typ.Platforms, p) {
+		t.Errorf("T: Platforms = %v, want %v", typ.Platforms, p)
+	}
+	if typ.Decl.Text != want[0].Text {
+		t.Errorf("T: Decl = %q, want %q", typ.Decl.Text, want[0].Text)
+	}
+
+	var names []string
+	for _, f := range pkg.Funcs {
+		names = append(names, f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"F", "G"}) {
+		t.Fatalf("Funcs = %v, want [F G]", names)
+	}
+	if pds := pkg.Funcs[0].PlatformDecls; pds != nil {
+		t.Errorf("F: declarations = %+v, want none", decls(pds))
+	}
+	want = []decl{
+		{"func G(fd int)", unix, 0},
+		{"func G(h uintptr)", []string{"windows/amd64"}, 0},
+	}
+	if got := decls(pkg.Funcs[1].PlatformDecls); !reflect.DeepEqual(got, want) {
+		t.Errorf("G: declarations = %+v, want %+v", got, want)
+	}
+
+	// The names of constants are anchored at their first declaration.
+	var anchors []int
+	for _, c := range pkg.Consts {
+		n := 0
+		for _, a := range c.Decl.Annotations {
+			if a.Kind == AnchorAnnotation {
+				n++
+			}
+		}
+		anchors = append(anchors, n)
+	}
+	if !reflect.DeepEqual(anchors, []int{1, 0}) {
+		t.Errorf("anchors of the declarations of Sep = %v, want [1 0]", anchors)
+	}
+}
+
+func TestNewPackageSince(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte(`package p
+
+const (
+	A = 1
+	B = 2
+)
+
+const C, D = 3, 4
+
+func F() {}
+
+func G() {}
+
+type S struct {
+	X, Y int
+	Z    string
+}
+
+func (*S) M() {}
+
+type I interface {
+	Read()
+	Close()
+}
+`)},
+		},
+		Since: map[string]string{
+			"B":       "1.2",
+			"C":       "1.9",
+			"D":       "1.10",
+			"G":       "1.5",
+			"S.Y":     "1.3",
+			"S.Z":     "1.7",
+			"S.M":     "1.4",
+			"I":       "1.1",
+			"I.Read":  "1.1",
+			"I.Close": "1.8",
+		},
+	}
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, v := range pkg.Consts {
+		got[strings.Join(v.Names, ",")] = v.Since
+	}
+	for _, f := range pkg.Funcs {
+		got[f.Name] = f.Since
+	}
+	for _, typ := range pkg.Types {
+		got[typ.Name] = typ.Since
+		for _, f := range typ.Fields {
+			got[typ.Name+"."+f.Name] = f.Since
+		}
+		for _, f := range typ.Methods {
+			got[typ.Name+"."+f.Name] = f.Since
+		}
+	}
+	want := map[string]string{
+		"A,B":     "",
+		"C,D":     "1.9",
+		"F":       "",
+		"G":       "1.5",
+		"I":       "1.1",
+		"I.Close": "1.8",
+		"S":       "",
+		"S.Y":     "1.3",
+		"S.Z":     "1.7",
+		"S.M":     "1.4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Since = %v, want %v", got, want)
+	}
+}
+
+func TestNewPackageDeprecated(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte(`// Package p is old.
+//
+// Deprecated: Use package
+// example.com/q instead.
+package p
+
+// C is a constant.
+//
+// Deprecated: Use D.
+const C = 1
+
+// Deprecated: Use G.
+func F() {}
+
+// T is a type.
+type T struct {
+	// Deprecated: Use Y.
+	X int
+	Y int
+	Z int // Deprecated: Use Y.
+}
+
+// M is not deprecated.
+//
+// The Deprecated: prefix must start a paragraph.
+func (T) M() {}
+`)},
+		},
+	}
+	pkg, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{"p": pkg.Deprecated}
+	for _, v := range pkg.Consts {
+		got[v.Names[0]] = v.Deprecated
+	}
+	for _, f := range pkg.Funcs {
+		got[f.Name] = f.Deprecated
+	}
+	for _, typ := range pkg.Types {
+		got[typ.Name] = typ.Deprecated
+		for _, f := range typ.Fields {
+			got[typ.Name+"."+f.Name] = f.Deprecated
+		}
+		for _, f := range typ.Methods {
+			got[typ.Name+"."+f.Name] = f.Deprecated
+		}
+	}
+	want := map[string]string{
+		"p":   "Deprecated: Use package example.com/q instead.",
+		"C":   "Deprecated: Use D.",
+		"F":   "Deprecated: Use G.",
+		"T":   "",
+		"T.X": "Deprecated: Use Y.",
+		"T.Z": "Deprecated: Use Y.",
+		"T.M": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Deprecated = %v, want %v", got, want)
+	}
+}
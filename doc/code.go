@@ -47,6 +47,8 @@ var predeclared = map[string]int{
 	"uint8":      predeclaredType,
 	"uint":       predeclaredType,
 	"uintptr":    predeclaredType,
+	"any":        predeclaredType,
+	"comparable": predeclaredType,
 
 	"true":  predeclaredConstant,
 	"false": predeclaredConstant,
@@ -55,6 +57,7 @@ var predeclared = map[string]int{
 
 	"append":  predeclaredFunction,
 	"cap":     predeclaredFunction,
+	"clear":   predeclaredFunction,
 	"close":   predeclaredFunction,
 	"complex": predeclaredFunction,
 	"copy":    predeclaredFunction,
@@ -62,6 +65,8 @@ var predeclared = map[string]int{
 	"imag":    predeclaredFunction,
 	"len":     predeclaredFunction,
 	"make":    predeclaredFunction,
+	"max":     predeclaredFunction,
+	"min":     predeclaredFunction,
 	"new":     predeclaredFunction,
 	"panic":   predeclaredFunction,
 	"print":   predeclaredFunction,
@@ -74,7 +79,7 @@ type AnnotationKind int16
 
 const (
 	// Link to export in package specified by Paths[PathIndex] with fragment
-	// Text[strings.LastIndex(Text[Pos:End], ".")+1:End].
+	// Text[Pos:End].
 	LinkAnnotation AnnotationKind = iota
 
 	// Anchor with name specified by Text[Pos:End] or typeName + "." +
@@ -89,6 +94,23 @@ const (
 
 	// Link to builtin entity with name Text[Pos:End].
 	BuiltinAnnotation
+
+	// Anchor with name Paths[PathIndex] for the declaration of a type
+	// parameter. The name is the declaring function or type name + "." +
+	// the type parameter name.
+	TypeParamAnchorAnnotation
+
+	// Link to the type parameter anchor with name Paths[PathIndex].
+	TypeParamLinkAnnotation
+
+	// Link to the URL Paths[PathIndex].
+	URLAnnotation
+
+	// Keyword in source code.
+	KeywordAnnotation
+
+	// String or character literal in source code.
+	StringAnnotation
 )
 
 type Annotation struct {
@@ -109,6 +131,13 @@ type declVisitor struct {
 	paths       []string
 	pathIndex   map[string]int
 	comments    []*ast.CommentGroup
+
+	// typeParams maps type parameter objects to their anchor names.
+	typeParams map[*ast.Object]string
+
+	// recvTypeParams maps the names of method receiver type parameters,
+	// which are not resolved by the parser, to their anchor names.
+	recvTypeParams map[string]string
 }
 
 func (v *declVisitor) add(kind AnnotationKind, importPath string) {
@@ -129,10 +158,85 @@ func (v *declVisitor) ignoreName() {
 	v.add(-1, "")
 }
 
+// declareTypeParams adds the anchors for the type parameters in list and
+// walks their constraints. The anchor names are scope + "." + the type
+// parameter name.
+func (v *declVisitor) declareTypeParams(scope string, list *ast.FieldList) {
+	if list == nil {
+		return
+	}
+	// Constraints can refer to any type parameter in the list.
+	for _, f := range list.List {
+		for _, name := range f.Names {
+			if name.Obj != nil {
+				v.typeParams[name.Obj] = scope + "." + name.Name
+			}
+		}
+	}
+	for _, f := range list.List {
+		for _, name := range f.Names {
+			v.add(TypeParamAnchorAnnotation, scope+"."+name.Name)
+		}
+		ast.Walk(v, f.Type)
+	}
+}
+
+// receiverTypeParams maps the type parameters of the method receiver recv to
+// the anchors of the corresponding type parameters in the declaration of the
+// receiver type. Receivers can rename type parameters.
+func (v *declVisitor) receiverTypeParams(recv *ast.FieldList) {
+	if len(recv.List) != 1 {
+		return
+	}
+	typ := recv.List[0].Type
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ = x.X
+	}
+	var x ast.Expr
+	var indices []ast.Expr
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		x, indices = t.X, []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		x, indices = t.X, t.Indices
+	default:
+		return
+	}
+	name, _ := x.(*ast.Ident)
+	if name == nil || name.Obj == nil {
+		return
+	}
+	spec, _ := name.Obj.Decl.(*ast.TypeSpec)
+	if spec == nil || spec.TypeParams == nil {
+		return
+	}
+	var declared []string
+	for _, f := range spec.TypeParams.List {
+		for _, n := range f.Names {
+			declared = append(declared, n.Name)
+		}
+	}
+	for i, index := range indices {
+		if id, _ := index.(*ast.Ident); id != nil && id.Name != "_" && i < len(declared) {
+			v.recvTypeParams[id.Name] = name.Name + "." + declared[i]
+		}
+	}
+}
+
+// typeParamAnchor returns the anchor name of the type parameter n or "" if n
+// is not a type parameter.
+func (v *declVisitor) typeParamAnchor(n *ast.Ident) string {
+	if n.Obj != nil {
+		return v.typeParams[n.Obj]
+	}
+	return v.recvTypeParams[n.Name]
+}
+
 func (v *declVisitor) Visit(n ast.Node) ast.Visitor {
 	switch n := n.(type) {
 	case *ast.TypeSpec:
 		v.ignoreName()
+		v.declareTypeParams(n.Name.Name, n.TypeParams)
 		switch n := n.Type.(type) {
 		case *ast.InterfaceType:
 			for _, f := range n.Methods.List {
@@ -153,10 +257,15 @@ func (v *declVisitor) Visit(n ast.Node) ast.Visitor {
 		}
 	case *ast.FuncDecl:
 		if n.Recv != nil {
+			v.receiverTypeParams(n.Recv)
 			ast.Walk(v, n.Recv)
 		}
 		v.ignoreName()
-		ast.Walk(v, n.Type)
+		v.declareTypeParams(n.Name.Name, n.Type.TypeParams)
+		ast.Walk(v, n.Type.Params)
+		if n.Type.Results != nil {
+			ast.Walk(v, n.Type.Results)
+		}
 	case *ast.Field:
 		for _ = range n.Names {
 			v.ignoreName()
@@ -174,6 +283,8 @@ func (v *declVisitor) Visit(n ast.Node) ast.Visitor {
 		}
 	case *ast.Ident:
 		switch {
+		case v.typeParamAnchor(n) != "":
+			v.add(TypeParamLinkAnnotation, v.typeParamAnchor(n))
 		case n.Obj == nil && predeclared[n.Name] != notPredeclared:
 			v.add(BuiltinAnnotation, "")
 		case n.Obj != nil && ast.IsExported(n.Name):
@@ -231,7 +342,7 @@ func (v *declVisitor) Visit(n ast.Node) ast.Visitor {
 }
 
 func (b *builder) printDecl(decl ast.Decl) (d Code) {
-	v := &declVisitor{pathIndex: make(map[string]int)}
+	v := &declVisitor{pathIndex: make(map[string]int), typeParams: make(map[*ast.Object]string), recvTypeParams: make(map[string]string)}
 	ast.Walk(v, decl)
 	b.buf = b.buf[:0]
 	err := (&printer.Config{Mode: printer.UseSpaces, Tabwidth: 4}).Fprint(
@@ -0,0 +1,109 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var annotationNames = map[AnnotationKind]string{
+	LinkAnnotation:            "link",
+	AnchorAnnotation:          "anchor",
+	PackageLinkAnnotation:     "pkg",
+	BuiltinAnnotation:         "builtin",
+	TypeParamAnchorAnnotation: "tparam",
+	TypeParamLinkAnnotation:   "tparam-link",
+	URLAnnotation:             "url",
+	KeywordAnnotation:         "kwd",
+	StringAnnotation:          "str",
+}
+
+// formatCode returns the text of c with the annotations, except comments,
+// written as {kind path:text}.
+func formatCode(c Code) string {
+	var buf bytes.Buffer
+	last := int32(0)
+	for _, a := range c.Annotations {
+		if a.Kind == CommentAnnotation {
+			continue
+		}
+		buf.WriteString(c.Text[last:a.Pos])
+		buf.WriteString("{" + annotationNames[a.Kind])
+		if a.PathIndex >= 0 {
+			buf.WriteString(" " + c.Paths[a.PathIndex])
+		}
+		buf.WriteString(":" + c.Text[a.Pos:a.End] + "}")
+		last = a.End
+	}
+	buf.WriteString(c.Text[last:])
+	return buf.String()
+}
+
+func TestCodeGolden(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "code", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := strings.TrimSuffix(filepath.Base(name), ".go")
+		pkg, err := newPackage(&gosrc.Directory{
+			ImportPath: "example.com/" + base,
+			Files:      []*gosrc.File{{Name: base + ".go", Data: data}},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(pkg.Errors) > 0 {
+			t.Fatalf("%s: %v", name, pkg.Errors)
+		}
+
+		var buf bytes.Buffer
+		decl := func(c Code) { fmt.Fprintf(&buf, "%s\n\n", formatCode(c)) }
+		for _, f := range pkg.Funcs {
+			decl(f.Decl)
+		}
+		for _, typ := range pkg.Types {
+			decl(typ.Decl)
+			for _, f := range typ.Funcs {
+				decl(f.Decl)
+			}
+			for _, f := range typ.Methods {
+				decl(f.Decl)
+			}
+		}
+		got := buf.String()
+
+		golden := strings.TrimSuffix(name, ".go") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/doc/comment"
+	"net/url"
+	"strings"
+)
+
+type BlockKind int16
+
+const (
+	// Paragraph with the annotated text Text.
+	ParagraphBlock BlockKind = iota
+
+	// Heading with the annotated text Text and anchor ID.
+	HeadingBlock
+
+	// Preformatted text Text.
+	CodeBlock
+
+	// List with the items Items.
+	ListBlock
+)
+
+// Comment is a doc comment parsed into blocks. Doc links and URLs in the text
+// of paragraphs, headings and list items are annotated like the identifiers
+// in a declaration: links to exports are LinkAnnotations, links to packages
+// are PackageLinkAnnotations and other links are URLAnnotations.
+type Comment struct {
+	Blocks []*Block
+}
+
+type Block struct {
+	Kind  BlockKind
+	Text  Code
+	ID    string
+	Items []*ListItem
+}
+
+type ListItem struct {
+	// Number is the number of the item in a numbered list, or "" for an item
+	// in a bullet list.
+	Number string
+
+	Paragraphs []Code
+}
+
+// parseComment parses the doc comment text. Doc links are resolved by p.
+func parseComment(p *comment.Parser, text string) *Comment {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	d := p.Parse(text)
+	c := &Comment{}
+	for _, b := range d.Content {
+		switch b := b.(type) {
+		case *comment.Paragraph:
+			c.Blocks = append(c.Blocks, &Block{Kind: ParagraphBlock, Text: commentCode(b.Text)})
+		case *comment.Heading:
+			c.Blocks = append(c.Blocks, &Block{Kind: HeadingBlock, Text: commentCode(b.Text), ID: b.DefaultID()})
+		case *comment.Code:
+			c.Blocks = append(c.Blocks, &Block{Kind: CodeBlock, Text: Code{Text: b.Text}})
+		case *comment.List:
+			list := &Block{Kind: ListBlock}
+			for _, item := range b.Items {
+				li := &ListItem{Number: item.Number}
+				for _, content := range item.Content {
+					if p, ok := content.(*comment.Paragraph); ok {
+						li.Paragraphs = append(li.Paragraphs, commentCode(p.Text))
+					}
+				}
+				list.Items = append(list.Items, li)
+			}
+			c.Blocks = append(c.Blocks, list)
+		}
+	}
+	return c
+}
+
+// commentVisitor collects the text and annotations of doc comment text.
+type commentVisitor struct {
+	declVisitor
+	buf strings.Builder
+}
+
+func (v *commentVisitor) annotate(kind AnnotationKind, path string, pos int) {
+	v.add(kind, path)
+	a := &v.annotations[len(v.annotations)-1]
+	a.Pos = int32(pos)
+	a.End = int32(v.buf.Len())
+}
+
+func (v *commentVisitor) text(texts []comment.Text) {
+	for _, t := range texts {
+		pos := v.buf.Len()
+		switch t := t.(type) {
+		case comment.Plain:
+			v.buf.WriteString(string(t))
+		case comment.Italic:
+			v.buf.WriteString(string(t))
+		case *comment.Link:
+			v.text(t.Text)
+			if isSafeURL(t.URL) {
+				v.annotate(URLAnnotation, t.URL, pos)
+			}
+		case *comment.DocLink:
+			v.docLink(t)
+		}
+	}
+}
+
+// docLink adds the text of the doc link l. A qualified link is annotated as a
+// package link for the package name or import path and a link to the export
+// for the rest of the text.
+func (v *commentVisitor) docLink(l *comment.DocLink) {
+	pos := v.buf.Len()
+	var text strings.Builder
+	for _, t := range l.Text {
+		switch t := t.(type) {
+		case comment.Plain:
+			text.WriteString(string(t))
+		case comment.Italic:
+			text.WriteString(string(t))
+		}
+	}
+	s := text.String()
+	if l.Name == "" {
+		v.buf.WriteString(s)
+		v.annotate(PackageLinkAnnotation, l.ImportPath, pos)
+		return
+	}
+	name := l.Name
+	if l.Recv != "" {
+		name = l.Recv + "." + l.Name
+	}
+	if !strings.HasSuffix(s, name) {
+		v.buf.WriteString(s)
+		return
+	}
+	qualifier := strings.TrimPrefix(s[:len(s)-len(name)], "*")
+	v.buf.WriteString(s[:len(s)-len(name)-len(qualifier)])
+	if l.ImportPath != "" && strings.HasSuffix(qualifier, ".") {
+		pos := v.buf.Len()
+		v.buf.WriteString(qualifier[:len(qualifier)-1])
+		v.annotate(PackageLinkAnnotation, l.ImportPath, pos)
+		v.buf.WriteString(".")
+	} else {
+		v.buf.WriteString(qualifier)
+	}
+	pos = v.buf.Len()
+	v.buf.WriteString(name)
+	v.annotate(LinkAnnotation, l.ImportPath, pos)
+}
+
+// commentCode returns the doc comment text as annotated code.
+func commentCode(texts []comment.Text) Code {
+	v := &commentVisitor{declVisitor: declVisitor{pathIndex: make(map[string]int)}}
+	v.text(texts)
+	return Code{Text: v.buf.String(), Annotations: v.annotations, Paths: v.paths}
+}
+
+// isSafeURL returns true if a link to u can be shown.
+func isSafeURL(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(p.Scheme) {
+	case "http", "https", "ftp", "mailto":
+		return true
+	}
+	return false
+}
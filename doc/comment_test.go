@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const commentSrc = `// Package p has modern doc comments.
+//
+// # Usage
+//
+// Call [F] with a [*bytes.Buffer], or see [T.M], [io.Reader], [encoding/json.Marshal]
+// and [the spec].
+//
+//   - one
+//   - two
+//
+// Steps:
+//
+//  1. first
+//  2. second
+//
+// Example:
+//
+//	p.F()
+//
+// [the spec]: https://go.dev/ref/spec
+package p
+
+import "bytes"
+
+// F writes to [bytes.Buffer]. See https://example.com and [passwords].
+//
+// [passwords]: file:///etc/passwd
+func F(*bytes.Buffer) {}
+
+type T int
+
+// M is a method.
+func (T) M() {}
+`
+
+func TestParseComment(t *testing.T) {
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(commentSrc)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Comment == nil {
+		t.Fatal("package comment not parsed")
+	}
+	var got []string
+	for _, b := range pkg.Comment.Blocks {
+		switch b.Kind {
+		case ListBlock:
+			s := "list:"
+			for _, item := range b.Items {
+				s += " " + item.Number + "<" + formatCode(item.Paragraphs[0]) + ">"
+			}
+			got = append(got, s)
+		case HeadingBlock:
+			got = append(got, "heading "+b.ID+": "+formatCode(b.Text))
+		case CodeBlock:
+			got = append(got, "code: "+b.Text.Text)
+		default:
+			got = append(got, formatCode(b.Text))
+		}
+	}
+	want := []string{
+		"Package p has modern doc comments.",
+		"heading hdr-Usage: Usage",
+		"Call {link:F} with a *{pkg bytes:bytes}.{link bytes:Buffer}, or see {link:T.M}, {pkg io:io}.{link io:Reader}, {pkg encoding/json:encoding/json}.{link encoding/json:Marshal}\nand {url https://go.dev/ref/spec:the spec}.",
+		"list: <one> <two>",
+		"Steps:",
+		"list: 1<first> 2<second>",
+		"Example:",
+		"code: p.F()\n",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("blocks =\n%q\nwant\n%q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if got, want := formatCode(pkg.Funcs[0].Comment.Blocks[0].Text), "F writes to {pkg bytes:bytes}.{link bytes:Buffer}. See {url https://example.com:https://example.com} and passwords."; got != want {
+		t.Errorf("func comment = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import "strings"
+
+// deprecation returns the paragraph of the doc comment text which starts with
+// "Deprecated: ", joined into one line, or "" if there is no such paragraph.
+func deprecation(text string) string {
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "Deprecated: ") {
+			return strings.Join(strings.Fields(p), " ")
+		}
+	}
+	return ""
+}
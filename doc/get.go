@@ -12,11 +12,21 @@ import (
 	"go/doc"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/gddo/gosrc"
 )
 
+// Get gets the documentation for the package at importPath from the default
+// branch of its repository, along with the list of known versions.
 func Get(ctx context.Context, client *http.Client, importPath string, etag string) (*Package, error) {
+	return GetVersion(ctx, client, importPath, "", etag)
+}
+
+// GetVersion gets the documentation for the package at importPath at the
+// given version. The empty version selects the default branch.
+func GetVersion(ctx context.Context, client *http.Client, importPath, version, etag string) (*Package, error) {
 
 	const versionPrefix = PackageVersion + "-"
 
@@ -26,7 +36,7 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 		etag = ""
 	}
 
-	dir, err := gosrc.Get(ctx, client, importPath, etag)
+	dir, err := gosrc.GetVersion(ctx, client, importPath, version, etag)
 	if err != nil {
 		return nil, err
 	}
@@ -53,5 +63,61 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 		}
 	}
 
+	if version == "" {
+		// The version list is informational. Errors listing versions do not
+		// prevent documenting the package.
+		pdoc.Versions, _ = getVersions(ctx, client, dir)
+	}
+
 	return pdoc, nil
 }
+
+// versionListTTL is how long the version list of a project is reused for the
+// packages in the project.
+const versionListTTL = time.Hour
+
+type versionList struct {
+	etag     string // etag of the project root when the list was fetched
+	versions []string
+	fetched  time.Time
+}
+
+var versionLists = struct {
+	sync.Mutex
+	m map[string]*versionList
+}{m: make(map[string]*versionList)}
+
+// getVersions returns the known versions of the project containing dir. The
+// versions are listed once per project root for versionListTTL, or until the
+// project root is crawled at a new etag.
+func getVersions(ctx context.Context, client *http.Client, dir *gosrc.Directory) ([]string, error) {
+	if dir.ProjectRoot == "" {
+		return gosrc.GetVersions(ctx, client, dir.ImportPath)
+	}
+	isRoot := dir.ImportPath == dir.ProjectRoot
+	now := time.Now()
+	versionLists.Lock()
+	l := versionLists.m[dir.ProjectRoot]
+	versionLists.Unlock()
+	if l != nil && now.Sub(l.fetched) < versionListTTL && (!isRoot || l.etag == dir.Etag) {
+		return l.versions, nil
+	}
+
+	versions, err := gosrc.GetVersions(ctx, client, dir.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+	l = &versionList{versions: versions, fetched: now}
+	if isRoot {
+		l.etag = dir.Etag
+	}
+	versionLists.Lock()
+	for k, l := range versionLists.m {
+		if now.Sub(l.fetched) >= versionListTTL {
+			delete(versionLists.m, k)
+		}
+	}
+	versionLists.m[dir.ProjectRoot] = l
+	versionLists.Unlock()
+	return versions, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+func TestGetVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doc-proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	vdir := filepath.Join(dir, "example.com", "m", "@v")
+	if err := os.MkdirAll(vdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	setList := func(list string) {
+		if err := ioutil.WriteFile(filepath.Join(vdir, "list"), []byte(list), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gosrc.SetModuleProxy("file://" + filepath.ToSlash(dir)); err != nil {
+		t.Fatal(err)
+	}
+	defer gosrc.SetModuleProxy("")
+	ctx := context.Background()
+
+	setList("v1.0.0\n")
+	for _, tt := range []struct {
+		importPath, etag string
+		want             []string
+	}{
+		{"example.com/m", "1", []string{"v1.0.0"}},
+		// The list is fetched once for the packages of a project, even if
+		// it changed.
+		{"example.com/m/sub", "", []string{"v1.0.0"}},
+		{"example.com/m", "1", []string{"v1.0.0"}},
+		// The list is fetched again when the project root changes.
+		{"example.com/m", "2", []string{"v1.1.0", "v1.0.0"}},
+	} {
+		if tt.importPath == "example.com/m/sub" {
+			setList("v1.0.0\nv1.1.0\n")
+		}
+		versions, err := getVersions(ctx, nil, &gosrc.Directory{
+			ImportPath:  tt.importPath,
+			ProjectRoot: "example.com/m",
+			Etag:        tt.etag,
+		})
+		if err != nil {
+			t.Fatalf("getVersions(%s) returned error %v", tt.importPath, err)
+		}
+		if !reflect.DeepEqual(versions, tt.want) {
+			t.Errorf("getVersions(%s, %s) = %v, want %v", tt.importPath, tt.etag, versions, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"strconv"
+)
+
+// TypeRef refers to a named type. Pointer is set when only the pointer type
+// *Name implements the interface which refers to it.
+type TypeRef struct {
+	ImportPath string // package declaring the type, "" for this package
+	Name       string
+	Pointer    bool
+}
+
+// wellKnownInterfaces are the standard library interfaces for which
+// implementations are listed. The methods are in the form of methodKey.
+var wellKnownInterfaces = []struct {
+	importPath, name string
+	methods          []string
+}{
+	{"builtin", "error", []string{"Error() string"}},
+	{"encoding", "BinaryMarshaler", []string{"MarshalBinary() ([]byte, error)"}},
+	{"encoding", "BinaryUnmarshaler", []string{"UnmarshalBinary([]byte) error"}},
+	{"encoding", "TextMarshaler", []string{"MarshalText() ([]byte, error)"}},
+	{"encoding", "TextUnmarshaler", []string{"UnmarshalText([]byte) error"}},
+	{"encoding/json", "Marshaler", []string{"MarshalJSON() ([]byte, error)"}},
+	{"encoding/json", "Unmarshaler", []string{"UnmarshalJSON([]byte) error"}},
+	{"flag", "Value", []string{"Set(string) error", "String() string"}},
+	{"fmt", "Formatter", []string{"Format(fmt.State, rune)"}},
+	{"fmt", "GoStringer", []string{"GoString() string"}},
+	{"fmt", "Stringer", []string{"String() string"}},
+	{"io", "ByteReader", []string{"ReadByte() (byte, error)"}},
+	{"io", "Closer", []string{"Close() error"}},
+	{"io", "ReadCloser", []string{"Close() error", "Read([]byte) (int, error)"}},
+	{"io", "ReadWriteCloser", []string{"Close() error", "Read([]byte) (int, error)", "Write([]byte) (int, error)"}},
+	{"io", "ReadWriter", []string{"Read([]byte) (int, error)", "Write([]byte) (int, error)"}},
+	{"io", "Reader", []string{"Read([]byte) (int, error)"}},
+	{"io", "ReaderAt", []string{"ReadAt([]byte, int64) (int, error)"}},
+	{"io", "ReaderFrom", []string{"ReadFrom(io.Reader) (int64, error)"}},
+	{"io", "Seeker", []string{"Seek(int64, int) (int64, error)"}},
+	{"io", "StringWriter", []string{"WriteString(string) (int, error)"}},
+	{"io", "WriteCloser", []string{"Close() error", "Write([]byte) (int, error)"}},
+	{"io", "Writer", []string{"Write([]byte) (int, error)"}},
+	{"io", "WriterAt", []string{"WriteAt([]byte, int64) (int, error)"}},
+	{"io", "WriterTo", []string{"WriteTo(io.Writer) (int64, error)"}},
+	{"net/http", "Handler", []string{"ServeHTTP(http.ResponseWriter, *http.Request)"}},
+	{"sort", "Interface", []string{"Len() int", "Less(int, int) bool", "Swap(int, int)"}},
+}
+
+// methodKey returns the name and signature of a method without parameter
+// names, such as "Read([]byte) (int, error)".
+func methodKey(name string, t *ast.FuncType) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	writeSignature(&buf, t)
+	return buf.String()
+}
+
+// methodSet is the method set of a type found from syntax. The methods are
+// in the form of methodKey.
+type methodSet struct {
+	value   map[string]bool // methods of T
+	pointer map[string]bool // methods of *T, including those of T
+
+	// The type is an interface or a constraint.
+	isInterface, isConstraint bool
+
+	// Some methods are unknown, such as those of embedded types declared in
+	// other packages or unexported interface methods.
+	incomplete bool
+}
+
+func (ms *methodSet) add(key string, pointerOnly bool) {
+	if !pointerOnly {
+		ms.value[key] = true
+	}
+	ms.pointer[key] = true
+}
+
+// implements returns true if the type implements an interface with the
+// methods. Pointer is set if only the pointer type implements it.
+func (ms *methodSet) implements(methods []string) (ok, pointer bool) {
+	for _, key := range methods {
+		if !ms.pointer[key] {
+			return false, false
+		}
+		if !ms.value[key] {
+			pointer = true
+		}
+	}
+	return true, pointer
+}
+
+// methodSetBuilder computes the method sets of the types of a package.
+type methodSetBuilder struct {
+	types map[string]*doc.Type
+	sets  map[string]*methodSet
+}
+
+func (msb *methodSetBuilder) methodSet(name string) *methodSet {
+	if ms, ok := msb.sets[name]; ok {
+		// A nil method set is being computed for a recursive embedding,
+		// which is invalid.
+		return ms
+	}
+	d := msb.types[name]
+	if d == nil {
+		return nil
+	}
+	msb.sets[name] = nil
+	ms := &methodSet{value: make(map[string]bool), pointer: make(map[string]bool)}
+	for _, m := range d.Methods {
+		ms.add(methodKey(m.Name, m.Decl.Type), len(m.Recv) > 0 && m.Recv[0] == '*')
+	}
+	for _, spec := range d.Decl.Specs {
+		s, ok := spec.(*ast.TypeSpec)
+		if !ok || s.Name.Name != d.Name {
+			continue
+		}
+		if s.TypeParams != nil {
+			// Signatures of generic types refer to type parameters.
+			ms.incomplete = true
+		}
+		switch t := s.Type.(type) {
+		case *ast.InterfaceType:
+			ms.isInterface = true
+			ms.incomplete = ms.incomplete || t.Incomplete
+			for _, f := range t.Methods.List {
+				if ft, ok := f.Type.(*ast.FuncType); ok && len(f.Names) > 0 {
+					for _, n := range f.Names {
+						ms.add(methodKey(n.Name, ft), false)
+					}
+					continue
+				}
+				msb.embed(ms, f.Type)
+			}
+		case *ast.StructType:
+			for _, f := range t.Fields.List {
+				if len(f.Names) == 0 {
+					msb.embed(ms, f.Type)
+				}
+			}
+		}
+	}
+	msb.sets[name] = ms
+	return ms
+}
+
+// embed adds the methods of the embedded type x to ms.
+func (msb *methodSetBuilder) embed(ms *methodSet, x ast.Expr) {
+	pointer := false
+	if star, ok := x.(*ast.StarExpr); ok {
+		x, pointer = star.X, true
+	}
+	switch t := x.(type) {
+	case *ast.Ident:
+		if e := msb.methodSet(t.Name); e != nil {
+			ms.incomplete = ms.incomplete || e.incomplete
+			if ms.isInterface && !e.isInterface {
+				// A type term of a constraint.
+				ms.isConstraint = true
+			}
+			for key := range e.value {
+				ms.add(key, false)
+			}
+			for key := range e.pointer {
+				ms.add(key, !pointer && !e.value[key])
+			}
+			return
+		}
+		if t.Name == "error" && t.Obj == nil {
+			ms.add("Error() string", false)
+			return
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Obj != nil && pkg.Obj.Kind == ast.Pkg {
+			if spec, ok := pkg.Obj.Decl.(*ast.ImportSpec); ok {
+				importPath, _ := strconv.Unquote(spec.Path.Value)
+				for _, wk := range wellKnownInterfaces {
+					if wk.importPath == importPath && wk.name == t.Sel.Name {
+						for _, key := range wk.methods {
+							ms.add(key, false)
+						}
+						return
+					}
+				}
+			}
+		}
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		// Type set terms such as ~int | ~string.
+		ms.isConstraint = true
+	}
+	ms.incomplete = true
+}
+
+// implementations sets Type.Implements of the non-interface types of pkg and
+// Type.Implementers of the interfaces of pkg. The method sets are found from
+// syntax. Interfaces with unknown methods are ignored and types with unknown
+// methods may not list all of the interfaces they implement.
+func implementations(pkg *Package, tdocs []*doc.Type) {
+	if pkg.ImportPath == "builtin" {
+		return
+	}
+	msb := &methodSetBuilder{
+		types: make(map[string]*doc.Type),
+		sets:  make(map[string]*methodSet),
+	}
+	for _, d := range tdocs {
+		msb.types[d.Name] = d
+	}
+
+	type iface struct {
+		ref     TypeRef
+		methods []string
+		typ     *Type
+	}
+	var ifaces []iface
+	for i, d := range tdocs {
+		ms := msb.methodSet(d.Name)
+		if ms == nil || !ms.isInterface || ms.isConstraint || ms.incomplete || len(ms.value) == 0 {
+			continue
+		}
+		var methods []string
+		for key := range ms.value {
+			methods = append(methods, key)
+		}
+		ifaces = append(ifaces, iface{ref: TypeRef{Name: d.Name}, methods: methods, typ: pkg.Types[i]})
+	}
+	for _, wk := range wellKnownInterfaces {
+		if wk.importPath != pkg.ImportPath {
+			ifaces = append(ifaces, iface{ref: TypeRef{ImportPath: wk.importPath, Name: wk.name}, methods: wk.methods})
+		}
+	}
+
+	for i, d := range tdocs {
+		ms := msb.methodSet(d.Name)
+		if ms == nil || ms.isInterface || len(ms.pointer) == 0 {
+			continue
+		}
+		for _, it := range ifaces {
+			ok, pointer := ms.implements(it.methods)
+			if !ok {
+				continue
+			}
+			ref := it.ref
+			ref.Pointer = pointer
+			pkg.Types[i].Implements = append(pkg.Types[i].Implements, &ref)
+			if it.typ != nil {
+				it.typ.Implementers = append(it.typ.Implementers, &TypeRef{Name: d.Name, Pointer: pointer})
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+	"github.com/google/go-cmp/cmp"
+)
+
+const implementsSrc = `package p
+
+import (
+	"io"
+	"other.example.com/x"
+)
+
+type Shape interface {
+	Area() float64
+	fmt() string
+}
+
+type Sizer interface {
+	Size() int
+}
+
+type SizeCloser interface {
+	Sizer
+	io.Closer
+}
+
+type Remote interface {
+	x.Interface
+}
+
+type Number interface {
+	~int | ~float64
+}
+
+type File struct{}
+
+func (f *File) Size() int                         { return 0 }
+func (f *File) Close() error                      { return nil }
+func (f *File) Read(p []byte) (n int, err error)  { return 0, nil }
+
+type Name string
+
+func (n Name) String() string { return string(n) }
+func (n Name) Size() int      { return len(n) }
+
+type Wrapper struct {
+	*File
+	x.Base
+}
+
+type Err struct {
+	Name
+}
+
+func (Err) Error() string { return "" }
+`
+
+func TestImplementations(t *testing.T) {
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(implementsSrc)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]*TypeRef)
+	for _, typ := range pkg.Types {
+		if typ.Implements != nil {
+			got[typ.Name] = typ.Implements
+		}
+		if typ.Implementers != nil {
+			got[typ.Name+" by"] = typ.Implementers
+		}
+	}
+	want := map[string][]*TypeRef{
+		"File": {
+			{Name: "SizeCloser", Pointer: true},
+			{Name: "Sizer", Pointer: true},
+			{ImportPath: "io", Name: "Closer", Pointer: true},
+			{ImportPath: "io", Name: "ReadCloser", Pointer: true},
+			{ImportPath: "io", Name: "Reader", Pointer: true},
+		},
+		"Name": {
+			{Name: "Sizer"},
+			{ImportPath: "fmt", Name: "Stringer"},
+		},
+		"Wrapper": {
+			{Name: "SizeCloser"},
+			{Name: "Sizer"},
+			{ImportPath: "io", Name: "Closer"},
+			{ImportPath: "io", Name: "ReadCloser"},
+			{ImportPath: "io", Name: "Reader"},
+		},
+		"Err": {
+			{Name: "Sizer"},
+			{ImportPath: "builtin", Name: "error"},
+			{ImportPath: "fmt", Name: "Stringer"},
+		},
+		"SizeCloser by": {{Name: "File", Pointer: true}, {Name: "Wrapper"}},
+		"Sizer by":      {{Name: "Err"}, {Name: "File", Pointer: true}, {Name: "Name"}, {Name: "Wrapper"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("implementations mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Markdown is converted to HTML by goldmark with the GitHub Flavored Markdown
+// extensions. Raw HTML in the document is kept by goldmark, so the link and
+// image URLs of the output are rewritten and the headings are moved below the
+// headings of the page. The result is then sanitized by bluemonday, which
+// keeps only the elements and attributes in mdPolicy.
+
+// mdHeadingOffset is added to the level of headings so that README headings
+// nest below the headings of the page.
+const mdHeadingOffset = 2
+
+// mdIDPrefix is the prefix of the ids of README headings, which keeps them
+// apart from the ids of the page.
+const mdIDPrefix = "readme-"
+
+var mdConverter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+	goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(mdTransformer{}, 100))),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+var mdPolicy = newMDPolicy()
+
+func newMDPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements(
+		"b", "blockquote", "br", "code", "del", "details", "em", "hr", "i",
+		"kbd", "li", "ol", "p", "pre", "s", "strong", "sub", "summary", "sup",
+		"tbody", "thead", "tr", "ul")
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^`+mdIDPrefix+`[\p{L}\p{N}_-]*$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("src", "alt").OnElements("img")
+	p.AllowAttrs("title").OnElements("a", "img")
+	p.AllowAttrs("width", "height").Matching(regexp.MustCompile(`^[0-9]+%?$`)).OnElements("img")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^table table-condensed$`)).OnElements("table")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(?:left|center|right)$`)).OnElements("th", "td")
+	p.AllowElements("th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	return p
+}
+
+// renderMarkdown converts the Markdown document src to HTML. The linkURL
+// function returns the URL to use for a link or image destination, or "" to
+// drop the link or image.
+func renderMarkdown(src string, linkURL func(u string, image bool) string) string {
+	var buf bytes.Buffer
+	if err := mdConverter.Convert([]byte(src), &buf); err != nil {
+		return ""
+	}
+	return mdPolicy.Sanitize(rewriteHTML(buf.String(), linkURL))
+}
+
+// mdTransformer gives the headings of a document unique ids and the tables
+// the style of the page.
+type mdTransformer struct{}
+
+func (mdTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	src := reader.Source()
+	used := make(map[string]bool)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindHeading:
+			id := mdIDPrefix + mdSlug(mdText(n, src))
+			if id == mdIDPrefix {
+				id += "heading"
+			}
+			for i := 1; used[id]; i++ {
+				id = strings.TrimSuffix(id, "-"+strconv.Itoa(i-1)) + "-" + strconv.Itoa(i)
+			}
+			used[id] = true
+			n.SetAttributeString("id", []byte(id))
+			return ast.WalkSkipChildren, nil
+		case extast.KindTable:
+			n.SetAttributeString("class", []byte("table table-condensed"))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// mdText returns the text of the inline content of n without markup.
+func mdText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			switch n := n.(type) {
+			case *ast.Text:
+				b.Write(n.Segment.Value(src))
+			case *ast.String:
+				b.Write(n.Value)
+			case *ast.AutoLink:
+				b.Write(n.Label(src))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// mdSlug returns the anchor name of a heading with the text s, as done by
+// GitHub.
+func mdSlug(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// rewriteHTML rewrites the links and images of the HTML document s with
+// linkURL and moves the headings down by mdHeadingOffset levels. Links with
+// dropped URLs keep their text and images with dropped URLs are replaced by
+// their alternate text. Only the first element with an id keeps it. The
+// document is parsed as a browser would, so the result has no unclosed or
+// stray tags.
+func rewriteHTML(s string, linkURL func(u string, image bool) string) string {
+	context := &xhtml.Node{Type: xhtml.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(s), context)
+	if err != nil {
+		return ""
+	}
+	ids := make(map[string]bool)
+	var rewrite func(n *xhtml.Node)
+	rewrite = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode {
+			switch n.Data {
+			case "a":
+				n.Attr = rewriteAttr(n.Attr, "href", func(v string) string { return linkURL(v, false) })
+			case "img":
+				n.Attr = rewriteAttr(n.Attr, "src", func(v string) string { return linkURL(v, true) })
+				if !hasAttr(n.Attr, "src") {
+					alt := ""
+					for _, a := range n.Attr {
+						if a.Key == "alt" {
+							alt = a.Val
+							break
+						}
+					}
+					*n = xhtml.Node{Type: xhtml.TextNode, Data: alt, Parent: n.Parent, PrevSibling: n.PrevSibling, NextSibling: n.NextSibling}
+					return
+				}
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				level := int(n.Data[1]-'0') + mdHeadingOffset
+				if level > 6 {
+					level = 6
+				}
+				n.Data = "h" + strconv.Itoa(level)
+				n.DataAtom = atom.Lookup([]byte(n.Data))
+			}
+			n.Attr = rewriteAttr(n.Attr, "id", func(v string) string {
+				if ids[v] {
+					return ""
+				}
+				ids[v] = true
+				return v
+			})
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rewrite(c)
+		}
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		rewrite(n)
+		if err := xhtml.Render(&b, n); err != nil {
+			return ""
+		}
+	}
+	return b.String()
+}
+
+func hasAttr(attrs []xhtml.Attribute, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteAttr replaces the value v of the first attribute named key in attrs
+// with f(v) and removes the others. The attribute is removed if f returns "".
+func rewriteAttr(attrs []xhtml.Attribute, key string, f func(v string) string) []xhtml.Attribute {
+	result := attrs[:0]
+	seen := false
+	for _, a := range attrs {
+		if a.Key == key {
+			if seen {
+				continue
+			}
+			// Browsers use the first of duplicate attributes.
+			seen = true
+			if a.Val = f(a.Val); a.Val == "" {
+				continue
+			}
+		}
+		result = append(result, a)
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"sort"
+)
+
+// buildEnv is a package built for one of goEnvs.
+type buildEnv struct {
+	goos, goarch string
+	bpkg         *build.Package
+	dpkg         *doc.Package
+}
+
+func (env *buildEnv) platform() string {
+	return env.goos + "/" + env.goarch
+}
+
+// envUnion returns the sorted union of the lists returned by list for the
+// packages built for envs.
+func envUnion(envs []*buildEnv, list func(*build.Package) []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, env := range envs {
+		for _, s := range list(env.bpkg) {
+			if !seen[s] {
+				seen[s] = true
+				result = append(result, s)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// mergePlatforms merges the declarations of the packages built for envs into
+// dpkg and records the platforms providing each declaration. Functions and
+// types are merged by name; when the declaration of a function or type
+// differs between platforms, the declaration of each platform is recorded
+// with it. Constants and variables with the same text are merged, and each
+// version of one with a platform specific value is kept. The declarations of
+// the primary environment, envs[0], come first.
+func (b *builder) mergePlatforms(dpkg *doc.Package, envs []*buildEnv) {
+	b.platforms = make(map[interface{}][]string)
+	b.platformDecls = make(map[interface{}][]*platformDecl)
+	b.redeclared = make(map[*doc.Value]bool)
+	b.numPlatforms = len(envs)
+	var merged doc.Package
+	for _, env := range envs {
+		p := env.platform()
+		merged.Consts = b.mergeValues(merged.Consts, env.dpkg.Consts, p)
+		merged.Vars = b.mergeValues(merged.Vars, env.dpkg.Vars, p)
+		merged.Funcs = b.mergeFuncs(merged.Funcs, env.dpkg.Funcs, p)
+		merged.Types = b.mergeTypes(merged.Types, env.dpkg.Types, p)
+	}
+	sort.Stable(byFuncName(merged.Funcs))
+	sort.SliceStable(merged.Types, func(i, j int) bool { return merged.Types[i].Name < merged.Types[j].Name })
+	for _, t := range merged.Types {
+		sort.Stable(byFuncName(t.Funcs))
+		sort.Stable(byFuncName(t.Methods))
+	}
+	dpkg.Consts = merged.Consts
+	dpkg.Vars = merged.Vars
+	dpkg.Funcs = merged.Funcs
+	dpkg.Types = merged.Types
+}
+
+func (b *builder) addPlatform(d interface{}, platform string) {
+	p := b.platforms[d]
+	if len(p) == 0 || p[len(p)-1] != platform {
+		b.platforms[d] = append(p, platform)
+	}
+}
+
+// platformsOf returns the platforms providing the declaration d, or nil if d
+// is provided by all platforms.
+func (b *builder) platformsOf(d interface{}) []string {
+	p := b.platforms[d]
+	if len(p) == b.numPlatforms {
+		return nil
+	}
+	return p
+}
+
+// declsOf returns the declarations of the function or type d on the
+// platforms providing it, or nil if the platforms declare d the same way.
+// Only the first declaration, which is the declaration of d, has anchors.
+func (b *builder) declsOf(d interface{}) []*PlatformDecl {
+	pds := b.platformDecls[d]
+	if len(pds) < 2 {
+		return nil
+	}
+	result := make([]*PlatformDecl, len(pds))
+	for i, pd := range pds {
+		decl := b.printDecl(pd.decl)
+		if i > 0 {
+			decl = withoutAnchors(decl)
+		}
+		result[i] = &PlatformDecl{Decl: decl, Pos: b.position(pd.decl), Platforms: pd.platforms}
+	}
+	return result
+}
+
+// withoutAnchors returns code without the anchors of the declared names, for
+// a declaration of names which are anchored elsewhere.
+func withoutAnchors(code Code) Code {
+	annotations := make([]Annotation, 0, len(code.Annotations))
+	for _, a := range code.Annotations {
+		if a.Kind != AnchorAnnotation && a.Kind != TypeParamAnchorAnnotation {
+			annotations = append(annotations, a)
+		}
+	}
+	code.Annotations = annotations
+	return code
+}
+
+// declKey returns the key identifying a declaration when merging platforms:
+// its text without comments.
+func (b *builder) declKey(decl ast.Node) string {
+	var buf bytes.Buffer
+	if err := declPrinter.Fprint(&buf, b.fset, decl); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// platformDecl is a declaration of a function or type on some platforms.
+type platformDecl struct {
+	key       string
+	decl      ast.Decl
+	platforms []string
+}
+
+// addPlatformDecl records that platform declares the function or type d as
+// decl.
+func (b *builder) addPlatformDecl(d interface{}, decl ast.Decl, platform string) {
+	key := b.declKey(decl)
+	for _, pd := range b.platformDecls[d] {
+		if pd.key == key {
+			if pd.platforms[len(pd.platforms)-1] != platform {
+				pd.platforms = append(pd.platforms, platform)
+			}
+			return
+		}
+	}
+	b.platformDecls[d] = append(b.platformDecls[d], &platformDecl{key: key, decl: decl, platforms: []string{platform}})
+}
+
+var declPrinter = &printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 4}
+
+// mergeValues merges src into dst. Constants and variables are identified by
+// their names and the text of the specs declaring them, so each is
+// documented once per distinct declaration. The specs of a group which are
+// already documented are removed from it, and the platforms of a group are
+// those providing any of its names. Groups declaring a name documented by an
+// earlier group are recorded in b.redeclared.
+func (b *builder) mergeValues(dst, src []*doc.Value, platform string) []*doc.Value {
+	index := make(map[string]*doc.Value)
+	names := make(map[string]bool)
+	for _, v := range dst {
+		for _, key := range b.specKeys(v.Decl) {
+			index[key] = v
+		}
+		for _, name := range v.Names {
+			names[name] = true
+		}
+	}
+	for _, v := range src {
+		keys := b.specKeys(v.Decl)
+		var specs []ast.Spec
+		var specKeys []string
+		for i, spec := range v.Decl.Specs {
+			if d := index[keys[i]]; d != nil {
+				b.addPlatform(d, platform)
+			} else {
+				specs = append(specs, spec)
+				specKeys = append(specKeys, keys[i])
+			}
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		d := v
+		if len(specs) < len(v.Decl.Specs) {
+			decl := *v.Decl
+			decl.Specs = specs
+			c := *v
+			c.Decl = &decl
+			c.Names = nil
+			for _, spec := range specs {
+				for _, n := range spec.(*ast.ValueSpec).Names {
+					c.Names = append(c.Names, n.Name)
+				}
+			}
+			d = &c
+		}
+		for _, key := range specKeys {
+			index[key] = d
+		}
+		for _, name := range d.Names {
+			if names[name] {
+				b.redeclared[d] = true
+			}
+			names[name] = true
+		}
+		b.addPlatform(d, platform)
+		dst = append(dst, d)
+	}
+	return dst
+}
+
+// specKeys returns the keys identifying the specs of a constant or variable
+// declaration when merging platforms. The specs of a constant group with
+// implicitly repeated values depend on each other and have the text of the
+// whole group as their key.
+func (b *builder) specKeys(decl *ast.GenDecl) []string {
+	keys := make([]string, len(decl.Specs))
+	if decl.Tok == token.CONST {
+		for _, spec := range decl.Specs {
+			if len(spec.(*ast.ValueSpec).Values) == 0 {
+				key := b.declKey(decl)
+				for i := range keys {
+					keys[i] = key
+				}
+				return keys
+			}
+		}
+	}
+	for i, spec := range decl.Specs {
+		keys[i] = b.declKey(spec)
+	}
+	return keys
+}
+
+// mergeFuncs merges src into dst. Functions and methods are identified by
+// their names.
+func (b *builder) mergeFuncs(dst, src []*doc.Func, platform string) []*doc.Func {
+	index := make(map[string]*doc.Func)
+	for _, f := range dst {
+		index[f.Name] = f
+	}
+	for _, f := range src {
+		d := index[f.Name]
+		if d == nil {
+			d = f
+			index[f.Name] = d
+			dst = append(dst, d)
+		}
+		b.addPlatform(d, platform)
+		b.addPlatformDecl(d, f.Decl, platform)
+	}
+	return dst
+}
+
+// mergeTypes merges src into dst. Types are identified by their names and
+// the associated declarations of a type are merged into it.
+func (b *builder) mergeTypes(dst, src []*doc.Type, platform string) []*doc.Type {
+	index := make(map[string]*doc.Type)
+	for _, t := range dst {
+		index[t.Name] = t
+	}
+	for _, t := range src {
+		d := index[t.Name]
+		if d == nil {
+			// The associated declarations are merged below.
+			c := *t
+			c.Consts, c.Vars, c.Funcs, c.Methods = nil, nil, nil, nil
+			d = &c
+			index[t.Name] = d
+			dst = append(dst, d)
+		}
+		b.addPlatform(d, platform)
+		b.addPlatformDecl(d, t.Decl, platform)
+		d.Consts = b.mergeValues(d.Consts, t.Consts, platform)
+		d.Vars = b.mergeValues(d.Vars, t.Vars, platform)
+		d.Funcs = b.mergeFuncs(d.Funcs, t.Funcs, platform)
+		d.Methods = b.mergeFuncs(d.Methods, t.Methods, platform)
+	}
+	return dst
+}
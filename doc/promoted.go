@@ -0,0 +1,210 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Promoted is the set of fields and methods promoted to a type from one of
+// the types embedded in it, directly or through other embedded types.
+type Promoted struct {
+	ImportPath string // package declaring the embedded type, "" for this package
+	Type       string // name of the embedded type
+	Fields     []*PromotedMember
+	Methods    []*PromotedMember
+}
+
+// PromotedMember is a field or method promoted from an embedded type.
+type PromotedMember struct {
+	Name string
+	Decl string // field declaration or method signature
+}
+
+// PackageGetter returns the stored documentation of the package at
+// importPath, or nil if the package is not known.
+type PackageGetter func(ctx context.Context, importPath string) (*Package, error)
+
+// Promote sets the promoted fields and methods of the types of pkg. The
+// documentation of embedded types declared in other packages is read with
+// get. If get is nil, only embedded types of pkg are used.
+func Promote(ctx context.Context, pkg *Package, get PackageGetter) {
+	p := &promoter{ctx: ctx, pkg: pkg, get: get}
+	p.promoteTypes()
+}
+
+type promoter struct {
+	ctx  context.Context
+	pkg  *Package
+	get  PackageGetter
+	pkgs map[string]*Package // by import path, nil if not found
+}
+
+// embedding is an embedded type with the import path of its package.
+type embedding struct {
+	importPath string
+	name       string
+}
+
+func (p *promoter) promoteTypes() {
+	for _, t := range p.pkg.Types {
+		t.Promoted = p.promoted(t)
+	}
+}
+
+// promoted returns the members promoted to t. At each depth of embedding,
+// a member is promoted if no shallower type declares its name and exactly
+// one embedded type at the depth declares it.
+func (p *promoter) promoted(t *Type) []*Promoted {
+	fields, methods, next := typeMembers(p.pkg.ImportPath, t)
+	hidden := make(map[string]bool)
+	for _, m := range fields {
+		hidden[m.Name] = true
+	}
+	for _, m := range methods {
+		hidden[m.Name] = true
+	}
+	visited := map[embedding]bool{{p.pkg.ImportPath, t.Name}: true}
+
+	var result []*Promoted
+	for len(next) > 0 {
+		level := next
+		next = nil
+		var promoted []*Promoted
+		count := make(map[string]int)
+		for _, e := range level {
+			if visited[e] {
+				continue
+			}
+			visited[e] = true
+			et := p.lookup(e)
+			if et == nil {
+				continue
+			}
+			fields, methods, embedded := typeMembers(e.importPath, et)
+			for _, m := range fields {
+				count[m.Name]++
+			}
+			for _, m := range methods {
+				count[m.Name]++
+			}
+			pr := &Promoted{Type: e.name, Fields: fields, Methods: methods}
+			if e.importPath != p.pkg.ImportPath {
+				pr.ImportPath = e.importPath
+			}
+			promoted = append(promoted, pr)
+			next = append(next, embedded...)
+		}
+		keep := func(members []*PromotedMember) []*PromotedMember {
+			var kept []*PromotedMember
+			for _, m := range members {
+				if count[m.Name] == 1 && !hidden[m.Name] {
+					kept = append(kept, m)
+				}
+			}
+			return kept
+		}
+		for _, pr := range promoted {
+			pr.Fields = keep(pr.Fields)
+			pr.Methods = keep(pr.Methods)
+			if len(pr.Fields) > 0 || len(pr.Methods) > 0 {
+				result = append(result, pr)
+			}
+		}
+		for name := range count {
+			hidden[name] = true
+		}
+	}
+	return result
+}
+
+// lookup returns the type of an embedding, or nil if the type is not known.
+func (p *promoter) lookup(e embedding) *Type {
+	pkg := p.pkg
+	if e.importPath != p.pkg.ImportPath {
+		if p.get == nil {
+			return nil
+		}
+		var ok bool
+		pkg, ok = p.pkgs[e.importPath]
+		if !ok {
+			// Promoted members are informational. Errors reading the
+			// stored documentation do not prevent documenting the
+			// package.
+			pkg, _ = p.get(p.ctx, e.importPath)
+			if p.pkgs == nil {
+				p.pkgs = make(map[string]*Package)
+			}
+			p.pkgs[e.importPath] = pkg
+		}
+		if pkg == nil {
+			return nil
+		}
+	}
+	for _, t := range pkg.Types {
+		if t.Name == e.name {
+			return t
+		}
+	}
+	return nil
+}
+
+// typeMembers returns the exported fields and methods declared by the type t
+// of the package importPath, and the types embedded in t.
+func typeMembers(importPath string, t *Type) (fields, methods []*PromotedMember, embedded []embedding) {
+	if d, ok := parseDecl(t.Decl.Text).(*ast.GenDecl); ok {
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok || s.Name.Name != t.Name {
+				continue
+			}
+			switch st := s.Type.(type) {
+			case *ast.StructType:
+				for _, f := range st.Fields.List {
+					typ := types.ExprString(f.Type)
+					if len(f.Names) == 0 {
+						if n := embeddedName(f.Type); n != nil && n.IsExported() {
+							fields = append(fields, &PromotedMember{Name: n.Name, Decl: typ})
+						}
+					}
+					for _, n := range f.Names {
+						if n.IsExported() {
+							fields = append(fields, &PromotedMember{Name: n.Name, Decl: n.Name + " " + typ})
+						}
+					}
+				}
+			case *ast.InterfaceType:
+				for _, f := range st.Methods.List {
+					for _, n := range f.Names {
+						if n.IsExported() {
+							sig := strings.TrimPrefix(types.ExprString(f.Type), "func")
+							methods = append(methods, &PromotedMember{Name: n.Name, Decl: n.Name + sig})
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, m := range t.Methods {
+		methods = append(methods, &PromotedMember{Name: m.Name, Decl: m.Decl.Text})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	for _, e := range t.Embedded {
+		ip := e.ImportPath
+		if ip == "" {
+			ip = importPath
+		}
+		embedded = append(embedded, embedding{ip, e.Name})
+	}
+	return fields, methods, embedded
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+	"github.com/google/go-cmp/cmp"
+)
+
+const promotedSrcQ = `package q
+
+type Base struct {
+	ID   int
+	Name string
+}
+
+func (b *Base) Close() error { return nil }
+
+func (Base) String() string { return "" }
+`
+
+const promotedSrcP = `package p
+
+import (
+	"io"
+
+	"example.com/q"
+)
+
+type Inner struct {
+	Name  string
+	Count int
+}
+
+func (Inner) Len() int { return 0 }
+
+type Outer struct {
+	Inner
+	*q.Base
+	io.Reader
+	Count int
+}
+
+type Both struct {
+	Outer
+}
+`
+
+func TestPromote(t *testing.T) {
+	build := func(importPath, src string) *Package {
+		pkg, err := newPackage(&gosrc.Directory{
+			ImportPath: importPath,
+			Files:      []*gosrc.File{{Name: "p.go", Data: []byte(src)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	q := build("example.com/q", promotedSrcQ)
+	p := build("example.com/p", promotedSrcP)
+
+	promoted := func() map[string][]*Promoted {
+		m := make(map[string][]*Promoted)
+		for _, typ := range p.Types {
+			if typ.Promoted != nil {
+				m[typ.Name] = typ.Promoted
+			}
+		}
+		return m
+	}
+
+	// Without stored documentation, only types of the package are used.
+	want := map[string][]*Promoted{
+		"Outer": {{Type: "Inner", Fields: []*PromotedMember{{Name: "Name", Decl: "Name string"}}, Methods: []*PromotedMember{{Name: "Len", Decl: "func (Inner) Len() int"}}}},
+		"Both": {
+			{Type: "Outer", Fields: []*PromotedMember{{Name: "Base", Decl: "*q.Base"}, {Name: "Count", Decl: "Count int"}, {Name: "Inner", Decl: "Inner"}, {Name: "Reader", Decl: "io.Reader"}}},
+			{Type: "Inner", Fields: []*PromotedMember{{Name: "Name", Decl: "Name string"}}, Methods: []*PromotedMember{{Name: "Len", Decl: "func (Inner) Len() int"}}},
+		},
+	}
+	if diff := cmp.Diff(want, promoted()); diff != "" {
+		t.Errorf("promoted from package (-want +got):\n%s", diff)
+	}
+
+	Promote(context.Background(), p, func(ctx context.Context, importPath string) (*Package, error) {
+		if importPath == "example.com/q" {
+			return q, nil
+		}
+		return nil, nil
+	})
+	// Name is declared by both Inner and q.Base at the same depth.
+	base := &Promoted{
+		ImportPath: "example.com/q",
+		Type:       "Base",
+		Fields:     []*PromotedMember{{Name: "ID", Decl: "ID int"}},
+		Methods:    []*PromotedMember{{Name: "Close", Decl: "func (b *Base) Close() error"}, {Name: "String", Decl: "func (Base) String() string"}},
+	}
+	inner := &Promoted{Type: "Inner", Methods: []*PromotedMember{{Name: "Len", Decl: "func (Inner) Len() int"}}}
+	want = map[string][]*Promoted{
+		"Outer": {inner, base},
+		"Both": {
+			{Type: "Outer", Fields: []*PromotedMember{{Name: "Base", Decl: "*q.Base"}, {Name: "Count", Decl: "Count int"}, {Name: "Inner", Decl: "Inner"}, {Name: "Reader", Decl: "io.Reader"}}},
+			inner,
+			base,
+		},
+	}
+	if diff := cmp.Diff(want, promoted()); diff != "" {
+		t.Errorf("promoted with stored documentation (-want +got):\n%s", diff)
+	}
+}
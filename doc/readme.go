@@ -0,0 +1,120 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// maxReadmeSize is the size of the largest README file that is rendered.
+const maxReadmeSize = 512 << 10
+
+var readmePat = regexp.MustCompile(`(?i)^readme(?:$|\.)`)
+
+// isMarkdown returns true if the file with name n is a Markdown file.
+func isMarkdown(n string) bool {
+	switch strings.ToLower(path.Ext(n)) {
+	case ".md", ".markdown", ".mdown", ".mkd", ".mkdn":
+		return true
+	}
+	return false
+}
+
+// renderReadme returns the README file of a directory rendered as HTML, or
+// "" if the directory has no README file. Markdown is preferred over other
+// formats, which are shown as plain text. Relative links and images are
+// resolved against the README file's browse URL, which includes the revision,
+// or else against browseURL, the browse URL of the directory.
+func renderReadme(files []*gosrc.File, browseURL string) string {
+	var readme *gosrc.File
+	for _, f := range files {
+		if !readmePat.MatchString(f.Name) || len(f.Data) > maxReadmeSize {
+			continue
+		}
+		if readme == nil || isMarkdown(f.Name) && !isMarkdown(readme.Name) {
+			readme = f
+		}
+	}
+	if readme == nil {
+		return ""
+	}
+	data := string(readme.Data)
+	if !utf8.ValidString(data) {
+		data = strings.ToValidUTF8(data, "\uFFFD")
+	}
+	if !isMarkdown(readme.Name) {
+		return "<pre>" + html.EscapeString(data) + "</pre>\n"
+	}
+	base := readme.BrowseURL
+	if base == "" && browseURL != "" {
+		base = strings.TrimSuffix(browseURL, "/") + "/"
+	}
+	return renderMarkdown(data, readmeURLFn(base))
+}
+
+// readmeURLFn returns the function used to resolve the URLs of links and
+// images in a README file. Only web and mail URLs are kept. Relative URLs are
+// resolved against baseURL, the location of the README file on the version
+// control service website, and dropped if it is not known.
+func readmeURLFn(baseURL string) func(u string, image bool) string {
+	var base *url.URL
+	if u, err := url.Parse(baseURL); err == nil && u.IsAbs() {
+		base = u
+		base.RawQuery = ""
+		base.Fragment = ""
+	}
+	return func(s string, image bool) string {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return ""
+		}
+		if strings.HasPrefix(s, "#") && !image {
+			// Headings have ids with this prefix.
+			return "#readme-" + strings.ToLower(s[1:])
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return ""
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https":
+			return u.String()
+		case "mailto":
+			if image {
+				return ""
+			}
+			return u.String()
+		case "":
+			if u.Host != "" {
+				u.Scheme = "https"
+				return u.String()
+			}
+		default:
+			return ""
+		}
+		if base == nil {
+			return ""
+		}
+		u = base.ResolveReference(u)
+		if image && u.Host == "github.com" {
+			// Serve the file itself instead of its page.
+			parts := strings.SplitN(u.Path, "/", 5)
+			if len(parts) == 5 && (parts[3] == "tree" || parts[3] == "blob") {
+				parts[3] = "raw"
+				u.Path = strings.Join(parts, "/")
+			}
+		}
+		return u.String()
+	}
+}
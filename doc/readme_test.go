@@ -0,0 +1,253 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+var markdownTests = []struct {
+	name, src, want string
+}{
+	{"heading", "# Title\n\nSome *text* and **bold**.\n",
+		"<h3 id=\"readme-title\">Title</h3>\n<p>Some <em>text</em> and <strong>bold</strong>.</p>\n"},
+	{"setext", "Install\n-------\n", "<h4 id=\"readme-install\">Install</h4>\n"},
+	{"escape", "a < b & c \\*d\\* &copy;", "<p>a &lt; b &amp; c *d* ©</p>\n"},
+	{"code span", "Use `x < y` here.", "<p>Use <code>x &lt; y</code> here.</p>\n"},
+	{"fenced", "```go\nfunc f() {}\n```\n", "<pre><code class=\"language-go\">func f() {}\n</code></pre>\n"},
+	{"indented", "    go get example.com/x\n", "<pre><code>go get example.com/x\n</code></pre>\n"},
+	{"list", "- one\n- two\n  more\n\n1. a\n", "<ul>\n<li>one</li>\n<li>two\nmore</li>\n</ul>\n<ol>\n<li>a</li>\n</ol>\n"},
+	{"quote", "> note\n", "<blockquote>\n<p>note</p>\n</blockquote>\n"},
+	{"rule", "a\n\n***\n", "<p>a</p>\n<hr/>\n"},
+	{"link", "[docs](doc/x.md) [site](https://example.com \"Site\") [top](#Usage)",
+		"<p><a href=\"https://github.com/o/r/blob/main/sub/doc/x.md\" rel=\"nofollow\">docs</a> <a href=\"https://example.com\" title=\"Site\" rel=\"nofollow\">site</a> <a href=\"#readme-usage\" rel=\"nofollow\">top</a></p>\n"},
+	{"badge", "[![Build](ci.svg)][ci]\n\n[ci]: https://ci.example.com/o/r\n",
+		"<p><a href=\"https://ci.example.com/o/r\" rel=\"nofollow\"><img src=\"https://github.com/o/r/raw/main/sub/ci.svg\" alt=\"Build\"/></a></p>\n"},
+	{"unsafe link", "[x](javascript:alert(1)) ![y](data:image/png;base64,AAAA)", "<p>x y</p>\n"},
+	{"autolink", "See <https://example.com> or https://golang.org.", "<p>See <a href=\"https://example.com\" rel=\"nofollow\">https://example.com</a> or <a href=\"https://golang.org\" rel=\"nofollow\">https://golang.org</a>.</p>\n"},
+	{"inline html", "a <span onclick=\"x()\">b</span><br>c", "<p>a b<br/>c</p>\n"},
+	{"html block", "<p align=\"center\">\n  <img src=\"logo.png\" onerror=\"x()\" width=\"100\">\n  <script>alert(1)</script>\n</p>\n",
+		"<p>\n  <img src=\"https://github.com/o/r/raw/main/sub/logo.png\" width=\"100\"/>\n  \n</p>\n"},
+	{"html heading", "<h1 align=\"center\">Logo</h1>\n", "<h3>Logo</h3>\n"},
+	{"unbalanced html", "<details><summary>More</summary>\n\ntext\n", "<details><summary>More</summary>\n<p>text</p>\n</details>"},
+	{"table", "| a | b |\n|---|--:|\n| 1 | `\\|` |\n", "<table class=\"table table-condensed\">\n<thead>\n<tr>\n<th>a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td align=\"right\"><code>|</code></td>\n</tr>\n</tbody>\n</table>\n"},
+	{"hard break", "a  \nb", "<p>a<br/>\nb</p>\n"},
+	{"snake_case", "use snake_case_names", "<p>use snake_case_names</p>\n"},
+	{"task list", "- [x] done\n", "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"/> done</li>\n</ul>\n"},
+	{"heading ids", "# Usage\n\n## Usage\n\n### Usage-1\n\n# `go get` *now*\n",
+		"<h3 id=\"readme-usage\">Usage</h3>\n<h4 id=\"readme-usage-1\">Usage</h4>\n<h5 id=\"readme-usage-1-1\">Usage-1</h5>\n<h3 id=\"readme-go-get-now\"><code>go get</code> <em>now</em></h3>\n"},
+	{"heading without text", "# <img src=\"logo.png\">\n\n# !!\n",
+		"<h3 id=\"readme-heading\"><img src=\"https://github.com/o/r/raw/main/sub/logo.png\"/></h3>\n<h3 id=\"readme-heading-1\">!!</h3>\n"},
+}
+
+// markdownSanitizeTests are documents which try to get markup other than the
+// allowed tags, attributes and URLs past the renderer.
+var markdownSanitizeTests = []struct {
+	name, src, want string
+}{
+	{"entity in scheme", `<a href="jav&#x61;script:alert(1)">x</a>`, "<p>x</p>\n"},
+	{"decimal entity", `<a href="&#106;avascript:alert(1)">x</a>`, "<p>x</p>\n"},
+	{"named entity", `<a href="javascript&colon;alert(1)">x</a>`, "<p>x</p>\n"},
+	{"newline in scheme", `<a href="java&#x0A;script:alert(1)">x</a>`, "<p>x</p>\n"},
+	{"tab in scheme", `<a href="java&#9;script:alert(1)">x</a>`, "<p>x</p>\n"},
+	{"leading space", `<a href=" &#14;javascript:alert(1)">x</a>`, "<p>x</p>\n"},
+	{"unquoted", `<a href=javascript:alert(1)>x</a>`, "<p>x</p>\n"},
+	{"upper case", `<IMG SRC="JaVaScRiPt:alert(1)">`, ""},
+	{"attribute in alt", `<img src="x.png" alt="&quot; onerror=&quot;alert(1)">`,
+		"<img src=\"https://github.com/o/r/raw/main/sub/x.png\" alt=\"&#34; onerror=&#34;alert(1)\"/>"},
+	{"attribute in value", `<img title='src="javascript:alert(1)"' src="logo.png">`,
+		"<img title=\"src=&#34;javascript:alert(1)&#34;\" src=\"https://github.com/o/r/raw/main/sub/logo.png\"/>"},
+	{"slash separator", `<img src="logo.png"/onerror="alert(1)">`,
+		"<p>&lt;img src=&#34;logo.png&#34;/onerror=&#34;alert(1)&#34;&gt;</p>\n"},
+	{"no separator", `<a href="https://example.com" title="x"onclick="y">z</a>`,
+		"<p>&lt;a href=&#34;https://example.com&#34; title=&#34;x&#34;onclick=&#34;y&#34;&gt;z</p>\n"},
+	{"bad width", `<img src=logo.png onerror=alert(1) width="1 onload=x">`,
+		"<img src=\"https://github.com/o/r/raw/main/sub/logo.png\"/>"},
+	{"duplicate attribute", `<img src="javascript:alert(1)" src="logo.png">`, ""},
+	{"unterminated tag", "<p><img src=\"x\" onerror=\"alert(1)\"\n", "<p></p>"},
+	{"unterminated quote", "<p><a href=\"https://example.com>click</a>\n", "<p></p>"},
+	{"unterminated comment", "<p>\n<!-- <img src=x onerror=alert(1)>\n", "<p>\n</p>"},
+	{"split tag", "<scr<script>ipt>alert(1)</script>", "<p>&lt;scr</p>\n"},
+	{"nested html", `<details><summary><script>alert(1)</script></summary><a href="https://a.example"><a href="https://b.example">x</a></details></p></div>`,
+		"<details><summary></summary><a href=\"https://a.example\" rel=\"nofollow\"></a><a href=\"https://b.example\" rel=\"nofollow\">x</a></details><p></p>"},
+	{"raw text elements", `<div><style>body{}</style><iframe src="https://evil.example"></iframe><svg onload=alert(1)></svg></div>`, ""},
+	{"inline script", "a <script>alert(1)</script> b", "<p>a  b</p>\n"},
+	{"tag in attribute", `<p title="<script>">x</p>`, "<p>x</p>"},
+	{"markdown link schemes", "[x](jav&#x61;script:alert(1)) [y](javascript&#58;alert(1)) [z](<javascript:alert(1)>)",
+		"<p>x y z</p>\n"},
+	{"reference scheme", "[x][r]\n\n[r]: javascript:alert(1)\n", "<p>x</p>\n"},
+	{"autolink schemes", "<javascript:alert(1)> <vbscript:x> <data:text/html,x> <file:///etc/passwd>",
+		"<p>javascript:alert(1) vbscript:x data:text/html,x file:///etc/passwd</p>\n"},
+	{"escaped title quote", `[x](https://a.example "t\" onmouseover=\"x")`,
+		"<p><a href=\"https://a.example\" title=\"t&#34; onmouseover=&#34;x\" rel=\"nofollow\">x</a></p>\n"},
+	{"html in alt", `![<img src=x onerror=alert(1)>"](a.png)`, "<p><img src=\"https://github.com/o/r/raw/main/sub/a.png\" alt=\"&#34;\"/></p>\n"},
+	{"heading id", `# a" onclick="x`, "<h3 id=\"readme-a-onclickx\">a&#34; onclick=&#34;x</h3>\n"},
+	{"raw heading id", "# Install\n\n<h2 id=\"readme-install\">x</h2>\n<h2 id=\"top\">y</h2>\n",
+		"<h3 id=\"readme-install\">Install</h3>\n<h4>x</h4>\n<h4>y</h4>\n"},
+	{"fence info", "```go\" onclick=\"x\ncode\n```\n", "<pre><code>code\n</code></pre>\n"},
+	{"bare url", `https://a.example/"><script>alert(1)</script>`,
+		"<p><a href=\"https://a.example/%22%3E\" rel=\"nofollow\">https://a.example/&#34;&gt;</a></p>\n"},
+	{"escaped entities", "&lt;script&gt; &#60;script&#62; &amp;lt;", "<p>&lt;script&gt; &lt;script&gt; &amp;lt;</p>\n"},
+	{"link in link", `[<a href="https://x.example">y</a>](https://z.example)`,
+		"<p><a href=\"https://z.example\" rel=\"nofollow\"></a><a href=\"https://x.example\" rel=\"nofollow\">y</a></p>\n"},
+	{"destination escapes", "[x](a\\_b.md) [y](a&amp;b.md)",
+		"<p><a href=\"https://github.com/o/r/blob/main/sub/a_b.md\" rel=\"nofollow\">x</a> <a href=\"https://github.com/o/r/blob/main/sub/a&amp;b.md\" rel=\"nofollow\">y</a></p>\n"},
+	{"style", `<p style="position:fixed" class="x">a</p>`, "<p>a</p>"},
+}
+
+func TestRenderMarkdownSanitize(t *testing.T) {
+	linkURL := readmeURLFn("https://github.com/o/r/blob/main/sub/README.md")
+	for _, tt := range markdownSanitizeTests {
+		if got := renderMarkdown(tt.src, linkURL); got != tt.want {
+			t.Errorf("%s: renderMarkdown(%q) =\n%q\nwant\n%q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	linkURL := readmeURLFn("https://github.com/o/r/blob/main/sub/README.md")
+	for _, tt := range markdownTests {
+		if got := renderMarkdown(tt.src, linkURL); got != tt.want {
+			t.Errorf("%s: renderMarkdown(%q) =\n%q\nwant\n%q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+// markdownAttrs are the attributes allowed on the elements of rendered
+// Markdown.
+var markdownAttrs = map[string][]string{
+	"a": {"href", "rel", "title"}, "b": nil, "blockquote": nil, "br": nil,
+	"code": {"class"}, "del": nil, "details": nil, "em": nil,
+	"h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"}, "hr": nil,
+	"i": nil, "img": {"alt", "height", "src", "title", "width"},
+	"input": {"checked", "disabled", "type"}, "kbd": nil, "li": nil,
+	"ol": {"start"}, "p": nil, "pre": nil, "s": nil, "strong": nil,
+	"sub": nil, "summary": nil, "sup": nil, "table": {"class"}, "tbody": nil,
+	"td": {"align"}, "th": {"align"}, "thead": nil, "tr": nil, "ul": nil,
+}
+
+// checkMarkdownHTML returns an error if the rendered Markdown s has elements
+// or attributes which are not allowed, URLs other than web and mail URLs
+// and links to the README headings, or duplicate ids.
+func checkMarkdownHTML(s string) error {
+	ids := make(map[string]bool)
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return nil
+			}
+			return z.Err()
+		case html.CommentToken, html.DoctypeToken:
+			return fmt.Errorf("unexpected %s", z.Token())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			allowed, ok := markdownAttrs[t.Data]
+			if !ok {
+				return fmt.Errorf("unexpected element %s", t)
+			}
+			for _, a := range t.Attr {
+				if !contains(allowed, a.Key) {
+					return fmt.Errorf("unexpected attribute %s in %s", a.Key, t)
+				}
+				switch a.Key {
+				case "href", "src":
+					u, err := url.Parse(a.Val)
+					if err != nil {
+						return fmt.Errorf("bad URL in %s: %v", t, err)
+					}
+					switch {
+					case u.Scheme == "http" || u.Scheme == "https":
+					case u.Scheme == "mailto" && a.Key == "href":
+					case strings.HasPrefix(a.Val, "#readme-") && a.Key == "href":
+					default:
+						return fmt.Errorf("unexpected URL in %s", t)
+					}
+				case "id":
+					if !strings.HasPrefix(a.Val, "readme-") || ids[a.Val] {
+						return fmt.Errorf("bad or duplicate id in %s", t)
+					}
+					ids[a.Val] = true
+				}
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func FuzzRenderMarkdown(f *testing.F) {
+	for _, tt := range markdownTests {
+		f.Add(tt.src)
+	}
+	for _, tt := range markdownSanitizeTests {
+		f.Add(tt.src)
+	}
+	linkURL := readmeURLFn("https://github.com/o/r/blob/main/sub/README.md")
+	f.Fuzz(func(t *testing.T, src string) {
+		got := renderMarkdown(src, linkURL)
+		if err := checkMarkdownHTML(got); err != nil {
+			t.Errorf("renderMarkdown(%q) = %q: %v", src, got, err)
+		}
+	})
+}
+
+func TestRenderReadme(t *testing.T) {
+	files := []*gosrc.File{
+		{Name: "README.rst", Data: []byte("Title\n=====\n")},
+		{Name: "x.go", Data: []byte("package x")},
+	}
+	if got, want := renderReadme(files, ""), "<pre>Title\n=====\n</pre>\n"; got != want {
+		t.Errorf("renderReadme(rst) = %q, want %q", got, want)
+	}
+	files = append(files, &gosrc.File{Name: "README.md", Data: []byte("[x](y)")})
+	if got, want := renderReadme(files, ""), "<p>x</p>\n"; got != want {
+		t.Errorf("renderReadme(md) = %q, want %q", got, want)
+	}
+	if got := renderReadme(files[1:2], ""); got != "" {
+		t.Errorf("renderReadme(no readme) = %q, want empty", got)
+	}
+}
+
+func TestRenderReadmeRoot(t *testing.T) {
+	// The browse URL of a repository root does not include the revision,
+	// so relative URLs are resolved against the README file's browse URL.
+	readme := &gosrc.File{
+		Name:      "README.md",
+		Data:      []byte("[docs](doc/x.md) ![logo](img/logo.png)"),
+		BrowseURL: "https://github.com/o/r/blob/main/README.md",
+	}
+	got := renderReadme([]*gosrc.File{readme}, "https://github.com/o/r")
+	want := "<p><a href=\"https://github.com/o/r/blob/main/doc/x.md\" rel=\"nofollow\">docs</a> <img src=\"https://github.com/o/r/raw/main/img/logo.png\" alt=\"logo\"/></p>\n"
+	if got != want {
+		t.Errorf("renderReadme(root) =\n%q\nwant\n%q", got, want)
+	}
+
+	// Without the file's browse URL, the directory's browse URL is used.
+	readme.BrowseURL = ""
+	got = renderReadme([]*gosrc.File{readme}, "https://git.example.com/projects/P/repos/r/browse")
+	want = "<p><a href=\"https://git.example.com/projects/P/repos/r/browse/doc/x.md\" rel=\"nofollow\">docs</a> <img src=\"https://git.example.com/projects/P/repos/r/browse/img/logo.png\" alt=\"logo\"/></p>\n"
+	if got != want {
+		t.Errorf("renderReadme(root without file URL) =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/doc"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// valueSince returns the earliest Go version which added one of the names
+// declared by d, or "" if a name is in Go 1.0.
+func (b *builder) valueSince(d *doc.Value) string {
+	since := ""
+	for _, name := range d.Names {
+		if !token.IsExported(name) {
+			continue
+		}
+		v := b.since[name]
+		if v == "" {
+			return ""
+		}
+		if since == "" || versionLess(v, since) {
+			since = v
+		}
+	}
+	return since
+}
+
+// versionLess returns true if the Go version a, such as "1.9", is before b.
+func versionLess(a, b string) bool {
+	for a != "" || b != "" {
+		var x, y string
+		x, a = cutDot(a)
+		y, b = cutDot(b)
+		m, _ := strconv.Atoi(x)
+		n, _ := strconv.Atoi(y)
+		if m != n {
+			return m < n
+		}
+	}
+	return false
+}
+
+func cutDot(s string) (before, after string) {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
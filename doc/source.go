@@ -0,0 +1,152 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+)
+
+// SourceFile is the source of a Go file annotated with comments, keywords,
+// literals and links to the documentation of the identifiers.
+type SourceFile struct {
+	Name string
+	Code Code
+}
+
+const (
+	// maxSourceFileSize is the size of the largest Go file stored with a
+	// package.
+	maxSourceFileSize = 256 * 1024
+
+	// maxSourceSize is the total size of the Go files stored with a
+	// package.
+	maxSourceSize = 2 * 1024 * 1024
+)
+
+// addSources annotates the source of the files of apkg which are not stored
+// with pkg yet.
+func (b *builder) addSources(pkg *Package, apkg *ast.Package) {
+	size := 0
+	for _, f := range pkg.Sources {
+		size += len(f.Code.Text)
+	}
+	names := make([]string, 0, len(apkg.Files))
+	for name := range apkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src := b.srcs[name]
+		if src == nil || pkg.Files[src.index].Source {
+			continue
+		}
+		if len(src.data) > maxSourceFileSize || size+len(src.data) > maxSourceSize {
+			continue
+		}
+		size += len(src.data)
+		pkg.Files[src.index].Source = true
+		pkg.Sources = append(pkg.Sources, &SourceFile{
+			Name: name,
+			Code: b.annotateSource(pkg.ImportPath, apkg, apkg.Files[name], src.data),
+		})
+	}
+	sort.Slice(pkg.Sources, func(i, j int) bool { return pkg.Sources[i].Name < pkg.Sources[j].Name })
+}
+
+// annotateSource returns the source src of file with annotations. Exported
+// package-level identifiers link to their documentation in the package
+// importPath.
+func (b *builder) annotateSource(importPath string, apkg *ast.Package, file *ast.File, src []byte) Code {
+	v := &declVisitor{pathIndex: make(map[string]int)}
+	tf := b.fset.File(file.Pos())
+
+	// Find the annotations of identifiers by offset.
+	idents := make(map[int]Annotation)
+	ident := func(id *ast.Ident, kind AnnotationKind, path string) {
+		v.add(kind, path)
+		idents[tf.Offset(id.Pos())] = v.annotations[len(v.annotations)-1]
+	}
+	// Selected fields and methods, method names and composite literal keys
+	// are not resolved by the parser. They are not predeclared identifiers
+	// even if their names are.
+	names := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv != nil {
+				names[n.Name] = true
+			}
+		case *ast.KeyValueExpr:
+			if key, ok := n.Key.(*ast.Ident); ok {
+				names[key] = true
+			}
+		case *ast.SelectorExpr:
+			names[n.Sel] = true
+			x, _ := n.X.(*ast.Ident)
+			if x == nil || x.Obj == nil || x.Obj.Kind != ast.Pkg {
+				return true
+			}
+			spec, _ := x.Obj.Decl.(*ast.ImportSpec)
+			if spec == nil {
+				return true
+			}
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || path == "C" {
+				return true
+			}
+			ident(x, PackageLinkAnnotation, path)
+			if n.Sel.IsExported() {
+				ident(n.Sel, LinkAnnotation, path)
+			}
+			return false
+		case *ast.Ident:
+			switch {
+			case n.Obj == nil && !names[n] && predeclared[n.Name] != notPredeclared:
+				ident(n, BuiltinAnnotation, "")
+			case n.Obj != nil && n.IsExported() && apkg.Scope.Lookup(n.Name) == n.Obj:
+				ident(n, LinkAnnotation, importPath)
+			}
+		}
+		return true
+	})
+
+	var annotations []Annotation
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(src))
+	s.Init(f, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		p := f.Offset(pos)
+		a := Annotation{Pos: int32(p), End: int32(p + len(lit)), PathIndex: -1}
+		switch {
+		case tok == token.COMMENT:
+			a.Kind = CommentAnnotation
+		case tok == token.STRING || tok == token.CHAR:
+			a.Kind = StringAnnotation
+		case tok.IsKeyword():
+			a.Kind = KeywordAnnotation
+		case tok == token.IDENT:
+			ia, ok := idents[p]
+			if !ok {
+				continue
+			}
+			a.Kind, a.PathIndex = ia.Kind, ia.PathIndex
+		default:
+			continue
+		}
+		annotations = append(annotations, a)
+	}
+	return Code{Text: string(src), Annotations: annotations, Paths: v.paths}
+}
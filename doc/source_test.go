@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const sourceSrc = `package p
+
+import "strings"
+
+// T is a type.
+type T struct{ s string }
+
+func (t T) Upper() T {
+	return T{strings.ToUpper(t.s) + "!"}
+}
+
+func helper(n int) int { return len("x") + n }
+`
+
+func TestSources(t *testing.T) {
+	big := "package p\n\nvar Big = `" + strings.Repeat("x", maxSourceFileSize) + "`\n"
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte(sourceSrc)},
+			{Name: "big.go", Data: []byte(big)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range pkg.Files {
+		if want := f.Name == "p.go"; f.Source != want {
+			t.Errorf("%s: Source = %v, want %v", f.Name, f.Source, want)
+		}
+	}
+	if len(pkg.Sources) != 1 {
+		t.Fatalf("got %d sources, want 1", len(pkg.Sources))
+	}
+	got := formatCode(pkg.Sources[0].Code)
+	want := `{kwd:package} p
+
+{kwd:import} {str:"strings"}
+
+// T is a type.
+{kwd:type} {link example.com/p:T} {kwd:struct}{ s {builtin:string} }
+
+{kwd:func} (t {link example.com/p:T}) Upper() {link example.com/p:T} {
+	{kwd:return} {link example.com/p:T}{{pkg strings:strings}.{link strings:ToUpper}(t.s) + {str:"!"}}
+}
+
+{kwd:func} helper(n {builtin:int}) {builtin:int} { {kwd:return} {builtin:len}({str:"x"}) + n }
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+const sourceFieldsSrc = `package p
+
+type T struct{ max, len int }
+
+func (t T) cap() int { return t.max + t.len }
+
+func F() T { return T{len: len("x"), max: 1} }
+`
+
+func TestSourceFieldNames(t *testing.T) {
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(sourceFieldsSrc)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Sources) != 1 {
+		t.Fatalf("got %d sources, want 1", len(pkg.Sources))
+	}
+	got := formatCode(pkg.Sources[0].Code)
+	want := `{kwd:package} p
+
+{kwd:type} {link example.com/p:T} {kwd:struct}{ max, len {builtin:int} }
+
+{kwd:func} (t {link example.com/p:T}) cap() {builtin:int} { {kwd:return} t.max + t.len }
+
+{kwd:func} {link example.com/p:F}() {link example.com/p:T} { {kwd:return} {link example.com/p:T}{len: {builtin:len}({str:"x"}), max: 1} }
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSourcesNotRedistributable(t *testing.T) {
+	pkg, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(sourceSrc)}},
+		Licenses:   []*gosrc.License{{Path: "LICENSE"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkg.NotRedistributable {
+		t.Fatal("package is redistributable, want not redistributable")
+	}
+	if len(pkg.Sources) != 0 || pkg.Files[0].Source {
+		t.Errorf("got %d sources, file source %v; want no sources", len(pkg.Sources), pkg.Files[0].Source)
+	}
+}
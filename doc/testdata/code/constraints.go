@@ -0,0 +1,32 @@
+// Package constraints defines constraints for type parameters.
+package constraints
+
+// Signed is a constraint for signed integer types.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is a constraint for unsigned integer types.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Integer is a constraint for integer types.
+type Integer interface {
+	Signed | Unsigned
+}
+
+// Ordered is a constraint for ordered types.
+type Ordered interface {
+	Integer | ~float32 | ~float64 | ~string
+}
+
+// Set is a constraint for set types with elements of type E.
+type Set[E comparable] interface {
+	~map[E]struct{}
+	Has(e E) bool
+	comparable
+}
+
+// Max returns the larger of a and b.
+func Max[T Ordered](a, b T) T { return max(a, b) }
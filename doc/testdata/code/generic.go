@@ -0,0 +1,37 @@
+// Package generic has generic functions and types.
+package generic
+
+import "strings"
+
+// Map returns the results of applying f to the elements of s.
+func Map[S ~[]E, E, R any](s S, f func(E) R) []R { return nil }
+
+// Keys returns the keys of m.
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+
+// Join joins the strings of b.
+func Join(b *strings.Builder, l List[string]) Pair[int, string] { return Pair[int, string]{} }
+
+// List is a linked list.
+type List[T any] struct {
+	Next  *List[T]
+	Value T
+}
+
+// Push adds v to the front of the list.
+func (l *List[T]) Push(v T) *List[T] { return nil }
+
+// Each calls f for each element. The receiver renames the type parameter.
+func (l *List[E]) Each(f func(E)) {}
+
+// NewList returns a list with the values vs.
+func NewList[T any](vs ...T) *List[T] { return nil }
+
+// Pair is a pair of values.
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+// Swap returns the pair with the key and value swapped.
+func (p Pair[K, V]) Swap() Pair[V, K] { return Pair[V, K]{} }
@@ -6,9 +6,21 @@
 
 package doc
 
+import "strings"
+
 type sliceWriter struct{ p *[]byte }
 
 func (w sliceWriter) Write(p []byte) (int, error) {
 	*w.p = append(*w.p, p...)
 	return len(p), nil
 }
+
+// recvType returns the name of the receiver type recv, "T", "*T" or "*T[E]",
+// without the pointer and type parameters.
+func recvType(recv string) string {
+	recv = strings.TrimPrefix(recv, "*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return recv
+}
@@ -9,6 +9,7 @@ package main
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/trace"
@@ -21,14 +22,25 @@ func (s *server) doCrawl(ctx context.Context) error {
 	defer span.Finish()
 	ctx = trace.NewContext(ctx, span)
 
-	// Look for new package to crawl.
-	importPath, hasSubdirs, err := s.db.PopNewCrawl()
-	if err != nil {
-		log.Printf("db.PopNewCrawl() returned error %v", err)
-		return nil
+	// Look for new package to crawl. The new packages are not crawled while
+	// crawling of GitHub repositories is paused, because most of them are
+	// on GitHub. The existing packages on other services are crawled instead.
+	importPath, hasSubdirs := "", false
+	if _, paused := s.gitHubPaused("github.com/"); !paused {
+		var err error
+		importPath, hasSubdirs, err = s.db.PopNewCrawl()
+		if err != nil {
+			log.Printf("db.PopNewCrawl() returned error %v", err)
+			return nil
+		}
 	}
 	if importPath != "" {
-		if pdoc, err := s.crawlDoc(ctx, "new", importPath, nil, hasSubdirs, time.Time{}); pdoc == nil && err == nil {
+		pdoc, err := s.crawlDoc(ctx, "new", importPath, nil, hasSubdirs, time.Time{})
+		if _, ok := err.(*gosrc.RateLimitError); ok {
+			if err := s.db.AddNewCrawl(importPath); err != nil {
+				log.Printf("ERROR db.AddNewCrawl(%q): %v", importPath, err)
+			}
+		} else if pdoc == nil && err == nil {
 			if err := s.db.AddBadCrawl(importPath); err != nil {
 				log.Printf("ERROR db.AddBadCrawl(%q): %v", importPath, err)
 			}
@@ -45,15 +57,48 @@ func (s *server) doCrawl(ctx context.Context) error {
 	if pdoc == nil || nextCrawl.After(time.Now()) {
 		return nil
 	}
+	if until, paused := s.gitHubPaused(pdoc.ImportPath); paused {
+		// Touch package so that crawl advances to a package on another
+		// service.
+		if err := s.db.SetNextCrawl(pdoc.ImportPath, until); err != nil {
+			log.Printf("ERROR db.SetNextCrawl(%q): %v", pdoc.ImportPath, err)
+		}
+		return nil
+	}
 	if _, err = s.crawlDoc(ctx, "crawl", pdoc.ImportPath, pdoc, len(pkgs) > 0, nextCrawl); err != nil {
 		// Touch package so that crawl advances to next package.
-		if err := s.db.SetNextCrawl(pdoc.ImportPath, time.Now().Add(s.v.GetDuration(ConfigMaxAge)/3)); err != nil {
+		next := time.Now().Add(s.v.GetDuration(ConfigMaxAge) / 3)
+		if e, ok := err.(*gosrc.RateLimitError); ok {
+			// Retry as soon as the rate limit is reset.
+			next = e.Reset
+		}
+		if err := s.db.SetNextCrawl(pdoc.ImportPath, next); err != nil {
 			log.Printf("ERROR db.SetNextCrawl(%q): %v", pdoc.ImportPath, err)
 		}
 	}
 	return nil
 }
 
+// pauseGitHub pauses crawling of GitHub repositories until reset.
+func (s *server) pauseGitHub(reset time.Time) {
+	s.gitHubPause.Lock()
+	defer s.gitHubPause.Unlock()
+	if reset.After(s.gitHubPause.until) {
+		s.gitHubPause.until = reset
+	}
+}
+
+// gitHubPaused reports whether importPath is served by GitHub and crawling of
+// GitHub repositories is paused. It also returns the time when the pause ends.
+func (s *server) gitHubPaused(importPath string) (time.Time, bool) {
+	if !strings.HasPrefix(importPath, "github.com/") && !strings.HasPrefix(importPath, "gist.github.com/") {
+		return time.Time{}, false
+	}
+	s.gitHubPause.Lock()
+	defer s.gitHubPause.Unlock()
+	return s.gitHubPause.until, time.Now().Before(s.gitHubPause.until)
+}
+
 func (s *server) readGitHubUpdates(ctx context.Context) error {
 	span := s.traceClient.NewSpan("GitHubUpdates")
 	defer span.Finish()
@@ -64,7 +109,13 @@ func (s *server) readGitHubUpdates(ctx context.Context) error {
 	if err := s.db.GetGob(key, &last); err != nil {
 		return err
 	}
+	if _, paused := s.gitHubPaused("github.com/"); paused {
+		return nil
+	}
 	last, names, err := gosrc.GetGitHubUpdates(ctx, s.httpClient, last)
+	if e, ok := err.(*gosrc.RateLimitError); ok {
+		s.pauseGitHub(e.Reset)
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestDoCrawlGitHubPaused(t *testing.T) {
+	db := newTestDB(t)
+	defer func() {
+		c := db.Pool.Get()
+		c.Do("FLUSHDB")
+		c.Close()
+	}()
+	s := &server{db: db}
+	ctx := context.Background()
+
+	queue := []string{"github.com/user/a", "github.com/user/b"}
+	for _, p := range queue {
+		if err := db.AddNewCrawl(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An existing package due to be crawled.
+	pdoc := &doc.Package{ImportPath: "github.com/user/old", Name: "old"}
+	if err := db.Put(ctx, pdoc, time.Now().Add(-time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	s.pauseGitHub(until)
+	for i := 0; i < 3; i++ {
+		if err := s.doCrawl(ctx); err != nil {
+			t.Fatalf("doCrawl returned error %v", err)
+		}
+	}
+
+	// The new packages stay in the queue and the crawl advances past the
+	// existing GitHub package.
+	c := db.Pool.Get()
+	defer c.Close()
+	got, err := redis.Strings(c.Do("SMEMBERS", "newCrawl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if !cmp.Equal(got, queue) {
+		t.Errorf("new crawl queue = %v, want %v", got, queue)
+	}
+	_, _, nextCrawl, err := db.Get(ctx, "github.com/user/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nextCrawl.Equal(until) {
+		t.Errorf("next crawl of existing package = %v, want %v", nextCrawl, until)
+	}
+}
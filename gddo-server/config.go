@@ -12,6 +12,8 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
 	"github.com/golang/gddo/log"
 )
 
@@ -44,6 +46,7 @@ const (
 	ConfigSidebar        = "sidebar"
 	ConfigSourcegraphURL = "sourcegraph_url"
 	ConfigDefaultGOOS    = "default_goos"
+	ConfigAnalyses       = "analyses"
 	ConfigGAAccount      = "ga_account"
 
 	// Crawl Config
@@ -55,6 +58,13 @@ const (
 	ConfigDialTimeout     = "dial_timeout"
 	ConfigRequestTimeout  = "request_timeout"
 	ConfigMemcacheAddr    = "memcache_addr"
+	ConfigGitLabHosts     = "gitlab_hosts"
+	ConfigGiteaHosts      = "gitea_hosts"
+	ConfigBitbucketHosts  = "bitbucket_server_hosts"
+	ConfigModuleProxy     = "module_proxy"
+	ConfigURLTemplates    = "url_templates"
+	ConfigArchiveDir      = "archive_dir"
+	ConfigLocalModules    = "local_modules"
 
 	// Trace Config
 	ConfigTraceSamplerFraction = "trace_fraction"
@@ -160,6 +170,7 @@ func buildFlags() *pflag.FlagSet {
 	flags.String(ConfigBindAddress, ":8080", "Listen for HTTP connections on this address.")
 	flags.Bool(ConfigSidebar, false, "Enable package page sidebar.")
 	flags.String(ConfigDefaultGOOS, "", "Default GOOS to use when building package documents.")
+	flags.StringSlice(ConfigAnalyses, doc.AnalysisNames(), "Analyses run on the source of packages to find possible problems.")
 	flags.Bool(ConfigTrustProxyHeaders, false, "If enabled, identify the remote address of the request using X-Real-Ip in header.")
 	flags.String(ConfigSourcegraphURL, "https://sourcegraph.com", "Link to global uses on Sourcegraph based at this URL (no need for trailing slash).")
 	flags.Duration(ConfigGithubInterval, 0, "Github updates crawler sleeps for this duration between fetches. Zero disables the crawler.")
@@ -170,6 +181,12 @@ func buildFlags() *pflag.FlagSet {
 	flags.Duration(ConfigDBIdleTimeout, 250*time.Second, "Close Redis connections after remaining idle for this duration.")
 	flags.Bool(ConfigDBLog, false, "Log database commands")
 	flags.String(ConfigMemcacheAddr, "", "Address in the format host:port gddo uses to point to the memcache backend.")
+	flags.StringSlice(ConfigGitLabHosts, nil, "Hostnames of self-managed GitLab instances to fetch with the GitLab API.")
+	flags.StringSlice(ConfigGiteaHosts, nil, "Hostnames of Gitea or Forgejo instances to fetch with the Gitea API.")
+	flags.StringSlice(ConfigBitbucketHosts, nil, "Hostnames of Bitbucket Server or Data Center instances to fetch with the Bitbucket Server REST API.")
+	flags.String(ConfigArchiveDir, "", "Directory for caching repository archives. If set, all packages of a GitHub repository are read from one archive per commit.")
+	flags.StringSlice(ConfigLocalModules, nil, "Module root directories or go.work files to read packages from instead of fetching them, for local development.")
+	flags.String(ConfigModuleProxy, "", "Go module proxy URLs, in GOPROXY syntax, to fetch packages from before trying version control services.")
 	flags.String(ConfigGAERemoteAPI, "", "Remoteapi endpoint for App Engine Search. Defaults to serviceproxy-dot-${project}.appspot.com.")
 	flags.Float64(ConfigTraceSamplerFraction, 0.1, "Fraction of the requests sampled by the trace API.")
 	flags.Float64(ConfigTraceSamplerMaxQPS, 5, "Max number of requests sampled every second by the trace API.")
@@ -177,6 +194,29 @@ func buildFlags() *pflag.FlagSet {
 	return flags
 }
 
+// registerURLTemplates registers the URL templates for browse and line links
+// listed under ConfigURLTemplates in the config file. Each entry has the
+// fields of gosrc.URLTemplates, for example in TOML:
+//
+//	[[url_templates]]
+//	pattern = '^git\.example\.com/(?P<repo>[^/]+/[^/]+)$'
+//	dir = "https://git.example.com/{repo}/tree/{tag}/{dir}"
+//	file = "https://git.example.com/{repo}/blob/{tag}/{dir}{0}"
+//	project = "https://git.example.com/{repo}"
+//	line = "%s#L%d"
+func registerURLTemplates(v *viper.Viper) error {
+	var templates []gosrc.URLTemplates
+	if err := v.UnmarshalKey(ConfigURLTemplates, &templates); err != nil {
+		return err
+	}
+	for _, t := range templates {
+		if err := gosrc.RegisterURLTemplates(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // readViperConfig finds and then parses a config file. It will return
 // an error if the config file was specified or could not parse.
 // Otherwise it will only warn that it failed to load the config.
@@ -46,6 +46,13 @@ func (s *server) publishCrawl(ctx context.Context, importPath string) {
 	s.crawlTopic.Publish(ctx, &pubsub.Message{Data: b})
 }
 
+// storedDoc returns the stored documentation of the package at importPath
+// for computing promoted members. See doc.Promote.
+func (s *server) storedDoc(ctx context.Context, importPath string) (*doc.Package, error) {
+	pdoc, _, err := s.db.GetDoc(ctx, importPath)
+	return pdoc, err
+}
+
 // crawlDoc fetches the package documentation from the VCS and updates the database.
 func (s *server) crawlDoc(ctx context.Context, source string, importPath string, pdoc *doc.Package, hasSubdirs bool, nextCrawl time.Time) (*doc.Package, error) {
 	message := []interface{}{source}
@@ -92,6 +99,9 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 		} else if _, ok := err.(gosrc.NotModifiedError); !ok {
 			pdoc = pdocNew
 		}
+		if err == nil {
+			doc.Promote(ctx, pdoc, s.storedDoc)
+		}
 	}
 
 	maxAge := s.v.GetDuration(ConfigMaxAge)
@@ -110,6 +120,9 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 			log.Println(err)
 		}
 		s.publishCrawl(ctx, importPath)
+		if etag != "" && etag != pdoc.Etag && importPath == pdoc.ProjectRoot && s.v.GetString(ConfigArchiveDir) != "" {
+			go s.crawlProject(context.Background(), importPath)
+		}
 		return pdoc, nil
 	} else if e, ok := err.(gosrc.NotModifiedError); ok {
 		if pdoc.Status == gosrc.Active && !s.isActivePkg(importPath, e.Status) {
@@ -136,12 +149,42 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 			log.Printf("ERROR db.Delete(%q): %v", importPath, err)
 		}
 		return nil, e
+	} else if e, ok := err.(*gosrc.RateLimitError); ok {
+		message = append(message, "ratelimit:", e.Reset.Format(time.RFC3339))
+		s.pauseGitHub(e.Reset)
+		return nil, err
 	} else {
 		message = append(message, "ERROR:", err)
 		return nil, err
 	}
 }
 
+// crawlProject crawls the packages of the project after the project root
+// changed. With repository archives enabled, all directories of a project
+// share the etag of the root and are read from the archive fetched for the
+// root, so the project is refreshed in one pass at little cost.
+func (s *server) crawlProject(ctx context.Context, projectRoot string) {
+	pkgs, err := s.db.Project(projectRoot)
+	if err != nil {
+		log.Printf("ERROR db.Project(%q): %v", projectRoot, err)
+		return
+	}
+	for _, pkg := range pkgs {
+		if pkg.Path == projectRoot {
+			continue
+		}
+		pdoc, subdirs, nextCrawl, err := s.db.Get(ctx, pkg.Path)
+		if err != nil {
+			log.Printf("ERROR db.Get(%q): %v", pkg.Path, err)
+			continue
+		}
+		if pdoc == nil {
+			continue
+		}
+		s.crawlDoc(ctx, "proj ", pkg.Path, pdoc, len(subdirs) > 0, nextCrawl)
+	}
+}
+
 func (s *server) put(ctx context.Context, pdoc *doc.Package, nextCrawl time.Time) error {
 	if pdoc.Status == gosrc.NoRecentCommits &&
 		s.isActivePkg(pdoc.ImportPath, gosrc.NoRecentCommits) {
@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/logging"
@@ -139,6 +140,78 @@ func (s *server) getDoc(ctx context.Context, path string, requestType int) (*doc
 	}
 }
 
+// semverTagPat matches semantic version tags. Documentation for these
+// versions is assumed not to change once fetched.
+var semverTagPat = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+(?:[-+][0-9A-Za-z.\-+]*)?$`)
+
+// getVersionDoc gets the documentation for a version of a package from the
+// database or from the version control system as needed. Only the known
+// versions of the package are stored in the database; other versions, such as
+// commits, are fetched on every request.
+func (s *server) getVersionDoc(ctx context.Context, path, version string, known []string) (*doc.Package, error) {
+	if !gosrc.IsValidRemotePath(path) {
+		return nil, &httpError{status: http.StatusNotFound}
+	}
+	pdoc, err := s.db.GetVersion(ctx, path, version)
+	if err != nil {
+		return nil, err
+	}
+	if pdoc != nil && (semverTagPat.MatchString(version) || time.Since(pdoc.Updated) < s.v.GetDuration(ConfigMaxAge)) {
+		return pdoc, nil
+	}
+
+	store := false
+	for _, v := range known {
+		store = store || v == version
+	}
+
+	c := make(chan crawlResult, 1)
+	go func() {
+		// The fetch continues after the request times out so that the
+		// documentation is stored for the next request.
+		ctx := context.Background()
+		pdoc, err := doc.GetVersion(ctx, s.httpClient, path, version, "")
+		if err == nil && pdoc.Name == "" && len(pdoc.Subdirectories) == 0 {
+			err = gosrc.NotFoundError{Message: "no Go files or subdirs"}
+		}
+		if err == nil {
+			doc.Promote(ctx, pdoc, s.storedDoc)
+			if store {
+				log.Println("web  ", "put:", pdoc.Etag, path+"@"+version)
+				if err := s.db.PutVersion(ctx, pdoc); err != nil {
+					log.Printf("ERROR db.PutVersion(%q, %q): %v", path, version, err)
+				}
+			}
+		}
+		c <- crawlResult{pdoc, err}
+	}()
+
+	select {
+	case cr := <-c:
+		err = cr.err
+		if err == nil {
+			pdoc = cr.pdoc
+		}
+	case <-time.After(s.v.GetDuration(ConfigFirstGetTimeout)):
+		err = errUpdateTimeout
+	}
+
+	switch {
+	case err == nil:
+		return pdoc, nil
+	case gosrc.IsNotFound(err):
+		return nil, err
+	case pdoc != nil:
+		log.Printf("Serving %q at %q from database after error getting doc: %v", path, version, err)
+		return pdoc, nil
+	case err == errUpdateTimeout:
+		log.Printf("Serving %q at %q as not found after timeout getting doc", path, version)
+		return nil, &httpError{status: http.StatusNotFound}
+	default:
+		return nil, err
+	}
+}
+
 func templateExt(req *http.Request) string {
 	if httputil.NegotiateContentType(req, []string{"text/html", "text/plain"}, "text/html") == "text/plain" {
 		return ".txt"
@@ -175,12 +248,102 @@ func isView(req *http.Request, key string) bool {
 		(len(rq) == len(key) || rq[len(key)] == '=' || rq[len(key)] == '&')
 }
 
+// platformDoc returns the documentation of pdoc restricted to the
+// declarations available on goos. The documentation is returned unchanged if
+// the package does not build for goos.
+func platformDoc(pdoc *doc.Package, goos string) *doc.Package {
+	platform := ""
+	for _, p := range pdoc.Platforms {
+		if strings.HasPrefix(p, goos+"/") {
+			platform = p
+			break
+		}
+	}
+	if platform == "" {
+		return pdoc
+	}
+	available := func(platforms []string) bool {
+		if platforms == nil {
+			return true
+		}
+		for _, p := range platforms {
+			if strings.HasPrefix(p, goos+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	values := func(vs []*doc.Value) []*doc.Value {
+		var result []*doc.Value
+		for _, v := range vs {
+			if available(v.Platforms) {
+				result = append(result, v)
+			}
+		}
+		return result
+	}
+	// platformDecl returns the declaration on goos of a function or type
+	// declared differently on the platforms.
+	platformDecl := func(pds []*doc.PlatformDecl) *doc.PlatformDecl {
+		for _, pd := range pds {
+			if available(pd.Platforms) {
+				return pd
+			}
+		}
+		return nil
+	}
+	funcs := func(fs []*doc.Func) []*doc.Func {
+		var result []*doc.Func
+		for _, f := range fs {
+			if !available(f.Platforms) {
+				continue
+			}
+			if f.PlatformDecls != nil {
+				f := *f
+				if pd := platformDecl(f.PlatformDecls); pd != nil {
+					f.Decl, f.Pos = pd.Decl, pd.Pos
+				}
+				f.PlatformDecls = nil
+				result = append(result, &f)
+				continue
+			}
+			result = append(result, f)
+		}
+		return result
+	}
+
+	p := *pdoc
+	p.GOOS = goos
+	p.GOARCH = platform[len(goos)+1:]
+	p.Consts = values(pdoc.Consts)
+	p.Vars = values(pdoc.Vars)
+	p.Funcs = funcs(pdoc.Funcs)
+	p.Types = nil
+	for _, t := range pdoc.Types {
+		if available(t.Platforms) {
+			t := *t
+			if pd := platformDecl(t.PlatformDecls); pd != nil {
+				t.Decl, t.Pos = pd.Decl, pd.Pos
+			}
+			t.PlatformDecls = nil
+			t.Consts = values(t.Consts)
+			t.Vars = values(t.Vars)
+			t.Funcs = funcs(t.Funcs)
+			t.Methods = funcs(t.Methods)
+			p.Types = append(p.Types, &t)
+		}
+	}
+	return &p
+}
+
 // httpEtag returns the package entity tag used in HTTP transactions.
 func (s *server) httpEtag(pdoc *doc.Package, pkgs []database.Package, importerCount int, flashMessages []flashMessage) string {
 	b := make([]byte, 0, 128)
 	b = strconv.AppendInt(b, pdoc.Updated.Unix(), 16)
 	b = append(b, 0)
 	b = append(b, pdoc.Etag...)
+	b = append(b, 0)
+	b = append(b, pdoc.GOOS...)
 	if importerCount >= 8 {
 		importerCount = 8
 	}
@@ -235,6 +398,9 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 	}
 
 	importPath := strings.TrimPrefix(req.URL.Path, "/")
+	if i := strings.LastIndex(importPath, "@"); i >= 0 {
+		return s.serveVersionedPackage(resp, req, importPath[:i], importPath[i+1:])
+	}
 	pdoc, pkgs, err := s.getDoc(req.Context(), importPath, requestType)
 
 	if e, ok := err.(gosrc.NotFoundError); ok && e.Redirect != "" {
@@ -290,6 +456,38 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			"pdoc":                      newTDoc(s.v, pdoc),
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
 		})
+	case isView(req, "changes"):
+		if pdoc.Name == "" {
+			return &httpError{status: http.StatusNotFound}
+		}
+		diffs, err := s.db.APIDiffs(importPath)
+		if err != nil {
+			return err
+		}
+		return s.templates.execute(resp, "changes.html", http.StatusOK, nil, map[string]interface{}{
+			"flashMessages":             flashMessages,
+			"diffs":                     diffs,
+			"pdoc":                      newTDoc(s.v, pdoc),
+			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+		})
+	case isView(req, "file"):
+		name := req.Form.Get("file")
+		if pdoc.NotRedistributable || !hasSource(pdoc, name) {
+			return &httpError{status: http.StatusNotFound}
+		}
+		src, err := s.db.GetSource(importPath, name)
+		if err != nil {
+			return err
+		}
+		if src == nil {
+			return &httpError{status: http.StatusNotFound}
+		}
+		return s.templates.execute(resp, "file.html", http.StatusOK, nil, map[string]interface{}{
+			"flashMessages":             flashMessages,
+			"file":                      src,
+			"pdoc":                      newTDoc(s.v, pdoc),
+			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+		})
 	case isView(req, "tools"):
 		proto := "http"
 		if req.Host == "godoc.org" {
@@ -359,6 +557,10 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
 		})
 	case isView(req, "play"):
+		if pdoc.NotRedistributable {
+			// The examples of the package are not shown.
+			return &httpError{status: http.StatusNotFound}
+		}
 		u, err := s.playURL(pdoc, req.Form.Get("play"), req.Header.Get("X-AppEngine-Country"))
 		if err != nil {
 			return err
@@ -394,6 +596,10 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			}
 		}
 
+		if goos := req.Form.Get("GOOS"); goos != "" && pdoc.Name != "" {
+			pdoc = platformDoc(pdoc, goos)
+		}
+
 		etag := s.httpEtag(pdoc, pkgs, importerCount, flashMessages)
 		status := http.StatusOK
 		if req.Header.Get("If-None-Match") == etag {
@@ -433,6 +639,64 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 	}
 }
 
+// serveVersionedPackage serves the documentation page for a version of a
+// package. Other package views are served from the unversioned page.
+func (s *server) serveVersionedPackage(resp http.ResponseWriter, req *http.Request, importPath, version string) error {
+	if importPath == "" || version == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	if req.URL.RawQuery != "" {
+		http.Redirect(resp, req, "/"+importPath+"?"+req.URL.RawQuery, http.StatusFound)
+		return nil
+	}
+
+	// The version list is kept with the documentation for the default
+	// branch.
+	latest, _, err := s.db.GetDoc(req.Context(), importPath)
+	if err != nil {
+		return err
+	}
+	var versions []string
+	if latest != nil {
+		versions = latest.Versions
+	}
+
+	pdoc, err := s.getVersionDoc(req.Context(), importPath, version, versions)
+	if err != nil {
+		return err
+	}
+	pdoc.Versions = versions
+
+	var pkgs []database.Package
+	for _, subdir := range pdoc.Subdirectories {
+		pkgs = append(pkgs, database.Package{Path: importPath + "/" + subdir})
+	}
+
+	flashMessages := getFlashMessages(resp, req)
+	etag := s.httpEtag(pdoc, pkgs, 0, flashMessages)
+	status := http.StatusOK
+	if req.Header.Get("If-None-Match") == etag {
+		status = http.StatusNotModified
+	}
+
+	template := "dir"
+	switch {
+	case pdoc.IsCmd:
+		template = "cmd"
+	case pdoc.Name != "":
+		template = "pkg"
+	}
+	template += templateExt(req)
+
+	return s.templates.execute(resp, template, status, http.Header{"Etag": {etag}}, map[string]interface{}{
+		"flashMessages":             flashMessages,
+		"pkgs":                      pkgs,
+		"pdoc":                      newTDoc(s.v, pdoc),
+		"importerCount":             0,
+		"showPkgGoDevRedirectToast": userReturningFromPkgGoDev(req),
+	})
+}
+
 func (s *server) serveRefresh(resp http.ResponseWriter, req *http.Request) error {
 	importPath := req.Form.Get("path")
 	_, pkgs, _, err := s.db.Get(req.Context(), importPath)
@@ -640,7 +904,7 @@ func (s *server) serveAPISearch(resp http.ResponseWriter, req *http.Request) err
 			pdoc, _, err = s.getDoc(req.Context(), e.Redirect, robotRequest)
 		}
 		if err == nil && pdoc != nil {
-			pkgs = []database.Package{{Path: pdoc.ImportPath, Synopsis: pdoc.Synopsis}}
+			pkgs = []database.Package{{Path: pdoc.ImportPath, Synopsis: pdoc.Synopsis, Licenses: gosrc.LicenseTypes(pdoc.Licenses), Deprecated: pdoc.Deprecated != ""}}
 		}
 	}
 
@@ -690,6 +954,93 @@ func (s *server) serveAPIImporters(resp http.ResponseWriter, req *http.Request)
 	return json.NewEncoder(resp).Encode(&data)
 }
 
+func (s *server) serveAPIChanges(resp http.ResponseWriter, req *http.Request) error {
+	importPath := strings.TrimPrefix(req.URL.Path, "/changes/")
+	diffs, err := s.db.APIDiffs(importPath)
+	if err != nil {
+		return err
+	}
+	type change struct {
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Old        string `json:"old,omitempty"`
+		New        string `json:"new,omitempty"`
+		Compatible bool   `json:"compatible"`
+	}
+	type diff struct {
+		Updated time.Time `json:"updated"`
+		Changes []change  `json:"changes"`
+	}
+	data := struct {
+		Results []diff `json:"results"`
+	}{
+		[]diff{},
+	}
+	for _, d := range diffs {
+		r := diff{Updated: d.Updated}
+		for _, c := range d.Changes {
+			r.Changes = append(r.Changes, change{c.Kind.String(), c.Name, c.Old, c.New, c.Compatible})
+		}
+		data.Results = append(data.Results, r)
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
+func (s *server) serveAPISince(resp http.ResponseWriter, req *http.Request) error {
+	importPath := strings.TrimPrefix(req.URL.Path, "/since/")
+	pdoc, _, err := s.getDoc(req.Context(), importPath, robotRequest)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil || pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	type decl struct {
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Since      string `json:"since,omitempty"`
+		Deprecated bool   `json:"deprecated,omitempty"`
+	}
+	data := struct {
+		Results []decl `json:"results"`
+	}{
+		[]decl{},
+	}
+	add := func(kind, name, since, deprecated string) {
+		data.Results = append(data.Results, decl{kind, name, since, deprecated != ""})
+	}
+	values := func(kind string, values []*doc.Value) {
+		for _, v := range values {
+			for _, name := range v.Names {
+				add(kind, name, v.Since, v.Deprecated)
+			}
+		}
+	}
+	funcs := func(funcs []*doc.Func) {
+		for _, f := range funcs {
+			add("func", f.Name, f.Since, f.Deprecated)
+		}
+	}
+	values("const", pdoc.Consts)
+	values("var", pdoc.Vars)
+	funcs(pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		add("type", t.Name, t.Since, t.Deprecated)
+		for _, f := range t.Fields {
+			add("field", t.Name+"."+f.Name, f.Since, f.Deprecated)
+		}
+		values("const", t.Consts)
+		values("var", t.Vars)
+		funcs(t.Funcs)
+		for _, m := range t.Methods {
+			add("method", t.Name+"."+m.Name, m.Since, m.Deprecated)
+		}
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
 func (s *server) serveAPIImports(resp http.ResponseWriter, req *http.Request) error {
 	importPath := strings.TrimPrefix(req.URL.Path, "/imports/")
 	pdoc, _, err := s.getDoc(req.Context(), importPath, robotRequest)
@@ -877,6 +1228,13 @@ type server struct {
 
 	// A semaphore to limit concurrent ?import-graph requests.
 	importGraphSem chan struct{}
+
+	// Crawling of GitHub repositories is paused until the GitHub API rate
+	// limit is reset.
+	gitHubPause struct {
+		sync.Mutex
+		until time.Time
+	}
 }
 
 func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
@@ -935,6 +1293,8 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	apiMux.Handle("/packages", apiHandler(s.serveAPIPackages))
 	apiMux.Handle("/importers/", apiHandler(s.serveAPIImporters))
 	apiMux.Handle("/imports/", apiHandler(s.serveAPIImports))
+	apiMux.Handle("/changes/", apiHandler(s.serveAPIChanges))
+	apiMux.Handle("/since/", apiHandler(s.serveAPISince))
 	apiMux.Handle("/", apiHandler(serveAPIHome))
 
 	mux := http.NewServeMux()
@@ -1113,6 +1473,24 @@ func main() {
 		log.Fatal(ctx, "load config", "error", err.Error())
 	}
 	doc.SetDefaultGOOS(v.GetString(ConfigDefaultGOOS))
+	if err := doc.SetAnalyses(v.GetStringSlice(ConfigAnalyses)...); err != nil {
+		log.Fatal(ctx, "analyses", "error", err.Error())
+	}
+	gosrc.SetGitLabHosts(v.GetStringSlice(ConfigGitLabHosts)...)
+	gosrc.SetGiteaHosts(v.GetStringSlice(ConfigGiteaHosts)...)
+	gosrc.SetBitbucketServerHosts(v.GetStringSlice(ConfigBitbucketHosts)...)
+	if err := gosrc.SetModuleProxy(v.GetString(ConfigModuleProxy)); err != nil {
+		log.Fatal(ctx, "module proxy", "error", err.Error())
+	}
+	gosrc.SetArchiveDir(v.GetString(ConfigArchiveDir))
+	if roots := v.GetStringSlice(ConfigLocalModules); len(roots) > 0 {
+		if err := gosrc.SetLocalModules(roots...); err != nil {
+			log.Fatal(ctx, "local modules", "error", err.Error())
+		}
+	}
+	if err := registerURLTemplates(v); err != nil {
+		log.Fatal(ctx, "URL templates", "error", err.Error())
+	}
 
 	s, err := newServer(ctx, v)
 	if err != nil {
@@ -7,7 +7,27 @@
 package main
 
 import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
 )
 
 var robotTests = []string{
@@ -33,3 +53,324 @@ func TestRobotPat(t *testing.T) {
 		}
 	}
 }
+
+func TestPlatformDoc(t *testing.T) {
+	pdoc := &doc.Package{
+		Name:      "p",
+		GOOS:      "linux",
+		GOARCH:    "amd64",
+		Platforms: []string{"linux/amd64", "windows/amd64", "js/wasm"},
+		Funcs: []*doc.Func{
+			{Name: "F"},
+			{Name: "Linux", Platforms: []string{"linux/amd64"}},
+			{Name: "Windows", Platforms: []string{"windows/amd64"}},
+			{Name: "G", Decl: doc.Code{Text: "func G(fd int)"}, PlatformDecls: []*doc.PlatformDecl{
+				{Decl: doc.Code{Text: "func G(fd int)"}, Platforms: []string{"linux/amd64", "js/wasm"}},
+				{Decl: doc.Code{Text: "func G(h uintptr)"}, Platforms: []string{"windows/amd64"}},
+			}},
+		},
+		Types: []*doc.Type{
+			{Name: "T", Methods: []*doc.Func{{Name: "M", Platforms: []string{"linux/amd64"}}}},
+			{Name: "Handle", Platforms: []string{"windows/amd64"}},
+		},
+	}
+
+	p := platformDoc(pdoc, "windows")
+	if p.GOOS != "windows" || p.GOARCH != "amd64" {
+		t.Errorf("platformDoc(windows) GOOS/GOARCH = %s/%s, want windows/amd64", p.GOOS, p.GOARCH)
+	}
+	var names []string
+	for _, f := range p.Funcs {
+		names = append(names, f.Name)
+	}
+	for _, typ := range p.Types {
+		names = append(names, typ.Name)
+		for _, m := range typ.Methods {
+			names = append(names, typ.Name+"."+m.Name)
+		}
+	}
+	if want := []string{"F", "Windows", "G", "T", "Handle"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("platformDoc(windows) declarations = %v, want %v", names, want)
+	}
+	// Only the windows declaration of G is shown.
+	if g := p.Funcs[2]; g.Decl.Text != "func G(h uintptr)" || g.PlatformDecls != nil {
+		t.Errorf("platformDoc(windows) G = %q with declarations %+v, want the windows declaration only", g.Decl.Text, g.PlatformDecls)
+	}
+	if len(pdoc.Types[0].Methods) != 1 {
+		t.Error("platformDoc modified the original documentation")
+	}
+	if p := platformDoc(pdoc, "plan9"); p != pdoc {
+		t.Error("platformDoc(plan9) did not return the documentation unchanged")
+	}
+}
+
+// newTestDB returns a database using the redis server at :6379, as the
+// database package tests do. The test is skipped if there is no server.
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	dial := func() (redis.Conn, error) {
+		c, err := redis.DialTimeout("tcp", ":6379", 0, time.Second, time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.Do("SELECT", "9"); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	c, err := dial()
+	if err != nil {
+		t.Skipf("redis not available: %v", err)
+	}
+	defer c.Close()
+	if n, err := redis.Int(c.Do("DBSIZE")); n != 0 || err != nil {
+		t.Fatalf("DBSIZE returned %d, %v", n, err)
+	}
+	return &database.Database{Pool: redis.NewPool(dial, 1)}
+}
+
+func TestServeAPIChanges(t *testing.T) {
+	db := newTestDB(t)
+	defer func() {
+		c := db.Pool.Get()
+		c.Do("FLUSHDB")
+		c.Close()
+	}()
+	s := &server{db: db}
+
+	ctx := context.Background()
+	pdoc := &doc.Package{
+		ImportPath: "github.com/user/repo",
+		Name:       "repo",
+		Etag:       "1",
+		Funcs:      []*doc.Func{{Name: "F", Decl: doc.Code{Text: "func F(s string)"}}},
+	}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatal(err)
+	}
+	pdoc.Etag = "2"
+	pdoc.Funcs = append(pdoc.Funcs, &doc.Func{Name: "G", Decl: doc.Code{Text: "func G()"}})
+	pdoc.Funcs[0] = &doc.Func{Name: "F", Decl: doc.Code{Text: "func F(s string, n int)"}}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		Kind, Name, Old, New string
+		Compatible           bool
+	}
+	var data struct {
+		Results []struct {
+			Updated time.Time
+			Changes []change
+		}
+	}
+	for _, tt := range []struct {
+		path string
+		want []change
+	}{
+		{"/changes/github.com/user/repo", []change{
+			{Kind: "changed", Name: "F", Old: "func F(string)", New: "func F(string, int)"},
+			{Kind: "added", Name: "G", New: "func G()", Compatible: true},
+		}},
+		{"/changes/github.com/user/other", nil},
+	} {
+		resp := httptest.NewRecorder()
+		if err := s.serveAPIChanges(resp, httptest.NewRequest("GET", tt.path, nil)); err != nil {
+			t.Fatalf("%s: serveAPIChanges returned error %v", tt.path, err)
+		}
+		if ct := resp.Header().Get("Content-Type"); ct != jsonMIMEType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, ct, jsonMIMEType)
+		}
+		data.Results = nil
+		if err := json.Unmarshal(resp.Body.Bytes(), &data); err != nil {
+			t.Fatalf("%s: cannot decode response %q: %v", tt.path, resp.Body, err)
+		}
+		if data.Results == nil {
+			t.Errorf("%s: results is %s, want a list", tt.path, resp.Body)
+		}
+		var got []change
+		for _, r := range data.Results {
+			if r.Updated.IsZero() {
+				t.Errorf("%s: result has no update time", tt.path)
+			}
+			got = append(got, r.Changes...)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("%s: changes = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// noNetwork is an http.RoundTripper which fails all requests.
+type noNetwork struct{}
+
+func (noNetwork) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("network access in test: " + req.URL.String())
+}
+
+// writeTestModule writes the versions of module example.com/m, each with
+// the package example.com/m/sub, to a file system module proxy in dir.
+func writeTestModule(t *testing.T, dir string, versions ...string) {
+	t.Helper()
+	vdir := filepath.Join(dir, "example.com", "m", "@v")
+	if err := os.MkdirAll(vdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		base := filepath.Join(vdir, v)
+		if err := ioutil.WriteFile(base+".info", []byte(`{"Version": "`+v+`", "Time": "2099-01-01T00:00:00Z"}`), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(base+".mod", []byte("module example.com/m\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(base + ".zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := zip.NewWriter(f)
+		for name, data := range map[string]string{
+			"go.mod":     "module example.com/m\n",
+			"sub/sub.go": "// Package sub is at " + v + ".\npackage sub\n",
+		} {
+			w, err := zw.Create("example.com/m@" + v + "/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetVersionDoc(t *testing.T) {
+	db := newTestDB(t)
+	defer func() {
+		c := db.Pool.Get()
+		c.Do("FLUSHDB")
+		c.Close()
+	}()
+	dir, err := ioutil.TempDir("", "gddo-proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestModule(t, dir, "v1.0.0", "v1.1.0")
+	if err := gosrc.SetModuleProxy("file://" + filepath.ToSlash(dir)); err != nil {
+		t.Fatal(err)
+	}
+	defer gosrc.SetModuleProxy("")
+
+	v := viper.New()
+	v.Set(ConfigFirstGetTimeout, 10*time.Second)
+	s := &server{v: v, db: db, httpClient: &http.Client{Transport: noNetwork{}}}
+	ctx := context.Background()
+
+	if _, err := s.getVersionDoc(ctx, "example/m", "v1.0.0", nil); err == nil {
+		t.Error("getVersionDoc of invalid path returned nil error")
+	} else if e, ok := err.(*httpError); !ok || e.status != http.StatusNotFound {
+		t.Errorf("getVersionDoc of invalid path returned %v, want not found", err)
+	}
+
+	// Only the known versions are stored.
+	known := []string{"v1.0.0"}
+	for _, tt := range []struct {
+		version string
+		stored  bool
+	}{
+		{"v1.0.0", true},
+		{"v1.1.0", false},
+	} {
+		pdoc, err := s.getVersionDoc(ctx, "example.com/m/sub", tt.version, known)
+		if err != nil {
+			t.Fatalf("getVersionDoc(%s) returned error %v", tt.version, err)
+		}
+		if want := "Package sub is at " + tt.version + "."; pdoc.Synopsis != want {
+			t.Errorf("getVersionDoc(%s) synopsis = %q, want %q", tt.version, pdoc.Synopsis, want)
+		}
+		stored, err := db.GetVersion(ctx, "example.com/m/sub", tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (stored != nil) != tt.stored {
+			t.Errorf("version %s stored = %v, want %v", tt.version, stored != nil, tt.stored)
+		}
+	}
+}
+
+// playShare is an http.RoundTripper which shares all snippets at the
+// playground as abc.
+type playShare struct{}
+
+func (playShare) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.String() != "https://play.golang.org/share" {
+		return nil, errors.New("unexpected request: " + req.URL.String())
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("abc")),
+		Request:    req,
+	}, nil
+}
+
+func TestServePackageNotRedistributable(t *testing.T) {
+	db := newTestDB(t)
+	defer func() {
+		c := db.Pool.Get()
+		c.Do("FLUSHDB")
+		c.Close()
+	}()
+	s := &server{
+		v:          viper.New(),
+		db:         db,
+		httpClient: &http.Client{Transport: playShare{}},
+	}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		importPath, query  string
+		notRedistributable bool
+		want               int
+	}{
+		{"github.com/user/free", "play=package", false, http.StatusMovedPermanently},
+		{"github.com/user/proprietary", "play=package", true, http.StatusNotFound},
+		// Sources stored before the package lost its license are not
+		// served.
+		{"github.com/user/proprietary", "file=p.go", true, http.StatusNotFound},
+	} {
+		pdoc := &doc.Package{
+			ImportPath:         tt.importPath,
+			Name:               "p",
+			Examples:           []*doc.Example{{Play: "package main\n"}},
+			Files:              []*doc.File{{Name: "p.go", Source: true}},
+			Sources:            []*doc.SourceFile{{Name: "p.go", Code: doc.Code{Text: "package p\n"}}},
+			NotRedistributable: tt.notRedistributable,
+		}
+		if err := db.Put(ctx, pdoc, time.Now().Add(time.Hour), false); err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/"+tt.importPath+"?"+tt.query, nil)
+		status := 0
+		switch err := s.servePackage(resp, req).(type) {
+		case nil:
+			status = resp.Code
+		case *httpError:
+			status = err.status
+		default:
+			t.Fatalf("%s?%s: servePackage returned error %v", tt.importPath, tt.query, err)
+		}
+		if status != tt.want {
+			t.Errorf("%s?%s: status = %d, want %d", tt.importPath, tt.query, status, tt.want)
+		}
+	}
+}
@@ -87,6 +87,7 @@ type texample struct {
 	Label   string
 	Example *doc.Example
 	Play    bool
+	Hidden  bool // code not shown because of the package's license
 	obj     interface{}
 }
 
@@ -97,18 +98,47 @@ func newTDoc(v *viper.Viper, pdoc *doc.Package) *tdoc {
 	}
 }
 
+// SourceLink generates a link to the source of pos. The link is to the
+// source on this site if the VCS host has no URL for the file.
 func (pdoc *tdoc) SourceLink(pos doc.Pos, text string, textOnlyOK bool) htemp.HTML {
-	if pos.Line == 0 || pdoc.LineFmt == "" || pdoc.Files[pos.File].URL == "" {
+	var u string
+	switch {
+	case pos.Line == 0 || int(pos.File) >= len(pdoc.Files):
+	case pdoc.LineFmt != "" && pdoc.Files[pos.File].URL != "":
+		u = fmt.Sprintf(pdoc.LineFmt, pdoc.Files[pos.File].URL, pos.Line)
+	case pdoc.Files[pos.File].Source:
+		u = fmt.Sprintf("?file=%s#L%d", url.QueryEscape(pdoc.Files[pos.File].Name), pos.Line)
+	}
+	if u == "" {
 		if textOnlyOK {
 			return htemp.HTML(htemp.HTMLEscapeString(text))
 		}
 		return ""
 	}
 	return htemp.HTML(fmt.Sprintf(`<a title="View Source" href="%s">%s</a>`,
-		htemp.HTMLEscapeString(fmt.Sprintf(pdoc.LineFmt, pdoc.Files[pos.File].URL, pos.Line)),
+		htemp.HTMLEscapeString(u),
 		htemp.HTMLEscapeString(text)))
 }
 
+// hasSource returns true if the annotated source of the Go file name is
+// stored with the package.
+func hasSource(pdoc *doc.Package, name string) bool {
+	for _, f := range pdoc.Files {
+		if f.Name == name {
+			return f.Source
+		}
+	}
+	return false
+}
+
+// Position returns the file name and line of pos, such as "file.go:10".
+func (pdoc *tdoc) Position(pos doc.Pos) string {
+	if pos.Line == 0 || int(pos.File) >= len(pdoc.Files) {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", pdoc.Files[pos.File].Name, pos.Line)
+}
+
 // UsesLink generates a link to uses of a symbol definition.
 // title is used as the tooltip. defParts are parts of the symbol definition name.
 func (pdoc *tdoc) UsesLink(title string, defParts ...string) htemp.HTML {
@@ -135,8 +165,12 @@ func (pdoc *tdoc) UsesLink(title string, defParts ...string) htemp.HTML {
 			orig = recv
 		}
 
-		// Trim "*" from "*T" if it's a pointer receiver method.
+		// Trim "*" from "*T" if it's a pointer receiver method and the type
+		// parameters from "T[P]" if it's a method of a generic type.
 		typeName := strings.TrimPrefix(orig, "*")
+		if i := strings.IndexByte(typeName, '['); i >= 0 {
+			typeName = typeName[:i]
+		}
 
 		def = typeName + "/" + methodName
 	default:
@@ -174,7 +208,8 @@ func (pdoc *tdoc) addExamples(obj interface{}, export, method string, examples [
 			Example: e,
 			obj:     obj,
 			// Only show play links for packages within the standard library.
-			Play: e.Play != "" && gosrc.IsGoRepoPath(pdoc.ImportPath),
+			Play:   e.Play != "" && gosrc.IsGoRepoPath(pdoc.ImportPath),
+			Hidden: pdoc.NotRedistributable,
 		}
 		if e.Name != "" {
 			te.Label += " (" + e.Name + ")"
@@ -286,6 +321,48 @@ func (pdoc *tdoc) StatusDescription() htemp.HTML {
 	return htemp.HTML(desc)
 }
 
+// ReadmeHTML returns the rendered README file of the directory. The HTML is
+// sanitized when the package is built.
+func (pdoc *tdoc) ReadmeHTML() htemp.HTML {
+	return htemp.HTML(pdoc.Readme)
+}
+
+// LicenseDescription describes the licenses of the package.
+func (pdoc *tdoc) LicenseDescription() string {
+	if pdoc.Licenses == nil {
+		return ""
+	}
+	desc := ""
+	if types := gosrc.LicenseTypes(pdoc.Licenses); len(types) > 0 {
+		desc = "License: " + strings.Join(types, ", ") + "."
+	}
+	if pdoc.NotRedistributable {
+		if desc != "" {
+			desc += " "
+		}
+		desc += "No recognized license permits redistribution; example code is not shown."
+	}
+	return desc
+}
+
+// PlatformGOOS returns the operating systems the package builds for, or nil
+// if the package was built for one platform only.
+func (pdoc *tdoc) PlatformGOOS() []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, p := range pdoc.Platforms {
+		goos := p[:strings.IndexByte(p+"/", '/')]
+		if !seen[goos] {
+			seen[goos] = true
+			list = append(list, goos)
+		}
+	}
+	if len(list) < 2 {
+		return nil
+	}
+	return list
+}
+
 func formatPathFrag(path, fragment string) string {
 	if len(path) > 0 && path[0] != '/' {
 		path = "/" + path
@@ -358,10 +435,15 @@ func replaceAll(src []byte, re *regexp.Regexp, replace func(out, src []byte, m [
 	return append(out, src...)
 }
 
-// commentFn formats a source code comment as HTML.
-func commentFn(v string) htemp.HTML {
+// commentFn formats a doc comment as HTML. The comment text v is formatted
+// if the parsed comment c is not available.
+func commentFn(c *doc.Comment, v string) htemp.HTML {
 	var buf bytes.Buffer
-	godoc.ToHTML(&buf, v, nil)
+	if c == nil {
+		godoc.ToHTML(&buf, v, nil)
+	} else {
+		writeCommentHTML(&buf, c)
+	}
 	p := buf.Bytes()
 	p = replaceAll(p, h3Pat, func(out, src []byte, m []int) []byte {
 		out = append(out, `<h4 id="`...)
@@ -405,30 +487,158 @@ func commentFn(v string) htemp.HTML {
 	return htemp.HTML(p)
 }
 
-// commentTextFn formats a source code comment as text.
-func commentTextFn(v string) string {
+// writeCommentHTML writes the blocks of the parsed doc comment c as HTML.
+// Headings are written as <h3> elements for commentFn to add permalinks.
+func writeCommentHTML(buf *bytes.Buffer, c *doc.Comment) {
+	for _, b := range c.Blocks {
+		switch b.Kind {
+		case doc.HeadingBlock:
+			buf.WriteString(`<h3 id="`)
+			htemp.HTMLEscape(buf, []byte(b.ID))
+			buf.WriteString(`">`)
+			htemp.HTMLEscape(buf, []byte(b.Text.Text))
+			buf.WriteString("</h3>\n")
+		case doc.CodeBlock:
+			buf.WriteString("<pre>")
+			htemp.HTMLEscape(buf, []byte(b.Text.Text))
+			buf.WriteString("</pre>\n")
+		case doc.ListBlock:
+			tag := "ul"
+			if len(b.Items) > 0 && b.Items[0].Number != "" {
+				tag = "ol"
+			}
+			buf.WriteString("<" + tag + ">\n")
+			for _, item := range b.Items {
+				buf.WriteString("<li")
+				if item.Number != "" {
+					buf.WriteString(` value="`)
+					htemp.HTMLEscape(buf, []byte(item.Number))
+					buf.WriteString(`"`)
+				}
+				buf.WriteString(">")
+				for i, p := range item.Paragraphs {
+					if len(item.Paragraphs) > 1 {
+						buf.WriteString("<p>")
+					} else if i > 0 {
+						buf.WriteString("\n")
+					}
+					writeCode(buf, p, nil)
+				}
+				buf.WriteString("</li>\n")
+			}
+			buf.WriteString("</" + tag + ">\n")
+		default:
+			buf.WriteString("<p>")
+			writeCode(buf, b.Text, nil)
+			buf.WriteString("</p>\n")
+		}
+	}
+}
+
+// commentTextFn formats a doc comment as text. The comment text v is
+// formatted if the parsed comment c is not available.
+func commentTextFn(c *doc.Comment, v string) string {
 	const indent = "    "
+	const width = 80 - 2*len(indent)
 	var buf bytes.Buffer
-	godoc.ToText(&buf, v, indent, "\t", 80-2*len(indent))
-	p := buf.Bytes()
-	return string(p)
+	if c == nil {
+		godoc.ToText(&buf, v, indent, "\t", width)
+		return buf.String()
+	}
+	for i, b := range c.Blocks {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		switch b.Kind {
+		case doc.HeadingBlock:
+			buf.WriteString(indent + "# " + b.Text.Text + "\n")
+		case doc.CodeBlock:
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(b.Text.Text, "\n"), "\n") {
+				buf.WriteString(indent + "\t" + line)
+			}
+			buf.WriteByte('\n')
+		case doc.ListBlock:
+			for _, item := range b.Items {
+				marker := "  - "
+				if item.Number != "" {
+					marker = " " + item.Number + ". "
+				}
+				for i, p := range item.Paragraphs {
+					first := indent + marker
+					if i > 0 {
+						buf.WriteByte('\n')
+						first = indent + strings.Repeat(" ", len(marker))
+					}
+					writeWrapped(&buf, p.Text, first, indent+strings.Repeat(" ", len(marker)), width)
+				}
+			}
+		default:
+			writeWrapped(&buf, b.Text.Text, indent, indent, width)
+		}
+	}
+	return buf.String()
 }
 
-var period = []byte{'.'}
+// writeWrapped writes the words of text in lines of at most width
+// characters, not counting the line prefix. The first line starts with
+// first, the following lines with prefix.
+func writeWrapped(buf *bytes.Buffer, text, first, prefix string, width int) {
+	n := 0
+	buf.WriteString(first)
+	for _, w := range strings.Fields(text) {
+		if n > 0 && n+1+len(w) > width {
+			buf.WriteString("\n" + prefix)
+			n = 0
+		}
+		if n > 0 {
+			buf.WriteByte(' ')
+			n++
+		}
+		buf.WriteString(w)
+		n += len(w)
+	}
+	buf.WriteByte('\n')
+}
 
 func codeFn(c doc.Code, typ *doc.Type) htemp.HTML {
 	var buf bytes.Buffer
+	buf.WriteString("<pre>")
+	writeCode(&buf, c, typ)
+	buf.WriteString("</pre>")
+	return htemp.HTML(buf.String())
+}
+
+// sourceFn returns the annotated source of a Go file as HTML with a
+// column of line numbers. The line numbers are anchors named L1, L2 and
+// so on.
+func sourceFn(c doc.Code) htemp.HTML {
+	var buf bytes.Buffer
+	buf.WriteString(`<table class="source"><tr><td class="lines"><pre>`)
+	n := strings.Count(c.Text, "\n")
+	if !strings.HasSuffix(c.Text, "\n") {
+		n++
+	}
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&buf, "<a id=\"L%d\" href=\"#L%d\">%d</a>\n", i, i, i)
+	}
+	buf.WriteString(`</pre></td><td class="code"><pre>`)
+	writeCode(&buf, c, nil)
+	buf.WriteString(`</pre></td></tr></table>`)
+	return htemp.HTML(buf.String())
+}
+
+// writeCode writes the text of c with its annotations as HTML.
+func writeCode(buf *bytes.Buffer, c doc.Code, typ *doc.Type) {
 	last := 0
 	src := []byte(c.Text)
-	buf.WriteString("<pre>")
 	for _, a := range c.Annotations {
-		htemp.HTMLEscape(&buf, src[last:a.Pos])
+		htemp.HTMLEscape(buf, src[last:a.Pos])
 		switch a.Kind {
 		case doc.PackageLinkAnnotation:
 			buf.WriteString(`<a href="`)
 			buf.WriteString(formatPathFrag(c.Paths[a.PathIndex], ""))
 			buf.WriteString(`">`)
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
 			buf.WriteString(`</a>`)
 		case doc.LinkAnnotation, doc.BuiltinAnnotation:
 			var p string
@@ -437,38 +647,60 @@ func codeFn(c doc.Code, typ *doc.Type) htemp.HTML {
 			} else if a.PathIndex >= 0 {
 				p = c.Paths[a.PathIndex]
 			}
-			n := src[a.Pos:a.End]
-			n = n[bytes.LastIndex(n, period)+1:]
 			buf.WriteString(`<a href="`)
-			buf.WriteString(formatPathFrag(p, string(n)))
+			buf.WriteString(formatPathFrag(p, string(src[a.Pos:a.End])))
 			buf.WriteString(`">`)
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`</a>`)
+		case doc.URLAnnotation:
+			buf.WriteString(`<a href="`)
+			htemp.HTMLEscape(buf, []byte(c.Paths[a.PathIndex]))
+			buf.WriteString(`" rel="nofollow">`)
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
 			buf.WriteString(`</a>`)
 		case doc.CommentAnnotation:
 			buf.WriteString(`<span class="com">`)
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`</span>`)
+		case doc.KeywordAnnotation:
+			buf.WriteString(`<span class="kwd">`)
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`</span>`)
+		case doc.StringAnnotation:
+			buf.WriteString(`<span class="str">`)
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
 			buf.WriteString(`</span>`)
 		case doc.AnchorAnnotation:
 			buf.WriteString(`<span id="`)
 			if typ != nil {
-				htemp.HTMLEscape(&buf, []byte(typ.Name))
+				htemp.HTMLEscape(buf, []byte(typ.Name))
 				buf.WriteByte('.')
 			}
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`">`)
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`</span>`)
+		case doc.TypeParamAnchorAnnotation:
+			buf.WriteString(`<span id="`)
+			htemp.HTMLEscape(buf, []byte(c.Paths[a.PathIndex]))
 			buf.WriteString(`">`)
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
 			buf.WriteString(`</span>`)
+		case doc.TypeParamLinkAnnotation:
+			buf.WriteString(`<a href="#`)
+			htemp.HTMLEscape(buf, []byte(c.Paths[a.PathIndex]))
+			buf.WriteString(`">`)
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
+			buf.WriteString(`</a>`)
 		default:
-			htemp.HTMLEscape(&buf, src[a.Pos:a.End])
+			htemp.HTMLEscape(buf, src[a.Pos:a.End])
 		}
 		last = int(a.End)
 	}
-	htemp.HTMLEscape(&buf, src[last:])
-	buf.WriteString("</pre>")
-	return htemp.HTML(buf.String())
+	htemp.HTMLEscape(buf, src[last:])
 }
 
-var isInterfacePat = regexp.MustCompile(`^type [^ ]+ interface`)
+var isInterfacePat = regexp.MustCompile(`^type [^ \[]+(?:\[.*\])? interface`)
 
 func isInterfaceFn(t *doc.Type) bool {
 	return isInterfacePat.MatchString(t.Decl.Text)
@@ -530,8 +762,10 @@ func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (temp
 	htmlSets := [][]string{
 		{"about.html", "common.html", "layout.html"},
 		{"bot.html", "common.html", "layout.html"},
+		{"changes.html", "common.html", "layout.html"},
 		{"cmd.html", "common.html", "layout.html"},
 		{"dir.html", "common.html", "layout.html"},
+		{"file.html", "common.html", "layout.html"},
 		{"home.html", "common.html", "layout.html"},
 		{"importers.html", "common.html", "layout.html"},
 		{"importers_robot.html", "common.html", "layout.html"},
@@ -548,6 +782,7 @@ func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (temp
 		"code":              codeFn,
 		"comment":           commentFn,
 		"equal":             reflect.DeepEqual,
+		"source":            sourceFn,
 		"gaAccount":         func() string { return v.GetString(ConfigGAAccount) },
 		"host":              hostFn,
 		"htmlComment":       htmlCommentFn,
@@ -13,6 +13,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
 )
 
 func TestFlashMessages(t *testing.T) {
@@ -32,3 +34,48 @@ func TestFlashMessages(t *testing.T) {
 		t.Errorf("got messages %+v, want %+v", actualMessages, expectedMessages)
 	}
 }
+
+func TestComment(t *testing.T) {
+	c := &doc.Comment{Blocks: []*doc.Block{
+		{Kind: doc.HeadingBlock, Text: doc.Code{Text: "Usage"}, ID: "hdr-Usage"},
+		{Kind: doc.ParagraphBlock, Text: doc.Code{
+			Text:        "Call F or io.Reader, see the spec.",
+			Annotations: []doc.Annotation{{Pos: 5, End: 6, Kind: doc.LinkAnnotation, PathIndex: -1}, {Pos: 10, End: 12, Kind: doc.PackageLinkAnnotation, PathIndex: 0}, {Pos: 13, End: 19, Kind: doc.LinkAnnotation, PathIndex: 0}, {Pos: 25, End: 33, Kind: doc.URLAnnotation, PathIndex: 1}},
+			Paths:       []string{"io", "https://go.dev/ref/spec"},
+		}},
+		{Kind: doc.ListBlock, Items: []*doc.ListItem{{Number: "1", Paragraphs: []doc.Code{{Text: "first"}}}, {Number: "2", Paragraphs: []doc.Code{{Text: "second"}}}}},
+		{Kind: doc.CodeBlock, Text: doc.Code{Text: "x := <y>\n"}},
+	}}
+
+	wantHTML := `<h4 id="hdr-Usage">Usage <a class="permalink" href="#hdr-Usage">&para</a></h4>
+<p>Call <a href="#F">F</a> or <a href="/io">io</a>.<a href="/io#Reader">Reader</a>, see <a href="https://go.dev/ref/spec" rel="nofollow">the spec</a>.</p>
+<ol>
+<li value="1">first</li>
+<li value="2">second</li>
+</ol>
+<pre>x := &lt;y&gt;
+</pre>
+`
+	if got := string(commentFn(c, "")); got != wantHTML {
+		t.Errorf("commentFn =\n%s\nwant\n%s", got, wantHTML)
+	}
+
+	wantText := `    # Usage
+
+    Call F or io.Reader, see the spec.
+
+     1. first
+     2. second
+
+    	x := <y>
+`
+	if got := commentTextFn(c, ""); got != wantText {
+		t.Errorf("commentTextFn =\n%s\nwant\n%s", got, wantText)
+	}
+
+	// Documentation stored before comments were parsed is formatted from
+	// the comment text.
+	if got, want := string(commentFn(nil, "Hello.\n")), "<p>Hello.\n"; got != want {
+		t.Errorf("commentFn(nil) = %q, want %q", got, want)
+	}
+}
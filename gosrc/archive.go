@@ -0,0 +1,287 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxArchiveSize is the largest uncompressed repository archive that is
+	// extracted.
+	maxArchiveSize = 500 << 20
+
+	// maxArchivesPerProject is the number of commits of a project kept in the
+	// archive cache.
+	maxArchivesPerProject = 3
+)
+
+var archiveCacheDir string
+
+// SetArchiveDir enables fetching whole repository archives for services that
+// support it. Each archive is downloaded once per repository and commit and is
+// extracted to a directory below dir. All directories of the repository at
+// that commit are then read from the extracted files instead of with one or
+// more requests per directory. An empty dir disables archives.
+func SetArchiveDir(dir string) {
+	archiveCacheDir = dir
+}
+
+var archiveLocks = struct {
+	sync.Mutex
+	m map[string]*sync.Mutex
+}{m: make(map[string]*sync.Mutex)}
+
+// lockArchive serializes access to the archive cache of a project.
+func lockArchive(project string) func() {
+	archiveLocks.Lock()
+	mu := archiveLocks.m[project]
+	if mu == nil {
+		mu = new(sync.Mutex)
+		archiveLocks.m[project] = mu
+	}
+	archiveLocks.Unlock()
+	mu.Lock()
+	return mu.Unlock
+}
+
+// getArchiveDir reads the directory dir, either empty or starting with a
+// slash, of project at commit from the archive cache. If the commit is not in
+// the cache, the gzipped tar archive at archiveURL is downloaded and
+// extracted first. The archive holds the files of the repository in a single
+// top-level directory as created by GitHub and GitLab. getArchiveDir returns
+// the root of the extracted archive with the documentation files and
+// subdirectories of the directory.
+func getArchiveDir(ctx context.Context, c *httpClient, project, commit, archiveURL, dir string) (string, []*File, []string, error) {
+	projectDir := filepath.Join(archiveCacheDir, filepath.FromSlash(project))
+	root := filepath.Join(projectDir, commit)
+
+	// Holding the lock while reading prevents pruneArchives from removing
+	// the directory.
+	unlock := lockArchive(project)
+	defer unlock()
+
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		if err := fetchArchive(ctx, c, projectDir, root, archiveURL); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	files, subdirs, err := readArchiveDir(root, dir)
+	return root, files, subdirs, err
+}
+
+// fetchArchive downloads the archive at archiveURL and extracts it to root.
+func fetchArchive(ctx context.Context, c *httpClient, projectDir, root, archiveURL string) error {
+	if err := os.MkdirAll(projectDir, 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(projectDir, "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	resp, err := c.get(ctx, archiveURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return c.err(resp)
+	}
+	if err := extractArchive(tmp, resp.Body); err != nil {
+		return fmt.Errorf("gosrc: extracting %s: %v", archiveURL, err)
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		return err
+	}
+	pruneArchives(projectDir)
+	return nil
+}
+
+var errArchiveTooLarge = errors.New("archive too large")
+
+// walkArchive calls fn for the entries of the gzipped tar archive r with the
+// slash-separated name of the entry relative to the top-level directory of
+// the archive. Entries outside of the top-level directory are skipped.
+func walkArchive(r io.Reader, fn func(name string, h *tar.Header, data io.Reader) error) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(h.Name)
+		i := strings.IndexByte(name, '/')
+		if i < 0 || strings.HasPrefix(name, "../") {
+			continue
+		}
+		if err := fn(name[i+1:], h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractArchive extracts the directories and documentation files in the
+// gzipped tar archive r to dir, removing the top-level directory of the
+// archive from the names.
+func extractArchive(dir string, r io.Reader) error {
+	var size int64
+	return walkArchive(r, func(name string, h *tar.Header, data io.Reader) error {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch h.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(target, 0777)
+		case tar.TypeReg, tar.TypeRegA:
+			if !isDocFile(path.Base(name)) {
+				return nil
+			}
+			if size += h.Size; size > maxArchiveSize {
+				return errArchiveTooLarge
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+				return err
+			}
+			p, err := ioutil.ReadAll(io.LimitReader(data, h.Size))
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(target, p, 0666)
+		}
+		return nil
+	})
+}
+
+// readArchive reads the documentation files and subdirectories of the
+// directory dir, either empty or starting with a slash, from the gzipped tar
+// archive r without extracting the archive. It is used when the archive cache
+// is disabled.
+func readArchive(r io.Reader, dir string) ([]*File, []string, error) {
+	dir = strings.TrimPrefix(dir, "/")
+	var files []*File
+	var subdirs []string
+	seen := make(map[string]bool)
+	found := dir == ""
+	var size int64
+	err := walkArchive(r, func(name string, h *tar.Header, data io.Reader) error {
+		rel := name
+		if dir != "" {
+			if !strings.HasPrefix(name, dir+"/") {
+				return nil
+			}
+			rel = name[len(dir)+1:]
+		}
+		found = true
+		if i := strings.IndexByte(rel, '/'); i >= 0 {
+			// Archives created by Mercurial have no directory entries, so
+			// subdirectories are also found from the names of their files.
+			if d := rel[:i]; !seen[d] && isValidPathElement(d) {
+				seen[d] = true
+				subdirs = append(subdirs, d)
+			}
+			return nil
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if !seen[rel] && isValidPathElement(rel) {
+				seen[rel] = true
+				subdirs = append(subdirs, rel)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if !isDocFile(rel) {
+				return nil
+			}
+			if size += h.Size; size > maxArchiveSize {
+				return errArchiveTooLarge
+			}
+			p, err := ioutil.ReadAll(io.LimitReader(data, h.Size))
+			if err != nil {
+				return err
+			}
+			files = append(files, &File{Name: rel, Data: p})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, NotFoundError{Message: "Directory not found in repository archive."}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	sort.Strings(subdirs)
+	return files, subdirs, nil
+}
+
+// pruneArchives removes all but the most recently extracted commits in
+// projectDir.
+func pruneArchives(projectDir string) {
+	fis, err := ioutil.ReadDir(projectDir)
+	if err != nil {
+		return
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].ModTime().After(fis[j].ModTime()) })
+	n := 0
+	for _, fi := range fis {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), "tmp-") {
+			continue
+		}
+		if n++; n > maxArchivesPerProject {
+			os.RemoveAll(filepath.Join(projectDir, fi.Name()))
+		}
+	}
+}
+
+// readArchiveDir reads the documentation files and subdirectories of the
+// directory dir, either empty or starting with a slash, in the extracted
+// archive at root.
+func readArchiveDir(root, dir string) ([]*File, []string, error) {
+	d := filepath.Join(root, filepath.FromSlash(dir))
+	fis, err := ioutil.ReadDir(d)
+	if os.IsNotExist(err) {
+		return nil, nil, NotFoundError{Message: "Directory not found in repository archive."}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []*File
+	var subdirs []string
+	for _, fi := range fis {
+		switch {
+		case fi.IsDir():
+			if isValidPathElement(fi.Name()) {
+				subdirs = append(subdirs, fi.Name())
+			}
+		case isDocFile(fi.Name()):
+			p, err := ioutil.ReadFile(filepath.Join(d, fi.Name()))
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, &File{Name: fi.Name(), Data: p})
+		}
+	}
+	return files, subdirs, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// countingTransport counts the requests for each URL.
+type countingTransport struct {
+	t     http.RoundTripper
+	count map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count[req.URL.String()]++
+	return t.t.RoundTrip(req)
+}
+
+// testArchive returns a gzipped tar archive with the files in a top-level
+// directory as created by GitHub.
+func testArchive(t *testing.T, top string, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	dirs := map[string]bool{}
+	for name, data := range files {
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: top + "/" + name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for d := range dirs {
+		if err := tw.WriteHeader(&tar.Header{Name: top + "/" + d + "/", Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestGetGitHubArchiveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosrc-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetArchiveDir(dir)
+	defer SetArchiveDir("")
+
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	archive := testArchive(t, "owner-repo-0123456", map[string]string{
+		"go.mod":             "module github.com/owner/repo\n\ngo 1.15\n",
+		"repo.go":            "package repo",
+		"README.md":          "# repo",
+		"Makefile":           "all:",
+		"sub/sub.go":         "package sub",
+		"sub/sub_test.go":    "package sub",
+		"sub/inner/inner.go": "package inner",
+		"sub/assets/x.png":   "png",
+		"../escape.go":       "package escape",
+	})
+	ct := &countingTransport{
+		t: testTransport{
+			"https://api.github.com/repos/owner/repo": `{
+				"full_name": "owner/repo",
+				"stargazers_count": 7,
+				"default_branch": "main",
+				"created_at": "2020-01-01T00:00:00Z",
+				"pushed_at": "2099-01-01T00:00:00Z"
+			}`,
+			"https://api.github.com/repos/owner/repo/commits":        `[{"sha": "` + sha + `", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+			"https://api.github.com/repos/owner/repo/tarball/" + sha: archive,
+		},
+		count: make(map[string]int),
+	}
+	client := &http.Client{Transport: ct}
+	ctx := context.Background()
+
+	got, err := Get(ctx, client, "github.com/owner/repo/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:         "github.com/owner/repo/sub",
+		ResolvedPath:       "github.com/owner/repo/sub",
+		ResolvedGitHubPath: "github.com/owner/repo/sub",
+		ProjectRoot:        "github.com/owner/repo",
+		ProjectName:        "repo",
+		ProjectURL:         "https://github.com/owner/repo",
+		BrowseURL:          "https://github.com/owner/repo/tree/main/sub",
+		LineFmt:            "%s#L%d",
+		VCS:                "git",
+		Etag:               sha,
+		Stars:              7,
+		Files: []*File{
+			{Name: "sub.go", Data: []byte("package sub"), BrowseURL: "https://github.com/owner/repo/blob/main/sub/sub.go"},
+			{Name: "sub_test.go", Data: []byte("package sub"), BrowseURL: "https://github.com/owner/repo/blob/main/sub/sub_test.go"},
+		},
+		Subdirectories:   []string{"assets", "inner"},
+		ModulePath:       "github.com/owner/repo",
+		ModuleImportPath: "github.com/owner/repo/sub",
+		GoVersion:        "1.15",
+		Licenses:         []*License{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+
+	// Other directories of the project are read from the cached archive
+	// without further requests.
+	got, err = Get(ctx, client, "github.com/owner/repo", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	var names []string
+	for _, f := range got.Files {
+		names = append(names, f.Name)
+	}
+	if want := []string{"README.md", "go.mod", "repo.go"}; !cmp.Equal(names, want) {
+		t.Errorf("Get returned files %v, want %v", names, want)
+	}
+	if _, err := Get(ctx, client, "github.com/owner/repo/missing", ""); !IsNotFound(err) {
+		t.Errorf("Get of missing directory returned %v, want NotFoundError", err)
+	}
+	if _, err := Get(ctx, client, "github.com/owner/repo/sub/inner", sha); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError", err)
+	}
+	for u, n := range ct.count {
+		if n != 1 {
+			t.Errorf("%d requests for %s, want 1", n, u)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "github.com", "owner", "repo", "escape.go")); !os.IsNotExist(err) {
+		t.Errorf("archive extracted file outside of the cache directory")
+	}
+}
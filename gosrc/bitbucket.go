@@ -20,6 +20,7 @@ func init() {
 		pattern: regexp.MustCompile(`^bitbucket\.org/(?P<owner>[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/[a-z0-9A-Z_.\-/]*)?$`),
 		prefix:  "bitbucket.org/",
 		get:     getBitbucketDir,
+		getFile: getBitbucketFile,
 	})
 }
 
@@ -52,7 +53,7 @@ type bitbucketSrc struct {
 }
 
 type bitbucketPage struct {
-	Next string `json:"next",omitempty`
+	Next string `json:"next,omitempty"`
 }
 
 func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
@@ -133,7 +134,9 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 					dataURLs = append(dataURLs, expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{tag}/{0}", match, v.Path))
 				}
 			case "commit_directory":
-				dirs = append(dirs, v.Path)
+				if name := path.Base(v.Path); isValidPathElement(name) {
+					dirs = append(dirs, name)
+				}
 			}
 		}
 		if contents.Next == "" {
@@ -166,6 +169,11 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 	}, nil
 }
 
+func getBitbucketFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	c := &httpClient{client: client}
+	return c.getBytes(ctx, expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{commit}/{0}", match, name))
+}
+
 func getBitbucketRepo(ctx context.Context, c *httpClient, match map[string]string) (*bitbucketRepo, error) {
 	var repo bitbucketRepo
 	if _, err := c.getJSON(ctx, expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}", match), &repo); err != nil {
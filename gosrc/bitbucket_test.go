@@ -0,0 +1,161 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// queryTransport is like testTransport, but matches the query of the URL
+// too.
+type queryTransport map[string]string
+
+func (t queryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	statusCode := http.StatusOK
+	body, ok := t[req.URL.String()]
+	if !ok {
+		statusCode = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetBitbucketPagination(t *testing.T) {
+	const api = "https://api.bitbucket.org/2.0/repositories/owner/repo"
+	client := &http.Client{Transport: queryTransport{
+		api: `{"scm": "git", "created_on": "2020-01-01T00:00:00+00:00", "updated_on": "2020-02-01T00:00:00+00:00", "parent": {}}`,
+		api + "/refs?pagelen=100": `{
+			"values": [{"name": "master", "target": {"hash": "abc", "date": "2020-02-01T00:00:00+00:00"}}],
+			"next": "` + api + `/refs?pagelen=100&page=2"
+		}`,
+		api + "/refs?pagelen=100&page=2": `{
+			"values": [{"name": "go1", "target": {"hash": "def", "date": "2020-01-15T00:00:00+00:00"}}]
+		}`,
+		api + "/src/go1/sub/?pagelen=100": `{
+			"values": [
+				{"path": "sub/a.go", "type": "commit_file"},
+				{"path": "sub/inner", "type": "commit_directory"}
+			],
+			"next": "` + api + `/src/go1/sub/?pagelen=100&page=2"
+		}`,
+		api + "/src/go1/sub/?pagelen=100&page=2": `{
+			"values": [
+				{"path": "sub/b.go", "type": "commit_file"},
+				{"path": "sub/data.bin", "type": "commit_file"}
+			]
+		}`,
+		api + "/src/go1/sub/a.go": "package sub // a",
+		api + "/src/go1/sub/b.go": "package sub // b",
+	}}
+
+	got, err := Get(context.Background(), client, "bitbucket.org/owner/repo/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "bitbucket.org/owner/repo/sub",
+		ResolvedPath: "bitbucket.org/owner/repo/sub",
+		ProjectRoot:  "bitbucket.org/owner/repo",
+		ProjectName:  "repo",
+		ProjectURL:   "https://bitbucket.org/owner/repo/",
+		BrowseURL:    "https://bitbucket.org/owner/repo/src/go1/sub",
+		LineFmt:      "%s#cl-%d",
+		VCS:          "git",
+		Etag:         "git-def",
+		Fork:         true,
+		Files: []*File{
+			{Name: "a.go", Data: []byte("package sub // a"), BrowseURL: "https://bitbucket.org/owner/repo/src/go1/sub/a.go"},
+			{Name: "b.go", Data: []byte("package sub // b"), BrowseURL: "https://bitbucket.org/owner/repo/src/go1/sub/b.go"},
+		},
+		Subdirectories: []string{"inner"},
+		Licenses:       []*License{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetBitbucketServer(t *testing.T) {
+	savedServices := services
+	defer func() { services = savedServices }()
+	SetBitbucketServerHosts("stash.example.com")
+
+	const api = "https://stash.example.com/rest/api/1.0/projects/TOOLS/repos/lint"
+	client := &http.Client{Transport: queryTransport{
+		api:                       `{"slug": "lint", "scmId": "git", "archived": true, "origin": {"slug": "lint"}}`,
+		api + "/branches/default": `{"id": "refs/heads/main", "displayId": "main"}`,
+		api + "/commits?until=main&limit=10&path=cmd":              `{"values": [{"id": "0123abc", "committerTimestamp": 1600000000000}]}`,
+		api + "/browse/cmd?at=main&start=0&limit=500":              `{"children": {"values": [{"path": {"name": "main.go"}, "type": "FILE"}, {"path": {"name": "Makefile"}, "type": "FILE"}], "isLastPage": false, "nextPageStart": 2}}`,
+		api + "/browse/cmd?at=main&start=2&limit=500":              `{"children": {"values": [{"path": {"name": "internal"}, "type": "DIRECTORY"}], "isLastPage": true}}`,
+		api + "/raw/cmd/main.go?at=main":                           "package main",
+		api + "/raw/go.mod?at=main":                                "module stash.example.com/TOOLS/lint\n",
+		api + "/tags?start=0&limit=100":                            `{"values": [{"displayId": "v1.0.0"}], "isLastPage": false, "nextPageStart": 1}`,
+		api + "/tags?start=1&limit=100":                            `{"values": [{"displayId": "v1.1.0"}], "isLastPage": true}`,
+		"https://stash.example.com/rest/api/1.0/users/bob/repos/x": `{"slug": "x", "scmId": "git", "description": "Personal repository."}`,
+	}}
+	ctx := context.Background()
+
+	got, err := Get(ctx, client, "stash.example.com/TOOLS/lint/cmd", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "stash.example.com/TOOLS/lint/cmd",
+		ResolvedPath: "stash.example.com/TOOLS/lint/cmd",
+		ProjectRoot:  "stash.example.com/TOOLS/lint",
+		ProjectName:  "lint",
+		ProjectURL:   "https://stash.example.com/projects/TOOLS/repos/lint/browse",
+		BrowseURL:    "https://stash.example.com/projects/TOOLS/repos/lint/browse/cmd?at=main",
+		LineFmt:      "%s#%d",
+		VCS:          "git",
+		Etag:         "0123abc",
+		Status:       NoRecentCommits,
+		Fork:         true,
+		Files: []*File{
+			{Name: "main.go", Data: []byte("package main"), BrowseURL: "https://stash.example.com/projects/TOOLS/repos/lint/browse/cmd/main.go?at=main"},
+		},
+		Subdirectories:   []string{"internal"},
+		ModulePath:       "stash.example.com/TOOLS/lint",
+		ModuleImportPath: "stash.example.com/TOOLS/lint/cmd",
+		Licenses:         []*License{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Get(ctx, client, "stash.example.com/TOOLS/lint/cmd", "0123abc"); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if e, ok := err.(NotModifiedError); !ok || e.Status != NoRecentCommits {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError with status NoRecentCommits", err)
+	}
+
+	match := map[string]string{"host": "stash.example.com", "project": "TOOLS", "repo": "lint"}
+	versions, err := getBitbucketServerVersions(ctx, client, match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0"}; !cmp.Equal(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+
+	project, err := GetProject(ctx, client, "stash.example.com/~bob/x")
+	if err != nil {
+		t.Fatalf("GetProject returned error %v", err)
+	}
+	if project.Description != "Personal repository." {
+		t.Errorf("GetProject returned description %q", project.Description)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SetBitbucketServerHosts registers Bitbucket Server and Bitbucket Data Center
+// instances running on the given hostnames. Import paths on these hosts have
+// the form host/project/repo, where project is a project key or ~user for
+// personal repositories, and are fetched with the REST API instead of cloning
+// the repository.
+func SetBitbucketServerHosts(hosts ...string) {
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || host == "bitbucket.org" {
+			continue
+		}
+		addBitbucketServerService(host)
+	}
+}
+
+func addBitbucketServerService(host string) {
+	addService(&service{
+		pattern:     regexp.MustCompile(`^(?P<host>` + regexp.QuoteMeta(host) + `)/(?P<project>~?[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/.*)?$`),
+		prefix:      host + "/",
+		get:         getBitbucketServerDir,
+		getProject:  getBitbucketServerProject,
+		getVersions: getBitbucketServerVersions,
+		getFile:     getBitbucketServerFile,
+		getTree:     getBitbucketServerTree,
+	})
+}
+
+type bitbucketServerRepo struct {
+	Description string    `json:"description"`
+	ScmID       string    `json:"scmId"`
+	Archived    bool      `json:"archived"`
+	Origin      *struct{} `json:"origin"`
+}
+
+type bitbucketServerCommit struct {
+	ID                 string `json:"id"`
+	CommitterTimestamp int64  `json:"committerTimestamp"` // milliseconds
+}
+
+// bitbucketServerPage holds the paging fields of Bitbucket Server API
+// responses.
+type bitbucketServerPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+func bitbucketServerError(resp *http.Response) error {
+	var e struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && len(e.Errors) > 0 {
+		return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: %s (%s)", resp.StatusCode, e.Errors[0].Message, resp.Request.URL.String())}
+	}
+	return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: (%s)", resp.StatusCode, resp.Request.URL.String())}
+}
+
+// setupBitbucketServerMatch adds the base URL of the repository's REST API
+// resources and of its web pages to match.
+func setupBitbucketServerMatch(match map[string]string) {
+	if strings.HasPrefix(match["project"], "~") {
+		match["api"] = expand("https://{host}/rest/api/1.0/users/{0}/repos/{repo}", match, match["project"][1:])
+		match["web"] = expand("https://{host}/users/{0}/repos/{repo}", match, match["project"][1:])
+	} else {
+		match["api"] = expand("https://{host}/rest/api/1.0/projects/{project}/repos/{repo}", match)
+		match["web"] = expand("https://{host}/projects/{project}/repos/{repo}", match)
+	}
+}
+
+func getBitbucketServerRepo(ctx context.Context, c *httpClient, match map[string]string) (*bitbucketServerRepo, error) {
+	var repo bitbucketServerRepo
+	if _, err := c.getJSON(ctx, expand("{api}", match), &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func getBitbucketServerDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
+	setupBitbucketServerMatch(match)
+	c := &httpClient{client: client, errFn: bitbucketServerError}
+
+	repo, err := getBitbucketServerRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	if repo.ScmID != "" && repo.ScmID != "git" {
+		return nil, NotFoundError{Message: "Repository type " + repo.ScmID + " not supported."}
+	}
+
+	match["tag"] = match["version"]
+	if match["tag"] == "" {
+		var branch struct {
+			DisplayID string `json:"displayId"`
+		}
+		if _, err := c.getJSON(ctx, expand("{api}/branches/default", match), &branch); err != nil {
+			if IsNotFound(err) {
+				return nil, NotFoundError{Message: "Repository has no default branch."}
+			}
+			return nil, err
+		}
+		match["tag"] = branch.DisplayID
+	}
+	ref := url.QueryEscape(match["tag"])
+
+	u := expand("{api}/commits?until={0}&limit=10", match, ref)
+	if match["dir"] != "" {
+		u += "&path=" + url.QueryEscape(strings.TrimPrefix(match["dir"], "/"))
+	}
+	var commits struct {
+		Values []*bitbucketServerCommit `json:"values"`
+	}
+	if _, err := c.getJSON(ctx, u, &commits); err != nil {
+		return nil, err
+	}
+	if len(commits.Values) == 0 {
+		return nil, NotFoundError{Message: "package directory changed or removed"}
+	}
+
+	status := Active
+	lastCommitted := time.Unix(0, commits.Values[0].CommitterTimestamp*int64(time.Millisecond))
+	switch {
+	case repo.Archived:
+		// Archived repositories are read-only and receive no commits.
+		status = NoRecentCommits
+	case lastCommitted.Add(ExpiresAfter).Before(time.Now()):
+		status = NoRecentCommits
+	}
+	if commits.Values[0].ID == savedEtag {
+		return nil, NotModifiedError{
+			Since:  lastCommitted,
+			Status: status,
+		}
+	}
+
+	var files []*File
+	var dataURLs []string
+	var subdirs []string
+
+	for start := 0; ; {
+		var browse struct {
+			Children struct {
+				Values []*struct {
+					Path struct {
+						Name string `json:"name"`
+					} `json:"path"`
+					Type string `json:"type"`
+				} `json:"values"`
+				bitbucketServerPage
+			} `json:"children"`
+		}
+		u := expand("{api}/browse{dir}?at={0}&start={1}&limit=500", match, ref, fmt.Sprint(start))
+		if _, err := c.getJSON(ctx, u, &browse); err != nil {
+			return nil, err
+		}
+		for _, item := range browse.Children.Values {
+			name := item.Path.Name
+			switch {
+			case item.Type == "DIRECTORY":
+				if isValidPathElement(name) {
+					subdirs = append(subdirs, name)
+				}
+			case item.Type == "FILE" && isDocFile(name):
+				files = append(files, &File{Name: name, BrowseURL: expand("{web}/browse{dir}/{0}?at={1}", match, name, ref)})
+				dataURLs = append(dataURLs, expand("{api}/raw{dir}/{0}?at={1}", match, url.PathEscape(name), ref))
+			}
+		}
+		if browse.Children.IsLastPage || browse.Children.NextPageStart <= start {
+			break
+		}
+		start = browse.Children.NextPageStart
+	}
+
+	if len(files) == 0 && len(subdirs) == 0 {
+		return nil, NotFoundError{Message: "No files in directory."}
+	}
+
+	if err := c.getFiles(ctx, dataURLs, files); err != nil {
+		return nil, err
+	}
+
+	browseURL := expand("{web}/browse", match)
+	if match["dir"] != "" || match["version"] != "" {
+		browseURL = expand("{web}/browse{dir}?at={0}", match, ref)
+	}
+
+	return &Directory{
+		BrowseURL:      browseURL,
+		Etag:           commits.Values[0].ID,
+		Files:          files,
+		LineFmt:        "%s#%d",
+		ProjectName:    match["repo"],
+		ProjectRoot:    expand("{host}/{project}/{repo}", match),
+		ProjectURL:     expand("{web}/browse", match),
+		Subdirectories: subdirs,
+		VCS:            "git",
+		Status:         status,
+		Fork:           repo.Origin != nil,
+	}, nil
+}
+
+func getBitbucketServerVersions(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	setupBitbucketServerMatch(match)
+	c := &httpClient{client: client, errFn: bitbucketServerError}
+
+	var versions []string
+	for start := 0; ; {
+		var tags struct {
+			Values []*struct {
+				DisplayID string `json:"displayId"`
+			} `json:"values"`
+			bitbucketServerPage
+		}
+		if _, err := c.getJSON(ctx, expand("{api}/tags?start={0}&limit=100", match, fmt.Sprint(start)), &tags); err != nil {
+			return nil, err
+		}
+		for _, tag := range tags.Values {
+			versions = append(versions, tag.DisplayID)
+		}
+		if tags.IsLastPage || tags.NextPageStart <= start {
+			return versions, nil
+		}
+		start = tags.NextPageStart
+	}
+}
+
+func getBitbucketServerFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	c := &httpClient{client: client, errFn: bitbucketServerError}
+	return c.getBytes(ctx, expand("{api}/raw/{0}?at={1}", match, name, url.QueryEscape(match["tag"])))
+}
+
+func getBitbucketServerTree(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: bitbucketServerError}
+	var paths []string
+	for start, n := 0, 0; ; n++ {
+		if n == maxTreePages {
+			return nil, errNoListing
+		}
+		var files struct {
+			Values []string `json:"values"`
+			bitbucketServerPage
+		}
+		if _, err := c.getJSON(ctx, expand("{api}/files?at={0}&start={1}&limit=1000", match, url.QueryEscape(match["tag"]), fmt.Sprint(start)), &files); err != nil {
+			return nil, err
+		}
+		paths = append(paths, files.Values...)
+		if files.IsLastPage || files.NextPageStart <= start {
+			return paths, nil
+		}
+		start = files.NextPageStart
+	}
+}
+
+func getBitbucketServerProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error) {
+	setupBitbucketServerMatch(match)
+	c := &httpClient{client: client, errFn: bitbucketServerError}
+
+	repo, err := getBitbucketServerRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Project{
+		Description: repo.Description,
+	}, nil
+}
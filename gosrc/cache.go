@@ -0,0 +1,75 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"container/list"
+	"sync"
+)
+
+// maxCachedResponseSize is the size of the largest response body stored in a
+// responseCache.
+const maxCachedResponseSize = 1 << 20
+
+// responseCache is a fixed size cache of HTTP response bodies and their
+// entity tags. The cache is used to make conditional requests with the
+// If-None-Match header. Entries are evicted in least recently used order.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type cachedResponse struct {
+	key  string
+	etag string
+	link string // Link header, used to page through lists
+	body []byte
+}
+
+func newResponseCache(size int) *responseCache {
+	return &responseCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached response for key or nil if there is none.
+func (c *responseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	if e == nil {
+		return nil
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*cachedResponse)
+}
+
+// put stores the response body with the given entity tag and Link header for
+// key.
+func (c *responseCache) put(key, etag, link string, body []byte) {
+	if len(body) > maxCachedResponseSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := &cachedResponse{key: key, etag: etag, link: link, body: body}
+	if e := c.entries[key]; e != nil {
+		e.Value = r
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.ll.PushFront(r)
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.entries, e.Value.(*cachedResponse).key)
+	}
+}
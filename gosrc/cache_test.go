@@ -0,0 +1,207 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	c := newResponseCache(2)
+	c.put("a", `"1"`, "", []byte("a"))
+	c.put("b", `"2"`, "", []byte("b"))
+	c.get("a")
+	c.put("c", `"3"`, "", []byte("c"))
+	c.put("large", `"4"`, "", make([]byte, maxCachedResponseSize+1))
+
+	for _, tt := range []struct {
+		key  string
+		etag string
+	}{
+		{"a", `"1"`},
+		{"b", ""},
+		{"c", `"3"`},
+		{"large", ""},
+	} {
+		r := c.get(tt.key)
+		etag := ""
+		if r != nil {
+			etag = r.etag
+		}
+		if etag != tt.etag {
+			t.Errorf("get(%q) returned etag %q, want %q", tt.key, etag, tt.etag)
+		}
+	}
+}
+
+// etagTransport serves a single JSON document with an entity tag and answers
+// conditional requests for the current tag with 304 Not Modified.
+type etagTransport struct {
+	etag, body string
+	requests   []string
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	inm := req.Header.Get("If-None-Match")
+	t.requests = append(t.requests, inm)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {t.etag}},
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}
+	if inm == t.etag {
+		resp.StatusCode = http.StatusNotModified
+		resp.Body = ioutil.NopCloser(strings.NewReader(""))
+	}
+	return resp, nil
+}
+
+func TestGetCachedJSON(t *testing.T) {
+	tr := &etagTransport{etag: `"1"`, body: `{"name": "one"}`}
+	c := &httpClient{client: &http.Client{Transport: tr}, cache: newResponseCache(10)}
+	ctx := context.Background()
+	const url = "https://api.github.com/repos/owner/repo"
+
+	check := func(want string) {
+		t.Helper()
+		var v struct{ Name string }
+		if _, err := c.getJSON(ctx, url, &v); err != nil {
+			t.Fatalf("getJSON returned error %v", err)
+		}
+		if v.Name != want {
+			t.Errorf("getJSON returned name %q, want %q", v.Name, want)
+		}
+	}
+
+	check("one")
+	check("one")
+	tr.etag, tr.body = `"2"`, `{"name": "two"}`
+	check("two")
+
+	if want := []string{"", `"1"`, `"1"`}; strings.Join(tr.requests, " ") != strings.Join(want, " ") {
+		t.Errorf("requests with If-None-Match %q, want %q", tr.requests, want)
+	}
+}
+
+func TestGitHubRateLimitError(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	req, _ := http.NewRequest("GET", "https://api.github.com/repos/owner/repo", nil)
+	for _, tt := range []struct {
+		status int
+		header http.Header
+		reset  time.Time
+	}{
+		{http.StatusForbidden, http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+		}, reset},
+		{http.StatusForbidden, http.Header{"Retry-After": {"60"}}, time.Now().Add(time.Minute)},
+		{http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, time.Now().Add(time.Minute)},
+		{http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"10"}}, time.Time{}},
+		{http.StatusNotFound, http.Header{"X-Ratelimit-Remaining": {"0"}}, time.Time{}},
+	} {
+		resp := &http.Response{StatusCode: tt.status, Header: tt.header, Body: ioutil.NopCloser(strings.NewReader("{}")), Request: req}
+		err := gitHubError(resp)
+		e, ok := err.(*RateLimitError)
+		if tt.reset.IsZero() {
+			if ok {
+				t.Errorf("gitHubError(%d, %v) returned %v, want other error", tt.status, tt.header, err)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("gitHubError(%d, %v) returned %v, want RateLimitError", tt.status, tt.header, err)
+			continue
+		}
+		if d := e.Reset.Sub(tt.reset); d < -time.Second || d > time.Second {
+			t.Errorf("gitHubError(%d, %v) returned reset %v, want %v", tt.status, tt.header, e.Reset, tt.reset)
+		}
+		if e.Host != "api.github.com" {
+			t.Errorf("gitHubError(%d, %v) returned host %q, want api.github.com", tt.status, tt.header, e.Host)
+		}
+	}
+}
+
+// testPage is a page of a paginated list served by pageTransport.
+type testPage struct {
+	next string // URL of the next page
+	body string
+}
+
+// pageTransport serves paginated lists with the pagination headers of GitHub
+// and GitLab. Requests with the If-None-Match header are answered with 304
+// Not Modified without a Link header.
+type pageTransport map[string]testPage
+
+func (t pageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"1"`}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	page, ok := t[req.URL.String()]
+	switch {
+	case !ok:
+		resp.StatusCode = http.StatusNotFound
+	case req.Header.Get("If-None-Match") != "":
+		resp.StatusCode = http.StatusNotModified
+	default:
+		if page.next != "" {
+			resp.Header.Set("Link", `<`+page.next+`>; rel="next", <https://example.com/last>; rel="last"`)
+			if i := strings.LastIndex(page.next, "page="); i >= 0 {
+				resp.Header.Set("X-Next-Page", page.next[i+len("page="):])
+			}
+		}
+		resp.Body = ioutil.NopCloser(strings.NewReader(page.body))
+	}
+	return resp, nil
+}
+
+func TestGetVersionsPages(t *testing.T) {
+	const (
+		github = "https://api.github.com/repos/owner/repo/tags?per_page=100"
+		gitlab = "https://gitlab.com/api/v4/projects/owner%2Frepo"
+	)
+	client := &http.Client{Transport: pageTransport{
+		github:             {next: github + "&page=2", body: `[{"name": "v1.2.0"}, {"name": "v1.1.0"}]`},
+		github + "&page=2": {body: `[{"name": "v1.0.0"}]`},
+		gitlab:             {body: `{"path_with_namespace": "owner/repo", "default_branch": "main"}`},
+		gitlab + "/repository/tags?per_page=100&page=1": {next: gitlab + "/repository/tags?per_page=100&page=2", body: `[{"name": "v0.2.0"}]`},
+		gitlab + "/repository/tags?per_page=100&page=2": {body: `[{"name": "v0.1.0"}]`},
+	}}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		importPath string
+		want       []string
+	}{
+		{"github.com/owner/repo", []string{"v1.2.0", "v1.1.0", "v1.0.0"}},
+		// The second request is answered from the response cache.
+		{"github.com/owner/repo", []string{"v1.2.0", "v1.1.0", "v1.0.0"}},
+		{"gitlab.com/owner/repo", []string{"v0.2.0", "v0.1.0"}},
+	} {
+		versions, err := GetVersions(ctx, client, tt.importPath)
+		if err != nil {
+			t.Fatalf("GetVersions(%s) returned error %v", tt.importPath, err)
+		}
+		if strings.Join(versions, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("GetVersions(%s) = %v, want %v", tt.importPath, versions, tt.want)
+		}
+	}
+
+	if got := nextPageURL(&http.Response{Header: http.Header{"Link": {`<https://example.com/first>; rel="first"`}}}); got != "" {
+		t.Errorf("nextPageURL of last page = %q, want empty", got)
+	}
+}
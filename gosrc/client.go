@@ -13,12 +13,17 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 type httpClient struct {
 	errFn  func(*http.Response) error
 	header http.Header
 	client *http.Client
+
+	// cache, if set, stores the responses of getJSON for conditional
+	// requests.
+	cache *responseCache
 }
 
 func (c *httpClient) err(resp *http.Response) error {
@@ -33,6 +38,12 @@ func (c *httpClient) err(resp *http.Response) error {
 
 // get issues a GET to the specified URL.
 func (c *httpClient) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.getConditional(ctx, url, "")
+}
+
+// getConditional issues a GET to the specified URL. If etag is not empty, the
+// request is conditional on the resource not matching the entity tag.
+func (c *httpClient) getConditional(ctx context.Context, url, etag string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -43,6 +54,9 @@ func (c *httpClient) get(ctx context.Context, url string) (*http.Response, error
 	for k, vs := range c.header {
 		req.Header[k] = vs
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, &RemoteError{req.URL.Host, err}
@@ -97,6 +111,9 @@ func (c *httpClient) getReader(ctx context.Context, url string) (io.ReadCloser,
 }
 
 func (c *httpClient) getJSON(ctx context.Context, url string, v interface{}) (*http.Response, error) {
+	if c.cache != nil {
+		return c.getCachedJSON(ctx, url, v)
+	}
 	resp, err := c.get(ctx, url)
 	if err != nil {
 		return resp, err
@@ -112,6 +129,49 @@ func (c *httpClient) getJSON(ctx context.Context, url string, v interface{}) (*h
 	return resp, err
 }
 
+// getCachedJSON is getJSON for clients with a response cache. The request is
+// conditional on the entity tag of the cached response, if any, and the
+// cached response is used if the server reports that it is not modified.
+func (c *httpClient) getCachedJSON(ctx context.Context, url string, v interface{}) (*http.Response, error) {
+	key := c.header.Get("Accept") + " " + url
+	cached := c.cache.get(key)
+	etag := ""
+	if cached != nil {
+		etag = cached.etag
+	}
+	resp, err := c.getConditional(ctx, url, etag)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	var p []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		p = cached.body
+		if resp.Header.Get("Link") == "" && cached.link != "" {
+			if resp.Header == nil {
+				resp.Header = make(http.Header)
+			}
+			resp.Header.Set("Link", cached.link)
+		}
+	case resp.StatusCode == 200:
+		p, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return resp, &RemoteError{resp.Request.URL.Host, err}
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.cache.put(key, etag, resp.Header.Get("Link"), p)
+		}
+	default:
+		return resp, c.err(resp)
+	}
+	err = json.Unmarshal(p, v)
+	if _, ok := err.(*json.SyntaxError); ok {
+		err = NotFoundError{Message: "JSON syntax error at " + url}
+	}
+	return resp, err
+}
+
 func (c *httpClient) getFiles(ctx context.Context, urls []string, files []*File) error {
 	ch := make(chan error, len(files))
 	for i := range files {
@@ -147,3 +207,21 @@ func (c *httpClient) getFiles(ctx context.Context, urls []string, files []*File)
 	}
 	return nil
 }
+
+// nextPageURL returns the URL of the next page of a paginated list from the
+// Link header of resp, or "" if resp is the last page.
+func nextPageURL(resp *http.Response) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		u := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(u, "<") || !strings.HasSuffix(u, ">") {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return u[1 : len(u)-1]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	addGiteaService("codeberg.org")
+}
+
+// SetGiteaHosts registers Gitea and Forgejo instances running on the given
+// hostnames. Import paths on these hosts are fetched with the Gitea API
+// instead of cloning the repository.
+func SetGiteaHosts(hosts ...string) {
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || host == "codeberg.org" {
+			continue
+		}
+		addGiteaService(host)
+	}
+}
+
+func addGiteaService(host string) {
+	addService(&service{
+		pattern:     regexp.MustCompile(`^(?P<host>` + regexp.QuoteMeta(host) + `)/(?P<owner>[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/.*)?$`),
+		prefix:      host + "/",
+		get:         getGiteaDir,
+		getProject:  getGiteaProject,
+		getVersions: getGiteaVersions,
+		getFile:     getGiteaFile,
+		getTree:     getGiteaTree,
+	})
+}
+
+type giteaRepo struct {
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Fork          bool      `json:"fork"`
+	Stars         int       `json:"stars_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	DefaultBranch string    `json:"default_branch"`
+}
+
+type giteaCommit struct {
+	ID     string `json:"sha"`
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+func giteaError(resp *http.Response) error {
+	var e struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Message != "" {
+		return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: %s (%s)", resp.StatusCode, e.Message, resp.Request.URL.String())}
+	}
+	return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: (%s)", resp.StatusCode, resp.Request.URL.String())}
+}
+
+func getGiteaRepo(ctx context.Context, c *httpClient, match map[string]string) (*giteaRepo, error) {
+	var repo giteaRepo
+	if _, err := c.getJSON(ctx, expand("https://{host}/api/v1/repos/{owner}/{repo}", match), &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func getGiteaDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
+	c := &httpClient{client: client, errFn: giteaError}
+
+	repo, err := getGiteaRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	if repo.DefaultBranch == "" {
+		return nil, NotFoundError{Message: "Repository has no default branch."}
+	}
+	match["tag"] = repo.DefaultBranch
+	if match["version"] != "" {
+		match["tag"] = match["version"]
+	}
+	ref := url.QueryEscape(match["tag"])
+
+	u := expand("https://{host}/api/v1/repos/{owner}/{repo}/commits?sha={0}&limit=10", match, ref)
+	if match["dir"] != "" {
+		u += "&path=" + url.QueryEscape(strings.TrimPrefix(match["dir"], "/"))
+	}
+	var commits []*giteaCommit
+	if _, err := c.getJSON(ctx, u, &commits); err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, NotFoundError{Message: "package directory changed or removed"}
+	}
+
+	status := Active
+	lastCommitted := commits[0].Commit.Committer.Date
+	if lastCommitted.Add(ExpiresAfter).Before(time.Now()) {
+		status = NoRecentCommits
+	} else if repo.Fork {
+		dates := make([]time.Time, len(commits))
+		for i, commit := range commits {
+			dates[i] = commit.Commit.Committer.Date
+		}
+		if lastCommitted.Before(repo.CreatedAt) {
+			status = DeadEndFork
+		} else if isQuickFork(dates, repo.CreatedAt) {
+			status = QuickFork
+		}
+	}
+	if commits[0].ID == savedEtag {
+		return nil, NotModifiedError{
+			Since:  lastCommitted,
+			Status: status,
+		}
+	}
+
+	// The Gitea contents API returns array values for directories and object
+	// values for files.
+	var raw json.RawMessage
+	if _, err := c.getJSON(ctx, expand("https://{host}/api/v1/repos/{owner}/{repo}/contents{0}?ref={1}", match, escapePath(match["dir"]), ref), &raw); err != nil {
+		return nil, err
+	}
+	if raw = bytes.TrimSpace(raw); len(raw) == 0 || raw[0] != '[' {
+		return nil, NotFoundError{Message: "Not a directory"}
+	}
+	var contents []*struct {
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Path    string `json:"path"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(raw, &contents); err != nil {
+		return nil, NotFoundError{Message: "Malformed directory listing."}
+	}
+	if len(contents) == 0 {
+		return nil, NotFoundError{Message: "No files in directory."}
+	}
+
+	var files []*File
+	var dataURLs []string
+	var subdirs []string
+
+	for _, item := range contents {
+		switch {
+		case item.Type == "dir":
+			if isValidPathElement(item.Name) {
+				subdirs = append(subdirs, item.Name)
+			}
+		case item.Type == "file" && isDocFile(item.Name):
+			files = append(files, &File{Name: item.Name, BrowseURL: item.HTMLURL})
+			dataURLs = append(dataURLs, expand("https://{host}/api/v1/repos/{owner}/{repo}/raw/{0}?ref={1}", match, escapePath(item.Path), ref))
+		}
+	}
+
+	if err := c.getFiles(ctx, dataURLs, files); err != nil {
+		return nil, err
+	}
+
+	browseURL := expand("https://{host}/{owner}/{repo}", match)
+	if match["version"] != "" {
+		browseURL = expand("https://{host}/{owner}/{repo}/src/tag/{tag}{dir}", match)
+	} else if match["dir"] != "" {
+		browseURL = expand("https://{host}/{owner}/{repo}/src/branch/{tag}{dir}", match)
+	}
+
+	return &Directory{
+		BrowseURL:      browseURL,
+		Etag:           commits[0].ID,
+		Files:          files,
+		LineFmt:        "%s#L%d",
+		ProjectName:    match["repo"],
+		ProjectRoot:    expand("{host}/{owner}/{repo}", match),
+		ProjectURL:     expand("https://{host}/{owner}/{repo}", match),
+		Subdirectories: subdirs,
+		VCS:            "git",
+		Status:         status,
+		Fork:           repo.Fork,
+		Stars:          repo.Stars,
+	}, nil
+}
+
+func getGiteaVersions(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: giteaError}
+
+	var tags []*struct {
+		Name string `json:"name"`
+	}
+	if _, err := c.getJSON(ctx, expand("https://{host}/api/v1/repos/{owner}/{repo}/tags?limit=50", match), &tags); err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(tags))
+	for i, tag := range tags {
+		versions[i] = tag.Name
+	}
+	return versions, nil
+}
+
+func getGiteaFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	c := &httpClient{client: client, errFn: giteaError}
+	return c.getBytes(ctx, expand("https://{host}/api/v1/repos/{owner}/{repo}/raw/{0}?ref={1}", match, escapePath(name), url.QueryEscape(match["tag"])))
+}
+
+func getGiteaTree(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: giteaError}
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if _, err := c.getJSON(ctx, expand("https://{host}/api/v1/repos/{owner}/{repo}/git/trees/{0}?recursive=true&per_page=1000", match, url.PathEscape(match["tag"])), &tree); err != nil {
+		return nil, err
+	}
+	if tree.Truncated {
+		return nil, errNoListing
+	}
+	var paths []string
+	for _, e := range tree.Tree {
+		if e.Type == "blob" {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths, nil
+}
+
+func getGiteaProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error) {
+	c := &httpClient{client: client, errFn: giteaError}
+
+	repo, err := getGiteaRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Project{
+		Description: repo.Description,
+	}, nil
+}
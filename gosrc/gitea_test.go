@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const giteaTestAPI = "https://codeberg.org/api/v1/repos/alice/pkg"
+
+var giteaTestWeb = map[string]string{
+	giteaTestAPI: `{"full_name": "alice/pkg", "default_branch": "main", "stars_count": 3, "created_at": "2019-01-01T00:00:00Z"}`,
+	giteaTestAPI + "/commits?sha=main&limit=10&path=sub":     `[{"sha": "0123456789abcdef", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+	giteaTestAPI + "/commits?sha=v1.0.0&limit=10&path=sub":   `[{"sha": "fedcba9876543210", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+	giteaTestAPI + "/commits?sha=main&limit=10&path=main.go": `[{"sha": "0123456789abcdef", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+	giteaTestAPI + "/contents/sub?ref=main": `[` +
+		`{"type": "file", "name": "a b.go", "path": "sub/a b.go", "html_url": "https://codeberg.org/alice/pkg/src/branch/main/sub/a%20b.go"},` +
+		`{"type": "file", "name": "main.c", "path": "sub/main.c"},` +
+		`{"type": "dir", "name": "child", "path": "sub/child"}]`,
+	giteaTestAPI + "/contents/sub?ref=v1.0.0":      `[{"type": "file", "name": "a b.go", "path": "sub/a b.go", "html_url": "https://codeberg.org/alice/pkg/src/tag/v1.0.0/sub/a%20b.go"}]`,
+	giteaTestAPI + "/contents/main.go?ref=main":    ` {"type": "file", "name": "main.go", "path": "main.go"}`,
+	giteaTestAPI + "/raw/sub/a%20b.go?ref=main":    `package sub`,
+	giteaTestAPI + "/raw/sub/a%20b.go?ref=v1.0.0":  `package sub // v1`,
+	giteaTestAPI + "/raw/go.mod?ref=main":          "module codeberg.org/alice/pkg\n\ngo 1.18\n",
+	giteaTestAPI + "/tags?limit=50":                `[{"name": "v1.0.0"}, {"name": "v1.1.0"}, {"name": "v0.9.0"}]`,
+	"https://codeberg.org/api/v1/repos/alice/none": `{"full_name": "alice/none", "created_at": "2019-01-01T00:00:00Z"}`,
+}
+
+func TestGetGiteaDir(t *testing.T) {
+	client := &http.Client{Transport: queryTransport(giteaTestWeb)}
+	ctx := context.Background()
+
+	dir, err := Get(ctx, client, "codeberg.org/alice/pkg/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "codeberg.org/alice/pkg/sub",
+		ResolvedPath: "codeberg.org/alice/pkg/sub",
+		BrowseURL:    "https://codeberg.org/alice/pkg/src/branch/main/sub",
+		Etag:         "0123456789abcdef",
+		Files: []*File{{
+			Name:      "a b.go",
+			Data:      []byte("package sub"),
+			BrowseURL: "https://codeberg.org/alice/pkg/src/branch/main/sub/a%20b.go",
+		}},
+		LineFmt:          "%s#L%d",
+		ProjectName:      "pkg",
+		ProjectRoot:      "codeberg.org/alice/pkg",
+		ProjectURL:       "https://codeberg.org/alice/pkg",
+		Subdirectories:   []string{"child"},
+		VCS:              "git",
+		Stars:            3,
+		ModulePath:       "codeberg.org/alice/pkg",
+		ModuleImportPath: "codeberg.org/alice/pkg/sub",
+		GoVersion:        "1.18",
+		Licenses:         []*License{},
+	}
+	if !cmp.Equal(dir, want) {
+		t.Errorf("Get returned\n     %+v,\nwant %+v", dir, want)
+	}
+
+	if _, err := Get(ctx, client, "codeberg.org/alice/pkg/sub", "0123456789abcdef"); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError", err)
+	}
+}
+
+func TestGetGiteaNotDirectory(t *testing.T) {
+	client := &http.Client{Transport: queryTransport(giteaTestWeb)}
+	ctx := context.Background()
+
+	_, err := Get(ctx, client, "codeberg.org/alice/pkg/main.go", "")
+	if e, ok := err.(NotFoundError); !ok || e.Message != "Not a directory" {
+		t.Errorf("Get of file returned %v, want NotFoundError for not a directory", err)
+	}
+
+	if _, err := Get(ctx, client, "codeberg.org/alice/none", ""); !IsNotFound(err) {
+		t.Errorf("Get of repository without default branch returned %v, want NotFoundError", err)
+	}
+}
+
+func TestGetGiteaVersion(t *testing.T) {
+	client := &http.Client{Transport: queryTransport(giteaTestWeb)}
+	ctx := context.Background()
+
+	versions, err := GetVersions(ctx, client, "codeberg.org/alice/pkg/sub")
+	if err != nil {
+		t.Fatalf("GetVersions returned error %v", err)
+	}
+	if want := []string{"v1.1.0", "v1.0.0", "v0.9.0"}; !cmp.Equal(versions, want) {
+		t.Errorf("GetVersions returned %v, want %v", versions, want)
+	}
+
+	dir, err := GetVersion(ctx, client, "codeberg.org/alice/pkg/sub", "v1.0.0", "")
+	if err != nil {
+		t.Fatalf("GetVersion returned error %v", err)
+	}
+	if want := "https://codeberg.org/alice/pkg/src/tag/v1.0.0/sub"; dir.BrowseURL != want {
+		t.Errorf("GetVersion returned browse URL %q, want %q", dir.BrowseURL, want)
+	}
+	if dir.Etag != "fedcba9876543210" || len(dir.Files) != 1 || string(dir.Files[0].Data) != "package sub // v1" {
+		t.Errorf("GetVersion returned etag %q, files %+v", dir.Etag, dir.Files)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,6 +26,9 @@ func init() {
 		get:             getGitHubDir,
 		getPresentation: getGitHubPresentation,
 		getProject:      getGitHubProject,
+		getVersions:     getGitHubVersions,
+		getFile:         getGitHubFile,
+		getTree:         getGitHubTree,
 	})
 
 	addService(&service{
@@ -47,7 +52,14 @@ type githubCommit struct {
 	} `json:"commit"`
 }
 
+// gitHubCache holds the responses of GitHub API requests. Conditional
+// requests answered with 304 Not Modified do not count against the rate limit.
+var gitHubCache = newResponseCache(2000)
+
 func gitHubError(resp *http.Response) error {
+	if err := gitHubRateLimitError(resp); err != nil {
+		return err
+	}
 	var e struct {
 		Message string `json:"message"`
 	}
@@ -57,28 +69,75 @@ func gitHubError(resp *http.Response) error {
 	return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: (%s)", resp.StatusCode, resp.Request.URL.String())}
 }
 
+type githubRepo struct {
+	FullName      string    `json:"full_name"`
+	Fork          bool      `json:"fork"`
+	Stars         int       `json:"stargazers_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	PushedAt      time.Time `json:"pushed_at"`
+	DefaultBranch string    `json:"default_branch"`
+}
+
+// gitHubStatus returns the status of the repository given its most recent
+// commits in reverse chronological order.
+func gitHubStatus(repo *githubRepo, commits []*githubCommit) DirectoryStatus {
+	lastCommitted := commits[0].Commit.Committer.Date
+	switch {
+	case lastCommitted.Add(ExpiresAfter).Before(time.Now()):
+		return NoRecentCommits
+	case repo.Fork && repo.PushedAt.Before(repo.CreatedAt):
+		return DeadEndFork
+	case repo.Fork && isQuickFork(githubCommitDates(commits), repo.CreatedAt):
+		return QuickFork
+	}
+	return Active
+}
+
+// gitHubRateLimitError returns a RateLimitError if resp reports that the
+// primary or a secondary rate limit of the GitHub API was exceeded.
+func gitHubRateLimitError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil {
+			return &RateLimitError{Host: resp.Request.URL.Host, Reset: time.Now().Add(time.Duration(n) * time.Second)}
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+	reset := time.Now().Add(time.Hour)
+	if n, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(n, 0)
+	}
+	return &RateLimitError{Host: resp.Request.URL.Host, Reset: reset}
+}
+
 func getGitHubDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
 
-	c := &httpClient{client: client, errFn: gitHubError}
+	c := &httpClient{client: client, errFn: gitHubError, cache: gitHubCache}
 
-	var repo struct {
-		FullName      string    `json:"full_name"`
-		Fork          bool      `json:"fork"`
-		Stars         int       `json:"stargazers_count"`
-		CreatedAt     time.Time `json:"created_at"`
-		PushedAt      time.Time `json:"pushed_at"`
-		DefaultBranch string    `json:"default_branch"`
+	if archiveCacheDir != "" {
+		return getGitHubArchiveDir(ctx, c, match, savedEtag)
 	}
 
+	var repo githubRepo
 	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}", match), &repo); err != nil {
 		return nil, err
 	}
 
-	status := Active
 	var commits []*githubCommit
-	u := expand("https://api.github.com/repos/{owner}/{repo}/commits", match)
+	q := url.Values{}
+	if match["version"] != "" {
+		q.Set("sha", match["version"])
+	}
 	if match["dir"] != "" {
-		u += fmt.Sprintf("?path=%s", url.QueryEscape(match["dir"]))
+		q.Set("path", match["dir"])
+	}
+	u := expand("https://api.github.com/repos/{owner}/{repo}/commits", match)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
 	}
 	if _, err := c.getJSON(ctx, u, &commits); err != nil {
 		return nil, err
@@ -87,19 +146,10 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		return nil, NotFoundError{Message: "package directory changed or removed"}
 	}
 
-	lastCommitted := commits[0].Commit.Committer.Date
-	if lastCommitted.Add(ExpiresAfter).Before(time.Now()) {
-		status = NoRecentCommits
-	} else if repo.Fork {
-		if repo.PushedAt.Before(repo.CreatedAt) {
-			status = DeadEndFork
-		} else if isQuickFork(commits, repo.CreatedAt) {
-			status = QuickFork
-		}
-	}
+	status := gitHubStatus(&repo, commits)
 	if commits[0].ID == savedEtag {
 		return nil, NotModifiedError{
-			Since:  lastCommitted,
+			Since:  commits[0].Commit.Committer.Date,
 			Status: status,
 		}
 	}
@@ -111,7 +161,11 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		HTMLURL string `json:"html_url"`
 	}
 
-	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/contents{dir}", match), &contents); err != nil {
+	u = expand("https://api.github.com/repos/{owner}/{repo}/contents{dir}", match)
+	if match["version"] != "" {
+		u += "?ref=" + url.QueryEscape(match["version"])
+	}
+	if _, err := c.getJSON(ctx, u, &contents); err != nil {
 		// The GitHub content API returns array values for directories
 		// and object values for files. If there's a type mismatch at
 		// the beginning of the response, then assume that the path is
@@ -148,10 +202,15 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 	}
 
 	browseURL := expand("https://github.com/{owner}/{repo}", match)
-	if match["dir"] != "" {
+	if match["dir"] != "" || match["version"] != "" {
 		match["tag"] = repo.DefaultBranch // TODO: This doesn't respect "go1" tag/branch special case.
+		if match["version"] != "" {
+			match["tag"] = match["version"]
+		}
 		browseURL = expand("https://github.com/{owner}/{repo}/tree/{tag}{dir}", match)
 	}
+	// The files of the repository are listed at the commit of the directory.
+	match["commit"] = commits[0].ID
 
 	return &Directory{
 		ResolvedGitHubPath: "github.com/" + repo.FullName + match["dir"],
@@ -170,20 +229,207 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 	}, nil
 }
 
+// githubRepoState is the repository information and recent commits used to
+// read the directories of a repository from its archive.
+type githubRepoState struct {
+	repo    githubRepo
+	commits []*githubCommit
+	fetched time.Time
+}
+
+// githubRepoStateTTL is how long a githubRepoState is reused. Crawling all
+// packages of a project within this time costs two API requests and one
+// archive download.
+const githubRepoStateTTL = time.Minute
+
+var githubRepoStates = struct {
+	sync.Mutex
+	m map[string]*githubRepoState
+}{m: make(map[string]*githubRepoState)}
+
+func getGitHubRepoState(ctx context.Context, c *httpClient, match map[string]string) (*githubRepoState, error) {
+	key := strings.ToLower(match["owner"] + "/" + match["repo"] + "@" + match["version"])
+	now := time.Now()
+
+	githubRepoStates.Lock()
+	state := githubRepoStates.m[key]
+	githubRepoStates.Unlock()
+	if state != nil && now.Sub(state.fetched) < githubRepoStateTTL {
+		return state, nil
+	}
+
+	state = &githubRepoState{fetched: now}
+	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}", match), &state.repo); err != nil {
+		return nil, err
+	}
+	u := expand("https://api.github.com/repos/{owner}/{repo}/commits", match)
+	if match["version"] != "" {
+		u += "?sha=" + url.QueryEscape(match["version"])
+	}
+	if _, err := c.getJSON(ctx, u, &state.commits); err != nil {
+		return nil, err
+	}
+	if len(state.commits) == 0 {
+		return nil, NotFoundError{Message: "Repository has no commits."}
+	}
+
+	githubRepoStates.Lock()
+	for k, s := range githubRepoStates.m {
+		if now.Sub(s.fetched) >= githubRepoStateTTL {
+			delete(githubRepoStates.m, k)
+		}
+	}
+	githubRepoStates.m[key] = state
+	githubRepoStates.Unlock()
+	return state, nil
+}
+
+// getGitHubArchiveDir gets the directory from the archive of the repository
+// at its most recent commit. The etag of all directories in the repository is
+// the commit hash.
+func getGitHubArchiveDir(ctx context.Context, c *httpClient, match map[string]string, savedEtag string) (*Directory, error) {
+	state, err := getGitHubRepoState(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	commit := state.commits[0]
+	status := gitHubStatus(&state.repo, state.commits)
+	if commit.ID == savedEtag {
+		return nil, NotModifiedError{
+			Since:  commit.Commit.Committer.Date,
+			Status: status,
+		}
+	}
+
+	project := "github.com/" + state.repo.FullName
+	if state.repo.FullName == "" {
+		project = expand("github.com/{owner}/{repo}", match)
+	}
+	archiveURL := expand("https://api.github.com/repos/{owner}/{repo}/tarball/{0}", match, commit.ID)
+	root, files, subdirs, err := getArchiveDir(ctx, c, project, commit.ID, archiveURL, match["dir"])
+	if err != nil {
+		return nil, err
+	}
+
+	match["tag"] = state.repo.DefaultBranch
+	if match["version"] != "" {
+		match["tag"] = match["version"]
+	}
+	match["archive"] = root
+	for _, f := range files {
+		f.BrowseURL = expand("https://github.com/{owner}/{repo}/blob/{tag}{dir}/{0}", match, f.Name)
+	}
+	browseURL := expand("https://github.com/{owner}/{repo}", match)
+	if match["dir"] != "" || match["version"] != "" {
+		browseURL = expand("https://github.com/{owner}/{repo}/tree/{tag}{dir}", match)
+	}
+
+	return &Directory{
+		ResolvedGitHubPath: "github.com/" + state.repo.FullName + match["dir"],
+		BrowseURL:          browseURL,
+		Etag:               commit.ID,
+		Files:              files,
+		LineFmt:            "%s#L%d",
+		ProjectName:        match["repo"],
+		ProjectRoot:        expand("github.com/{owner}/{repo}", match),
+		ProjectURL:         expand("https://github.com/{owner}/{repo}", match),
+		Subdirectories:     subdirs,
+		VCS:                "git",
+		Status:             status,
+		Fork:               state.repo.Fork,
+		Stars:              state.repo.Stars,
+	}, nil
+}
+
+func getGitHubVersions(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: gitHubError, cache: gitHubCache}
+
+	var versions []string
+	u := expand("https://api.github.com/repos/{owner}/{repo}/tags?per_page=100", match)
+	for n := 0; u != "" && n < maxVersionPages; n++ {
+		var tags []*struct {
+			Name string `json:"name"`
+		}
+		resp, err := c.getJSON(ctx, u, &tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			versions = append(versions, tag.Name)
+		}
+		u = nextPageURL(resp)
+	}
+	return versions, nil
+}
+
+func getGitHubFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	if root := match["archive"]; root != "" {
+		return diskFS(root).readFile(name)
+	}
+	c := &httpClient{client: client, errFn: gitHubError}
+	ref := match["tag"]
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return c.getBytes(ctx, expand("https://raw.githubusercontent.com/{owner}/{repo}/{0}/{1}", match, ref, name))
+}
+
+func getGitHubTree(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	if root := match["archive"]; root != "" {
+		return diskFS(root).tree()
+	}
+	c := &httpClient{client: client, errFn: gitHubError, cache: gitHubCache}
+	ref := match["commit"]
+	if ref == "" {
+		ref = match["tag"]
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/git/trees/{0}?recursive=1", match, url.PathEscape(ref)), &tree); err != nil {
+		return nil, err
+	}
+	if tree.Truncated {
+		return nil, errNoListing
+	}
+	var paths []string
+	for _, e := range tree.Tree {
+		if e.Type == "blob" {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths, nil
+}
+
+func githubCommitDates(commits []*githubCommit) []time.Time {
+	dates := make([]time.Time, len(commits))
+	for i, commit := range commits {
+		dates[i] = commit.Commit.Committer.Date
+	}
+	return dates
+}
+
 // isQuickFork reports whether the repository is a "quick fork":
 // it has fewer than 3 commits, all within a week of the repo creation, createdAt.
-// Commits must be in reverse chronological order by Commit.Committer.Date.
-func isQuickFork(commits []*githubCommit, createdAt time.Time) bool {
+// Commit dates must be in reverse chronological order.
+func isQuickFork(commitDates []time.Time, createdAt time.Time) bool {
 	oneWeekOld := createdAt.Add(7 * 24 * time.Hour)
 	if oneWeekOld.After(time.Now()) {
 		return false // a newborn baby of a repository
 	}
 	n := 0
-	for _, commit := range commits {
-		if commit.Commit.Committer.Date.After(oneWeekOld) {
+	for _, date := range commitDates {
+		if date.After(oneWeekOld) {
 			return false
 		}
-		if commit.Commit.Committer.Date.Before(createdAt) {
+		if date.Before(createdAt) {
 			break
 		}
 		n++
@@ -254,7 +500,7 @@ func getGitHubPresentation(ctx context.Context, client *http.Client, match map[s
 // GetGitHubUpdates returns the full names ("owner/repo") of recently pushed GitHub repositories.
 // by pushedAfter.
 func GetGitHubUpdates(ctx context.Context, client *http.Client, pushedAfter string) (maxPushedAt string, names []string, err error) {
-	c := httpClient{client: client, header: gitHubPreviewHeader}
+	c := httpClient{client: client, header: gitHubPreviewHeader, errFn: gitHubError}
 
 	if pushedAfter == "" {
 		pushedAfter = time.Now().Add(-24 * time.Hour).UTC().Format("2006-01-02T15:04:05Z")
@@ -282,7 +528,7 @@ func GetGitHubUpdates(ctx context.Context, client *http.Client, pushedAfter stri
 }
 
 func getGitHubProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error) {
-	c := &httpClient{client: client, errFn: gitHubError}
+	c := &httpClient{client: client, errFn: gitHubError, cache: gitHubCache}
 
 	var repo struct {
 		Description string
@@ -298,7 +544,7 @@ func getGitHubProject(ctx context.Context, client *http.Client, match map[string
 }
 
 func getGistDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
-	c := &httpClient{client: client, errFn: gitHubError}
+	c := &httpClient{client: client, errFn: gitHubError, cache: gitHubCache}
 
 	var gist struct {
 		Files map[string]struct {
@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// gitHubTransport serves the responses in web with an entity tag. Requests
+// conditional on the entity tag are answered with 304 Not Modified. The
+// requests are recorded with their If-None-Match header.
+type gitHubTransport struct {
+	web map[string]string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (t *gitHubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := req.URL.String()
+	inm := req.Header.Get("If-None-Match")
+	t.mu.Lock()
+	t.requests = append(t.requests, strings.TrimSpace(u+" "+inm))
+	t.mu.Unlock()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"1"`}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	body, ok := t.web[u]
+	switch {
+	case !ok:
+		resp.StatusCode = http.StatusNotFound
+	case inm == `"1"`:
+		resp.StatusCode = http.StatusNotModified
+	default:
+		resp.Body = ioutil.NopCloser(strings.NewReader(body))
+	}
+	return resp, nil
+}
+
+// treeRequests returns the requests for lists of the files of repositories.
+func (t *gitHubTransport) treeRequests() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var requests []string
+	for _, r := range t.requests {
+		if strings.Contains(r, "/git/trees/") {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func TestGetGitHubDirTree(t *testing.T) {
+	const (
+		api = "https://api.github.com/repos/alice/"
+		raw = "https://raw.githubusercontent.com/alice/"
+	)
+	tr := &gitHubTransport{web: map[string]string{
+		api + "tree":                     `{"full_name": "alice/tree", "default_branch": "main"}`,
+		api + "tree/commits?path=%2Fsub": `[{"sha": "c1", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+		api + "tree/contents/sub": `[` +
+			`{"type": "file", "name": "a.go", "git_url": "` + api + `tree/git/blobs/a"},` +
+			`{"type": "dir", "name": "nested"}]`,
+		api + "tree/git/blobs/a": "package sub",
+		api + "tree/git/trees/c1?recursive=1": `{"tree": [` +
+			`{"path": "go.mod", "type": "blob"},` +
+			`{"path": "LICENSE", "type": "blob"},` +
+			`{"path": "sub/a.go", "type": "blob"},` +
+			`{"path": "sub/nested/go.mod", "type": "blob"}]}`,
+		raw + "tree/main/go.mod":  "module github.com/alice/tree\n",
+		raw + "tree/main/LICENSE": testMITLicense,
+
+		api + "mod":         `{"full_name": "alice/mod", "default_branch": "main"}`,
+		api + "mod/commits": `[{"sha": "c2", "commit": {"committer": {"date": "2099-01-01T00:00:00Z"}}}]`,
+		api + "mod/contents": `[` +
+			`{"type": "file", "name": "go.mod", "git_url": "` + api + `mod/git/blobs/mod"},` +
+			`{"type": "file", "name": "LICENSE", "git_url": "` + api + `mod/git/blobs/license"},` +
+			`{"type": "dir", "name": "sub"}]`,
+		api + "mod/git/blobs/mod":     "module github.com/alice/mod\n",
+		api + "mod/git/blobs/license": testMITLicense,
+	}}
+	client := &http.Client{Transport: tr}
+	ctx := context.Background()
+
+	// The files of the repository are listed at the commit of the directory
+	// once.
+	for i := 0; i < 2; i++ {
+		dir, err := Get(ctx, client, "github.com/alice/tree/sub", "")
+		if err != nil {
+			t.Fatalf("Get returned error %v", err)
+		}
+		if dir.ModulePath != "github.com/alice/tree" || len(dir.Licenses) != 1 || len(dir.Subdirectories) != 0 {
+			t.Errorf("Get returned module %q, licenses %+v, subdirectories %v; want module, license and no nested module",
+				dir.ModulePath, dir.Licenses, dir.Subdirectories)
+		}
+	}
+	want := []string{api + "tree/git/trees/c1?recursive=1"}
+	if got := tr.treeRequests(); !cmp.Equal(got, want) {
+		t.Errorf("tree requests %q, want %q", got, want)
+	}
+
+	// Lists of files are conditional requests.
+	repoTrees.Lock()
+	delete(repoTrees.m, "github.com/alice/tree@c1")
+	repoTrees.Unlock()
+	if _, err := Get(ctx, client, "github.com/alice/tree/sub", ""); err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want = append(want, api+`tree/git/trees/c1?recursive=1 "1"`)
+	if got := tr.treeRequests(); !cmp.Equal(got, want) {
+		t.Errorf("tree requests %q, want %q", got, want)
+	}
+
+	// The files of a directory with go.mod and license files are not
+	// listed.
+	dir, err := Get(ctx, client, "github.com/alice/mod", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	if dir.ModulePath != "github.com/alice/mod" || len(dir.Licenses) != 1 || !cmp.Equal(dir.Subdirectories, []string{"sub"}) {
+		t.Errorf("Get returned module %q, licenses %+v, subdirectories %v", dir.ModulePath, dir.Licenses, dir.Subdirectories)
+	}
+	if got := tr.treeRequests(); len(got) != len(want) {
+		t.Errorf("tree requests %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	addGitLabService("gitlab.com")
+}
+
+// SetGitLabHosts registers self-managed GitLab instances running on the given
+// hostnames. Import paths on these hosts are fetched with the GitLab v4 API
+// instead of cloning the repository.
+func SetGitLabHosts(hosts ...string) {
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || host == "gitlab.com" {
+			continue
+		}
+		addGitLabService(host)
+	}
+}
+
+func addGitLabService(host string) {
+	addService(&service{
+		pattern:         regexp.MustCompile(`^(?P<host>` + regexp.QuoteMeta(host) + `)/(?P<owner>[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/.*)?$`),
+		prefix:          host + "/",
+		get:             getGitLabDir,
+		getPresentation: getGitLabPresentation,
+		getProject:      getGitLabProject,
+		getVersions:     getGitLabVersions,
+		getFile:         getGitLabFile,
+		getTree:         getGitLabTree,
+	})
+}
+
+type gitlabProject struct {
+	PathWithNamespace string    `json:"path_with_namespace"`
+	Description       string    `json:"description"`
+	DefaultBranch     string    `json:"default_branch"`
+	Stars             int       `json:"star_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	ForkedFrom        *struct{} `json:"forked_from_project"`
+}
+
+type gitlabCommit struct {
+	ID            string    `json:"id"`
+	CommittedDate time.Time `json:"committed_date"`
+}
+
+func gitLabError(resp *http.Response) error {
+	var e struct {
+		Message interface{} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Message != nil {
+		return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: %v (%s)", resp.StatusCode, e.Message, resp.Request.URL.String())}
+	}
+	return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: (%s)", resp.StatusCode, resp.Request.URL.String())}
+}
+
+// setupGitLabMatch adds the API base URL and the URL encoded project ID used
+// by the GitLab v4 API to match.
+func setupGitLabMatch(match map[string]string) {
+	match["api"] = expand("https://{host}/api/v4/projects/", match) + url.PathEscape(expand("{owner}/{repo}", match))
+}
+
+// getGitLabProjectInfo gets the project of match. Projects can be in nested
+// subgroups, so if no project is found, the first element of the directory is
+// moved to the project path and the lookup is repeated. For example, the
+// import path gitlab.com/group/sub/repo/dir is tried as project group/sub
+// with directory /repo/dir and then as project group/sub/repo with directory
+// /dir. The owner, repo, dir and api keys of match are updated to the project
+// found.
+func getGitLabProjectInfo(ctx context.Context, c *httpClient, match map[string]string) (*gitlabProject, error) {
+	for {
+		setupGitLabMatch(match)
+		var project gitlabProject
+		_, err := c.getJSON(ctx, expand("{api}", match), &project)
+		if err == nil {
+			return &project, nil
+		}
+		if !IsNotFound(err) || match["dir"] == "" {
+			return nil, err
+		}
+		subgroup, dir := match["dir"][1:], ""
+		if i := strings.Index(subgroup, "/"); i >= 0 {
+			subgroup, dir = subgroup[:i], subgroup[i:]
+		}
+		if !isValidPathElement(subgroup) {
+			return nil, err
+		}
+		match["owner"] += "/" + match["repo"]
+		match["repo"] = subgroup
+		match["dir"] = dir
+	}
+}
+
+func getGitLabDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+
+	project, err := getGitLabProjectInfo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	if project.DefaultBranch == "" {
+		return nil, NotFoundError{Message: "Repository has no default branch."}
+	}
+	match["tag"] = project.DefaultBranch
+	if match["version"] != "" {
+		match["tag"] = match["version"]
+	}
+	ref := url.QueryEscape(match["tag"])
+
+	u := expand("{api}/repository/commits?ref_name={0}", match, ref)
+	if match["dir"] != "" {
+		u += "&path=" + url.QueryEscape(strings.TrimPrefix(match["dir"], "/"))
+	}
+	var commits []*gitlabCommit
+	if _, err := c.getJSON(ctx, u, &commits); err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, NotFoundError{Message: "package directory changed or removed"}
+	}
+
+	status := Active
+	fork := project.ForkedFrom != nil
+	lastCommitted := commits[0].CommittedDate
+	if lastCommitted.Add(ExpiresAfter).Before(time.Now()) {
+		status = NoRecentCommits
+	} else if fork {
+		dates := make([]time.Time, len(commits))
+		for i, commit := range commits {
+			dates[i] = commit.CommittedDate
+		}
+		if lastCommitted.Before(project.CreatedAt) {
+			status = DeadEndFork
+		} else if isQuickFork(dates, project.CreatedAt) {
+			status = QuickFork
+		}
+	}
+	if commits[0].ID == savedEtag {
+		return nil, NotModifiedError{
+			Since:  lastCommitted,
+			Status: status,
+		}
+	}
+
+	var files []*File
+	var dataURLs []string
+	var subdirs []string
+
+	for page := 1; page > 0; {
+		u := expand("{api}/repository/tree?per_page=100&ref={0}&page={1}", match, ref, fmt.Sprint(page))
+		if match["dir"] != "" {
+			u += "&path=" + url.QueryEscape(strings.TrimPrefix(match["dir"], "/"))
+		}
+		var tree []*struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+			Path string `json:"path"`
+		}
+		resp, err := c.getJSON(ctx, u, &tree)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range tree {
+			switch {
+			case item.Type == "tree":
+				if isValidPathElement(item.Name) {
+					subdirs = append(subdirs, item.Name)
+				}
+			case item.Type == "blob" && isDocFile(item.Name):
+				files = append(files, &File{Name: item.Name, BrowseURL: expand("https://{host}/{owner}/{repo}/-/blob/{tag}/{0}", match, item.Path)})
+				dataURLs = append(dataURLs, expand("{api}/repository/files/{0}/raw?ref={1}", match, url.PathEscape(item.Path), ref))
+			}
+		}
+		page = 0
+		if next := resp.Header.Get("X-Next-Page"); next != "" {
+			fmt.Sscan(next, &page)
+		}
+	}
+
+	if len(files) == 0 && len(subdirs) == 0 {
+		return nil, NotFoundError{Message: "No files in directory."}
+	}
+
+	if err := c.getFiles(ctx, dataURLs, files); err != nil {
+		return nil, err
+	}
+
+	browseURL := expand("https://{host}/{owner}/{repo}", match)
+	if match["dir"] != "" || match["version"] != "" {
+		browseURL = expand("https://{host}/{owner}/{repo}/-/tree/{tag}{dir}", match)
+	}
+
+	return &Directory{
+		BrowseURL:      browseURL,
+		Etag:           commits[0].ID,
+		Files:          files,
+		LineFmt:        "%s#L%d",
+		ProjectName:    match["repo"],
+		ProjectRoot:    expand("{host}/{owner}/{repo}", match),
+		ProjectURL:     expand("https://{host}/{owner}/{repo}", match),
+		Subdirectories: subdirs,
+		VCS:            "git",
+		Status:         status,
+		Fork:           fork,
+		Stars:          project.Stars,
+	}, nil
+}
+
+func getGitLabPresentation(ctx context.Context, client *http.Client, match map[string]string) (*Presentation, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+
+	project, err := getGitLabProjectInfo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	branch := url.QueryEscape(project.DefaultBranch)
+	dir := strings.TrimPrefix(match["dir"], "/")
+	if dir != "" {
+		dir += "/"
+	}
+
+	p, err := c.getBytes(ctx, expand("{api}/repository/files/{0}/raw?ref={1}", match, url.PathEscape(dir+match["file"]), branch))
+	if err != nil {
+		return nil, err
+	}
+
+	rawBase, err := url.Parse(expand("https://{host}/{owner}/{repo}/-/raw/{0}{dir}/", match, project.DefaultBranch))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &presBuilder{
+		data:     p,
+		filename: match["file"],
+		fetch: func(fnames []string) ([]*File, error) {
+			var files []*File
+			var dataURLs []string
+			for _, fname := range fnames {
+				files = append(files, &File{Name: fname})
+				dataURLs = append(dataURLs, expand("{api}/repository/files/{0}/raw?ref={1}", match, url.PathEscape(dir+fname), branch))
+			}
+			err := c.getFiles(ctx, dataURLs, files)
+			return files, err
+		},
+		resolveURL: func(fname string) string {
+			u, err := rawBase.Parse(fname)
+			if err != nil {
+				return "/notfound"
+			}
+			return u.String()
+		},
+	}
+
+	return b.build()
+}
+
+func getGitLabVersions(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+
+	if _, err := getGitLabProjectInfo(ctx, c, match); err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for page, n := 1, 0; page > 0 && n < maxVersionPages; n++ {
+		var tags []*struct {
+			Name string `json:"name"`
+		}
+		resp, err := c.getJSON(ctx, expand("{api}/repository/tags?per_page=100&page={0}", match, fmt.Sprint(page)), &tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			versions = append(versions, tag.Name)
+		}
+		page = 0
+		if next := resp.Header.Get("X-Next-Page"); next != "" {
+			fmt.Sscan(next, &page)
+		}
+	}
+	return versions, nil
+}
+
+func getGitLabFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+	return c.getBytes(ctx, expand("{api}/repository/files/{0}/raw?ref={1}", match, url.PathEscape(name), url.QueryEscape(match["tag"])))
+}
+
+func getGitLabTree(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+	var paths []string
+	for page, n := 1, 0; page > 0; n++ {
+		if n == maxTreePages {
+			return nil, errNoListing
+		}
+		var tree []*struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		}
+		resp, err := c.getJSON(ctx, expand("{api}/repository/tree?recursive=true&per_page=100&ref={0}&page={1}", match, url.QueryEscape(match["tag"]), fmt.Sprint(page)), &tree)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range tree {
+			if item.Type == "blob" {
+				paths = append(paths, item.Path)
+			}
+		}
+		page = 0
+		if next := resp.Header.Get("X-Next-Page"); next != "" {
+			fmt.Sscan(next, &page)
+		}
+	}
+	return paths, nil
+}
+
+func getGitLabProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error) {
+	c := &httpClient{client: client, errFn: gitLabError}
+
+	project, err := getGitLabProjectInfo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Project{
+		Description: project.Description,
+	}, nil
+}
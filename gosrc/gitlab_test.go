@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const gitLabTestAPI = "https://gitlab.com/api/v4/projects/alice%2Fpkg"
+
+var gitLabTestWeb = map[string]string{
+	gitLabTestAPI: `{"path_with_namespace": "alice/pkg", "default_branch": "main", "star_count": 7, "created_at": "2019-01-01T00:00:00Z"}`,
+	gitLabTestAPI + "/repository/commits?ref_name=main&path=sub": `[{"id": "0123456789abcdef", "committed_date": "2099-01-01T00:00:00Z"}]`,
+	gitLabTestAPI + "/repository/tree?per_page=100&ref=main&page=1&path=sub": `[` +
+		`{"type": "blob", "name": "main.go", "path": "sub/main.go"},` +
+		`{"type": "blob", "name": "main.c", "path": "sub/main.c"},` +
+		`{"type": "tree", "name": "child", "path": "sub/child"},` +
+		`{"type": "tree", "name": "nested", "path": "sub/nested"}]`,
+	gitLabTestAPI + "/repository/tree?recursive=true&per_page=100&ref=main&page=1": `[` +
+		`{"type": "blob", "name": "go.mod", "path": "go.mod"},` +
+		`{"type": "tree", "name": "sub", "path": "sub"},` +
+		`{"type": "blob", "name": "main.go", "path": "sub/main.go"},` +
+		`{"type": "blob", "name": "main.c", "path": "sub/main.c"},` +
+		`{"type": "tree", "name": "child", "path": "sub/child"},` +
+		`{"type": "blob", "name": "child.go", "path": "sub/child/child.go"},` +
+		`{"type": "tree", "name": "nested", "path": "sub/nested"},` +
+		`{"type": "blob", "name": "go.mod", "path": "sub/nested/go.mod"}]`,
+	gitLabTestAPI + "/repository/files/sub%2Fmain.go/raw?ref=main": `package main`,
+	gitLabTestAPI + "/repository/files/go.mod/raw?ref=main":        "module gitlab.com/alice/pkg\n\ngo 1.16\n",
+}
+
+func TestGetGitLabDir(t *testing.T) {
+	ct := &countingTransport{t: queryTransport(gitLabTestWeb), count: map[string]int{}}
+	client := &http.Client{Transport: ct}
+	ctx := context.Background()
+
+	dir, err := Get(ctx, client, "gitlab.com/alice/pkg/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "gitlab.com/alice/pkg/sub",
+		ResolvedPath: "gitlab.com/alice/pkg/sub",
+		BrowseURL:    "https://gitlab.com/alice/pkg/-/tree/main/sub",
+		Etag:         "0123456789abcdef",
+		Files: []*File{{
+			Name:      "main.go",
+			Data:      []byte("package main"),
+			BrowseURL: "https://gitlab.com/alice/pkg/-/blob/main/sub/main.go",
+		}},
+		LineFmt:          "%s#L%d",
+		ProjectName:      "pkg",
+		ProjectRoot:      "gitlab.com/alice/pkg",
+		ProjectURL:       "https://gitlab.com/alice/pkg",
+		Subdirectories:   []string{"child"},
+		VCS:              "git",
+		Stars:            7,
+		ModulePath:       "gitlab.com/alice/pkg",
+		ModuleImportPath: "gitlab.com/alice/pkg/sub",
+		GoVersion:        "1.16",
+		Licenses:         []*License{},
+	}
+	if !cmp.Equal(dir, want) {
+		t.Errorf("Get returned\n     %+v,\nwant %+v", dir, want)
+	}
+
+	// The go.mod file is read only from the directories which contain one.
+	for u, n := range ct.count {
+		if strings.HasSuffix(u, "go.mod/raw?ref=main") && u != gitLabTestAPI+"/repository/files/go.mod/raw?ref=main" {
+			t.Errorf("Get requested %s %d times", u, n)
+		}
+	}
+
+	if _, err := Get(ctx, client, "gitlab.com/alice/pkg/sub", "0123456789abcdef"); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError", err)
+	}
+}
+
+func TestGetGitLabSubgroupDir(t *testing.T) {
+	client := &http.Client{Transport: testTransport{
+		"https://gitlab.com/api/v4/projects/group%2Fsub%2Frepo":                                   `{"path_with_namespace": "group/sub/repo", "default_branch": "main", "created_at": "2019-01-01T00:00:00Z"}`,
+		"https://gitlab.com/api/v4/projects/group%2Fsub%2Frepo/repository/commits":                `[{"id": "fedcba9876543210", "committed_date": "2099-01-01T00:00:00Z"}]`,
+		"https://gitlab.com/api/v4/projects/group%2Fsub%2Frepo/repository/tree":                   `[{"type": "blob", "name": "pkg.go", "path": "pkg/pkg.go"}]`,
+		"https://gitlab.com/api/v4/projects/group%2Fsub%2Frepo/repository/files/pkg%2Fpkg.go/raw": `package pkg`,
+	}}
+
+	dir, err := Get(context.Background(), client, "gitlab.com/group/sub/repo/pkg", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	if dir.ProjectRoot != "gitlab.com/group/sub/repo" || dir.ProjectName != "repo" {
+		t.Errorf("Get returned project root %q, name %q; want gitlab.com/group/sub/repo, repo", dir.ProjectRoot, dir.ProjectName)
+	}
+	if want := "https://gitlab.com/group/sub/repo/-/tree/main/pkg"; dir.BrowseURL != want {
+		t.Errorf("Get returned browse URL %q, want %q", dir.BrowseURL, want)
+	}
+	if len(dir.Files) != 1 || dir.Files[0].BrowseURL != "https://gitlab.com/group/sub/repo/-/blob/main/pkg/pkg.go" {
+		t.Errorf("Get returned files %+v", dir.Files)
+	}
+
+	if _, err := Get(context.Background(), client, "gitlab.com/group/sub/missing/pkg", ""); !IsNotFound(err) {
+		t.Errorf("Get of missing project returned %v, want NotFoundError", err)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// goAPIURL is the location of the api/go1.*.txt files of a Go release. The
+// files list the exported API of the standard library added by each release.
+const goAPIURL = "https://raw.githubusercontent.com/golang/go/{ref}/api/{file}"
+
+var goReleaseRe = regexp.MustCompile(`^go1(?:\.[0-9]+)*$`)
+
+// stdAPI caches the API history of the standard library for the release with
+// the entity tag etag. The API files are fetched without holding mu so that
+// lookups of the cached history are not blocked by a fetch. Concurrent
+// lookups of an uncached release wait for a single fetch.
+var stdAPI struct {
+	mu    sync.Mutex
+	etag  string
+	since map[string]map[string]string
+	fetch *stdAPIFetch // fetch in progress, nil if none
+}
+
+// stdAPIFetch is a fetch of the API history of the release with the entity tag
+// etag. The since and err fields are set before done is closed.
+type stdAPIFetch struct {
+	etag  string
+	done  chan struct{}
+	since map[string]map[string]string
+	err   error
+}
+
+// getStandardSince returns the Go versions which added the exported
+// declarations of the standard package importPath. See Directory.Since.
+func getStandardSince(ctx context.Context, c *httpClient, importPath, etag string) (map[string]string, error) {
+	stdAPI.mu.Lock()
+	since := stdAPI.since
+	if since == nil || stdAPI.etag != etag {
+		f := stdAPI.fetch
+		if f == nil || f.etag != etag {
+			f = &stdAPIFetch{etag: etag, done: make(chan struct{})}
+			stdAPI.fetch = f
+			stdAPI.mu.Unlock()
+
+			f.since, f.err = fetchStandardAPI(ctx, c, etag)
+
+			stdAPI.mu.Lock()
+			if f.err == nil {
+				stdAPI.etag = etag
+				stdAPI.since = f.since
+			}
+			if stdAPI.fetch == f {
+				stdAPI.fetch = nil
+			}
+			stdAPI.mu.Unlock()
+			close(f.done)
+		} else {
+			stdAPI.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+		since = f.since
+	} else {
+		stdAPI.mu.Unlock()
+	}
+
+	var result map[string]string
+	for name, version := range since[importPath] {
+		if version == "" {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[name] = version
+	}
+	return result, nil
+}
+
+// fetchStandardAPI reads the API files of the Go release with the entity tag
+// etag, or of the development tree if etag is not a release. It returns the
+// version which added each declaration by import path and name.
+func fetchStandardAPI(ctx context.Context, c *httpClient, etag string) (map[string]map[string]string, error) {
+	ref := "master"
+	if goReleaseRe.MatchString(etag) {
+		ref = etag
+	}
+	since := make(map[string]map[string]string)
+	for i := 0; ; i++ {
+		version := "1." + strconv.Itoa(i)
+		name := "go" + version + ".txt"
+		if i == 0 {
+			// Go 1.0 is the baseline. Its declarations are not annotated.
+			version = ""
+			name = "go1.txt"
+		}
+		p, err := c.getBytes(ctx, expand(goAPIURL, map[string]string{"ref": ref, "file": name}))
+		if IsNotFound(err) && i > 0 {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		parseAPI(bytes.NewReader(p), version, since)
+	}
+	return since, nil
+}
+
+// parseAPI reads an API file of the Go release version. The first version
+// seen for a declaration is recorded in since by import path and name.
+//
+// The lines of an API file have the form:
+//
+//	pkg net/http, func Get(string) (*Response, error)
+//	pkg net/http, method (*Client) CloseIdleConnections()
+//	pkg net/http, type Cookie struct, SameSite SameSite
+//	pkg net/http, type Pusher interface, Push(string, *PushOptions) error
+//	pkg syscall (windows-386), const ERROR_NOT_FOUND Errno
+func parseAPI(r io.Reader, version string, since map[string]map[string]string) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		importPath, name := parseAPILine(s.Text())
+		if name == "" {
+			continue
+		}
+		m := since[importPath]
+		if m == nil {
+			m = make(map[string]string)
+			since[importPath] = m
+		}
+		if _, ok := m[name]; !ok {
+			m[name] = version
+		}
+	}
+}
+
+// parseAPILine returns the import path and the name of the declaration on a
+// line of an API file. The name of a method, struct field or interface
+// method is Type.Name.
+func parseAPILine(line string) (importPath, name string) {
+	if !strings.HasPrefix(line, "pkg ") {
+		return "", ""
+	}
+	line = line[len("pkg "):]
+	i := strings.Index(line, ", ")
+	if i < 0 {
+		return "", ""
+	}
+	importPath, line = line[:i], line[i+len(", "):]
+	if j := strings.Index(importPath, " ("); j >= 0 {
+		importPath = importPath[:j]
+	}
+
+	kind, line := cutWord(line)
+	switch kind {
+	case "func", "const", "var":
+		return importPath, identPrefix(line)
+	case "method":
+		// (recv) Name(...)
+		if !strings.HasPrefix(line, "(") {
+			return "", ""
+		}
+		j := strings.Index(line, ") ")
+		if j < 0 {
+			return "", ""
+		}
+		recv := identPrefix(strings.TrimPrefix(line[1:j], "*"))
+		return importPath, recv + "." + identPrefix(line[j+len(") "):])
+	case "type":
+		typ := identPrefix(line)
+		if j := strings.Index(line, ", "); j >= 0 && !strings.Contains(line[:j], "{") {
+			member := line[j+len(", "):]
+			if strings.HasPrefix(member, "embedded ") {
+				member = member[len("embedded "):]
+				if k := strings.LastIndex(member, "."); k >= 0 {
+					member = member[k+1:]
+				}
+				member = strings.TrimPrefix(member, "*")
+			}
+			return importPath, typ + "." + identPrefix(member)
+		}
+		return importPath, typ
+	}
+	return "", ""
+}
+
+func cutWord(s string) (word, rest string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// identPrefix returns the identifier at the start of s.
+func identPrefix(s string) string {
+	i := strings.IndexAny(s, " ([,=")
+	if i < 0 {
+		return s
+	}
+	return s[:i]
+}
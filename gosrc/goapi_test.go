@@ -0,0 +1,157 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var apiFiles = []struct {
+	version, data string
+}{
+	{"", `pkg net/http, func Get(string) (*Response, error)
+pkg net/http, type Client struct
+pkg net/http, type Client struct, Jar CookieJar
+pkg net/http, type Cookie struct
+pkg net/http, type Cookie struct, Name string
+pkg io, type ReadCloser interface { Close, Read }
+`},
+	{"1.12", `pkg net/http, method (*Client) CloseIdleConnections()
+pkg net/http, func Get(string) (*Response, error)
+pkg io, type StringWriter interface { WriteString }
+pkg io, type StringWriter interface, WriteString(string) (int, error)
+`},
+	{"1.18", `pkg net/http, type Cookie struct, SameSite SameSite
+pkg net/http, type MaxBytesError struct
+pkg net/http, type MaxBytesError struct, embedded *bytes.Buffer
+pkg sync/atomic, method (*Pointer[$0]) Load() *$0
+pkg syscall (windows-386), const ERROR_NOT_FOUND = 1168
+pkg syscall (windows-386), const ERROR_NOT_FOUND Errno
+pkg slices, func Clone[$0 interface{ ~[]$1 }, $1 interface{}]($0) $0
+`},
+}
+
+func TestParseAPI(t *testing.T) {
+	since := make(map[string]map[string]string)
+	for _, f := range apiFiles {
+		parseAPI(strings.NewReader(f.data), f.version, since)
+	}
+	want := map[string]map[string]string{
+		"net/http": {
+			"Get":                         "",
+			"Client":                      "",
+			"Client.Jar":                  "",
+			"Cookie":                      "",
+			"Cookie.Name":                 "",
+			"Client.CloseIdleConnections": "1.12",
+			"Cookie.SameSite":             "1.18",
+			"MaxBytesError":               "1.18",
+			"MaxBytesError.Buffer":        "1.18",
+		},
+		"io": {
+			"ReadCloser":               "",
+			"StringWriter":             "1.12",
+			"StringWriter.WriteString": "1.12",
+		},
+		"sync/atomic": {"Pointer.Load": "1.18"},
+		"syscall":     {"ERROR_NOT_FOUND": "1.18"},
+		"slices":      {"Clone": "1.18"},
+	}
+	if diff := cmp.Diff(want, since); diff != "" {
+		t.Errorf("parseAPI() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// apiTransport serves the API files of a Go release after release is closed.
+// It records the requested URLs and whether stdAPI.mu was held during a
+// request.
+type apiTransport struct {
+	release chan struct{}
+
+	mu     sync.Mutex
+	count  map[string]int
+	locked bool
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-t.release
+	locked := !stdAPI.mu.TryLock()
+	if !locked {
+		stdAPI.mu.Unlock()
+	}
+	t.mu.Lock()
+	t.count[req.URL.String()]++
+	t.locked = t.locked || locked
+	t.mu.Unlock()
+
+	statusCode := http.StatusOK
+	var body string
+	switch req.URL.Path {
+	case "/golang/go/go1.2/api/go1.txt":
+		body = apiFiles[0].data
+	case "/golang/go/go1.2/api/go1.1.txt":
+		body = apiFiles[1].data
+	default:
+		statusCode = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetStandardSince(t *testing.T) {
+	tr := &apiTransport{release: make(chan struct{}), count: make(map[string]int)}
+	c := &httpClient{client: &http.Client{Transport: tr}}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]map[string]string, 3)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = getStandardSince(ctx, c, "net/http", "go1.2")
+		}(i)
+	}
+	close(tr.release)
+	wg.Wait()
+
+	want := map[string]string{"Client.CloseIdleConnections": "1.1"}
+	for i := range results {
+		if errs[i] != nil {
+			t.Errorf("getStandardSince returned error %v", errs[i])
+		} else if diff := cmp.Diff(want, results[i]); diff != "" {
+			t.Errorf("getStandardSince() mismatch (-want +got):\n%s", diff)
+		}
+	}
+	for u, n := range tr.count {
+		if n != 1 {
+			t.Errorf("%s fetched %d times, want 1", u, n)
+		}
+	}
+	if tr.locked {
+		t.Error("stdAPI.mu held while fetching the API files")
+	}
+
+	n := len(tr.count)
+	if _, err := getStandardSince(ctx, c, "io", "go1.2"); err != nil {
+		t.Errorf("getStandardSince of cached release returned error %v", err)
+	}
+	if len(tr.count) != n {
+		t.Errorf("getStandardSince of cached release fetched %d files, want 0", len(tr.count)-n)
+	}
+}
@@ -9,6 +9,7 @@ package gosrc
 import (
 	"context"
 	"errors"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
@@ -52,6 +53,11 @@ func getStandardDir(ctx context.Context, client *http.Client, importPath string,
 		return nil, err
 	}
 
+	since, err := getStandardSince(ctx, c, importPath, etag)
+	if err != nil {
+		log.Printf("Getting standard library API history for %s: %v", importPath, err)
+	}
+
 	return &Directory{
 		BrowseURL:    browseURL,
 		Etag:         etag,
@@ -62,5 +68,6 @@ func getStandardDir(ctx context.Context, client *http.Client, importPath string,
 		ProjectRoot:  "",
 		ProjectURL:   "https://golang.org/",
 		ResolvedPath: importPath,
+		Since:        since,
 	}, nil
 }
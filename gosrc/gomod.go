@@ -0,0 +1,197 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goMod is the subset of a go.mod or go.work file used for documentation.
+type goMod struct {
+	// Module path from the module directive.
+	Module string
+
+	// Go version from the go directive.
+	Go string
+
+	// Replace maps the module paths in replace directives with a local
+	// directory as the replacement to the slash-separated directory.
+	Replace map[string]string
+
+	// Use is the list of module directories from the use directives of a
+	// go.work file.
+	Use []string
+}
+
+var majorVersionSuffixPat = regexp.MustCompile(`/v[0-9]+$`)
+
+// goModFields splits a go.mod line into its fields, removing comments and
+// unquoting quoted strings.
+func goModFields(line string) []string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if strings.HasPrefix(f, `"`) || strings.HasPrefix(f, "`") {
+			if s, err := strconv.Unquote(f); err == nil {
+				fields[i] = s
+			}
+		}
+	}
+	return fields
+}
+
+// isLocalReplacement returns true if the replacement path p in a replace
+// directive is a directory on the local file system.
+func isLocalReplacement(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || path.IsAbs(p) || filepath.IsAbs(p)
+}
+
+// parseGoMod parses the directives in the go.mod or go.work file data.
+// Unknown and malformed directives are ignored.
+func parseGoMod(data []byte) *goMod {
+	var mod goMod
+	block := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := goModFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			if len(fields) == 2 && mod.Module == "" {
+				mod.Module = fields[1]
+			}
+		case "go":
+			if len(fields) == 2 && mod.Go == "" {
+				mod.Go = fields[1]
+			}
+		case "use":
+			if len(fields) == 2 {
+				mod.Use = append(mod.Use, fields[1])
+			}
+		case "replace":
+			// replace old [version] => new [version]
+			if n := len(fields); (n == 4 || n == 5) && fields[n-2] == "=>" && isLocalReplacement(fields[n-1]) {
+				if mod.Replace == nil {
+					mod.Replace = make(map[string]string)
+				}
+				mod.Replace[fields[1]] = fields[n-1]
+			}
+		}
+	}
+	return &mod
+}
+
+// goModModulePath returns the module path declared in the go.mod file data.
+func goModModulePath(data []byte) string {
+	return parseGoMod(data).Module
+}
+
+// setModule records the module containing dir. The go.mod file is looked
+// for in the directory's files and then in each parent directory up to the
+// repository root in fs. The dirPath argument is the directory's path
+// relative to the repository root, either empty or starting with a slash. A
+// nil fs limits the search to the directory's files.
+func setModule(dir *Directory, dirPath string, fs repoFS) error {
+	for _, f := range dir.Files {
+		if f.Name == "go.mod" {
+			setGoMod(dir, f.Data, "")
+			return nil
+		}
+	}
+	if fs == nil {
+		return nil
+	}
+	for d := dirPath; d != "" && d != "/"; {
+		d = path.Dir(d)
+		rel := strings.TrimPrefix(d, "/")
+		if ok, err := hasFile(fs, rel, "go.mod"); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		data, err := fs.readFile(path.Join(rel, "go.mod"))
+		if _, ok := err.(NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		setGoMod(dir, data, strings.TrimPrefix(dirPath, strings.TrimSuffix(d, "/")))
+		return nil
+	}
+	return nil
+}
+
+// removeNestedModules removes the subdirectories containing a go.mod file
+// from dir.Subdirectories. They are the roots of other modules, which are
+// separate projects. The subdirectories are kept if fs cannot list
+// directories.
+func removeNestedModules(dir *Directory, dirPath string, fs repoFS) error {
+	if fs == nil || len(dir.Subdirectories) == 0 {
+		return nil
+	}
+	var subdirs []string
+	for _, name := range dir.Subdirectories {
+		names, err := fs.readDir(strings.TrimPrefix(path.Join(dirPath, name), "/"))
+		if err == errNoListing {
+			return nil
+		}
+		if err != nil && !IsNotFound(err) {
+			return err
+		}
+		nested := false
+		for _, n := range names {
+			nested = nested || n == "go.mod"
+		}
+		if !nested {
+			subdirs = append(subdirs, name)
+		}
+	}
+	dir.Subdirectories = subdirs
+	return nil
+}
+
+// setGoMod sets the module fields of dir from the go.mod file data. The rel
+// argument is the path of dir relative to the directory containing the
+// go.mod file, either empty or starting with a slash.
+func setGoMod(dir *Directory, data []byte, rel string) {
+	mod := parseGoMod(data)
+	if mod.Module == "" {
+		return
+	}
+	dir.ModulePath = mod.Module
+	dir.ModuleImportPath = mod.Module + rel
+	dir.GoVersion = mod.Go
+}
+
+// applyModule makes the module containing dir the project root of dir when
+// dir is at its canonical module import path. Nested modules and major
+// version subdirectories become separate projects this way.
+func applyModule(dir *Directory) {
+	if dir.ModulePath == "" || dir.ModuleImportPath != dir.ImportPath || dir.ModulePath == dir.ProjectRoot {
+		return
+	}
+	dir.ProjectRoot = dir.ModulePath
+	dir.ProjectName = path.Base(majorVersionSuffixPat.ReplaceAllString(dir.ModulePath, ""))
+}
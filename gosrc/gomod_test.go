@@ -0,0 +1,231 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var parseGoModTests = []struct {
+	data string
+	want goMod
+}{
+	{"module example.com/m\n\ngo 1.14\n", goMod{Module: "example.com/m", Go: "1.14"}},
+	{"// comment\nmodule \"example.com/quoted\" // trailing\n", goMod{Module: "example.com/quoted"}},
+	{"module (\n\texample.com/block\n)\n", goMod{Module: "example.com/block"}},
+	{"require (\n\tgo 1.0\n)\ngo 1.18\n", goMod{Go: "1.18"}},
+	{"garbage\n", goMod{}},
+	{"module example.com/m\nreplace example.com/a => ../a\nreplace (\n\texample.com/b v1.0.0 => ./b\n\texample.com/c => example.com/d v1.2.0\n)\n",
+		goMod{Module: "example.com/m", Replace: map[string]string{"example.com/a": "../a", "example.com/b": "./b"}}},
+	{"go 1.18\n\nuse (\n\t./a\n\t./b\n)\n", goMod{Go: "1.18", Use: []string{"./a", "./b"}}},
+}
+
+func TestParseGoMod(t *testing.T) {
+	for _, tt := range parseGoModTests {
+		if got := parseGoMod([]byte(tt.data)); !cmp.Equal(*got, tt.want) {
+			t.Errorf("parseGoMod(%q) = %+v, want %+v", tt.data, *got, tt.want)
+		}
+	}
+}
+
+var setModuleTests = []struct {
+	name    string
+	dirPath string
+	files   map[string]string
+	want    Directory
+}{
+	{
+		name:    "in directory",
+		dirPath: "/a",
+		files:   map[string]string{"a/go.mod": "module example.com/r/a\ngo 1.15\n"},
+		want:    Directory{ModulePath: "example.com/r/a", ModuleImportPath: "example.com/r/a", GoVersion: "1.15"},
+	},
+	{
+		name:    "root module",
+		dirPath: "/a/b",
+		files:   map[string]string{"go.mod": "module example.com/r\n"},
+		want:    Directory{ModulePath: "example.com/r", ModuleImportPath: "example.com/r/a/b"},
+	},
+	{
+		name:    "nested module",
+		dirPath: "/a/b",
+		files:   map[string]string{"go.mod": "module example.com/r\n", "a/go.mod": "module example.com/r/a\n"},
+		want:    Directory{ModulePath: "example.com/r/a", ModuleImportPath: "example.com/r/a/b"},
+	},
+	{
+		name:    "major version subdirectory",
+		dirPath: "/v2/c",
+		files:   map[string]string{"go.mod": "module example.com/r\n", "v2/go.mod": "module example.com/r/v2\n"},
+		want:    Directory{ModulePath: "example.com/r/v2", ModuleImportPath: "example.com/r/v2/c"},
+	},
+	{
+		name:    "no module",
+		dirPath: "/a",
+		files:   map[string]string{},
+		want:    Directory{},
+	},
+}
+
+// mapFS is a repoFS for tests. The keys are file paths relative to the
+// repository root.
+type mapFS map[string]string
+
+func (fs mapFS) readFile(name string) ([]byte, error) {
+	data, ok := fs[name]
+	if !ok {
+		return nil, NotFoundError{Message: "not found"}
+	}
+	return []byte(data), nil
+}
+
+func (fs mapFS) readDir(name string) ([]string, error) {
+	var names []string
+	found := name == ""
+	for p := range fs {
+		dir, file := path.Split(p)
+		if strings.TrimSuffix(dir, "/") == name {
+			names = append(names, file)
+		}
+		found = found || strings.HasPrefix(p, name+"/")
+	}
+	if !found {
+		return nil, NotFoundError{Message: "not found"}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// noListFS is a repoFS which cannot list directories.
+type noListFS struct{ mapFS }
+
+func (noListFS) readDir(name string) ([]string, error) { return nil, errNoListing }
+
+func TestSetModule(t *testing.T) {
+	for _, tt := range setModuleTests {
+		for _, fs := range []repoFS{mapFS(tt.files), noListFS{mapFS(tt.files)}} {
+			var dir Directory
+			want := tt.want
+			if data, ok := tt.files[tt.dirPath[1:]+"/go.mod"]; ok {
+				dir.Files = []*File{{Name: "go.mod", Data: []byte(data)}}
+				want.Files = dir.Files
+			}
+			if err := setModule(&dir, tt.dirPath, fs); err != nil {
+				t.Errorf("%s: setModule(%T) returned error %v", tt.name, fs, err)
+				continue
+			}
+			if !cmp.Equal(dir, want) {
+				t.Errorf("%s: setModule(%T) got %+v, want %+v", tt.name, fs, dir, want)
+			}
+		}
+	}
+}
+
+func TestRemoveNestedModules(t *testing.T) {
+	fs := mapFS{
+		"go.mod":           "module example.com/r\n",
+		"a/go.mod":         "module example.com/r/a\n",
+		"a/a.go":           "package a",
+		"b/b.go":           "package b",
+		"c/d/go.mod":       "module example.com/r/c/d\n",
+		"c/d/d.go":         "package d",
+		"sub/e/go.mod":     "module example.com/r/sub/e\n",
+		"sub/f/f.go":       "package f",
+		"sub/testdata/x.c": "",
+	}
+	for _, tt := range []struct {
+		dirPath string
+		subdirs []string
+		fs      repoFS
+		want    []string
+	}{
+		{"", []string{"a", "b", "c"}, fs, []string{"b", "c"}},
+		{"/sub", []string{"e", "f", "testdata"}, fs, []string{"f", "testdata"}},
+		{"/sub", []string{"e", "f"}, noListFS{fs}, []string{"e", "f"}},
+		{"/sub", []string{"e"}, fs, nil},
+	} {
+		dir := &Directory{Subdirectories: tt.subdirs}
+		if err := removeNestedModules(dir, tt.dirPath, tt.fs); err != nil {
+			t.Errorf("removeNestedModules(%q, %v, %T) returned error %v", tt.dirPath, tt.subdirs, tt.fs, err)
+			continue
+		}
+		if !cmp.Equal(dir.Subdirectories, tt.want) {
+			t.Errorf("removeNestedModules(%q, %v, %T) = %v, want %v", tt.dirPath, tt.subdirs, tt.fs, dir.Subdirectories, tt.want)
+		}
+	}
+}
+
+func TestApplyModule(t *testing.T) {
+	dir := &Directory{
+		ImportPath:       "github.com/u/r/v2/c",
+		ProjectRoot:      "github.com/u/r",
+		ProjectName:      "r",
+		ModulePath:       "github.com/u/r/v2",
+		ModuleImportPath: "github.com/u/r/v2/c",
+	}
+	applyModule(dir)
+	if dir.ProjectRoot != "github.com/u/r/v2" || dir.ProjectName != "r" {
+		t.Errorf("applyModule set project root %q, name %q; want github.com/u/r/v2, r", dir.ProjectRoot, dir.ProjectName)
+	}
+
+	dir = &Directory{
+		ImportPath:       "github.com/fork/r",
+		ProjectRoot:      "github.com/fork/r",
+		ModulePath:       "github.com/u/r",
+		ModuleImportPath: "github.com/u/r",
+	}
+	applyModule(dir)
+	if dir.ProjectRoot != "github.com/fork/r" {
+		t.Errorf("applyModule changed project root of directory not at module path to %q", dir.ProjectRoot)
+	}
+}
+
+func TestGetMajorBranch(t *testing.T) {
+	const api = "https://gitlab.com/api/v4/projects/alice%2Fmajor"
+	client := &http.Client{Transport: queryTransport{
+		api: `{"path_with_namespace": "alice/major", "default_branch": "main", "created_at": "2019-01-01T00:00:00Z"}`,
+		api + "/repository/commits?ref_name=main&path=sub":             `[{"id": "0123456789abcdef", "committed_date": "2099-01-01T00:00:00Z"}]`,
+		api + "/repository/tree?per_page=100&ref=main&page=1&path=sub": `[{"type": "blob", "name": "sub.go", "path": "sub/sub.go"}]`,
+		api + "/repository/files/sub%2Fsub.go/raw?ref=main":            `package sub`,
+		api + "/repository/files/go.mod/raw?ref=main":                  "module gitlab.com/alice/major/v2\n",
+	}}
+	ctx := context.Background()
+
+	dir, err := Get(ctx, client, "gitlab.com/alice/major/v2/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	if dir.ImportPath != "gitlab.com/alice/major/v2/sub" || dir.ModuleImportPath != dir.ImportPath {
+		t.Errorf("Get returned import path %q, module import path %q; want gitlab.com/alice/major/v2/sub", dir.ImportPath, dir.ModuleImportPath)
+	}
+	if dir.ProjectRoot != "gitlab.com/alice/major/v2" || dir.ProjectName != "major" {
+		t.Errorf("Get returned project root %q, name %q; want gitlab.com/alice/major/v2, major", dir.ProjectRoot, dir.ProjectName)
+	}
+	if want := "https://gitlab.com/alice/major/-/tree/main/sub"; dir.BrowseURL != want {
+		t.Errorf("Get returned browse URL %q, want %q", dir.BrowseURL, want)
+	}
+
+	// The path without the major version suffix is documented until it is
+	// redirected to the module path.
+	dir, err = Get(ctx, client, "gitlab.com/alice/major/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	if dir.ModuleImportPath != "gitlab.com/alice/major/v2/sub" {
+		t.Errorf("Get returned module import path %q, want gitlab.com/alice/major/v2/sub", dir.ModuleImportPath)
+	}
+
+	if _, err := Get(ctx, client, "gitlab.com/alice/major/v3/sub", ""); !IsNotFound(err) {
+		t.Errorf("Get of other major version returned %v, want NotFoundError", err)
+	}
+}
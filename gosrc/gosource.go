@@ -0,0 +1,198 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// GoSourceError describes an invalid go-source meta tag.
+type GoSourceError struct {
+	// Content attribute of the meta tag.
+	Content string
+
+	// Name of the invalid field: "prefix", "home", "directory" or "file".
+	// Field is empty if the tag does not have four fields.
+	Field string
+
+	Message string
+}
+
+func (e *GoSourceError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("go-source meta tag %q: %s", e.Content, e.Message)
+	}
+	return fmt.Sprintf("go-source meta tag %q: %s field: %s", e.Content, e.Field, e.Message)
+}
+
+// sourceMeta represents the values in a go-source meta tag. The format of the
+// content attribute is
+//
+//	prefix home directory file
+//
+// where prefix is the import path prefix of the repository and home is the
+// URL of the project home page. Directory is a URL template for the directory
+// page and may use the {dir} and {/dir} substitutions. File is a URL template
+// for a file and may use {dir}, {/dir}, {file} and {line}. The {dir}
+// substitution is the directory path relative to prefix and {/dir} is the same
+// path with a leading slash, or empty for the root directory. Any of home,
+// directory and file may be "_" to use the defaults of the version control
+// service.
+type sourceMeta struct {
+	projectRoot  string
+	projectURL   string
+	dirTemplate  string
+	fileTemplate string
+
+	// Template for the file URL and the line suffix split from the file
+	// template. The line suffix is empty if the file template does not use
+	// {line}.
+	fileHead, lineTail string
+
+	// Problems found with go-source meta tags for the import path.
+	errors []string
+}
+
+var sourceSubstitutionPat = regexp.MustCompile(`\{[^{}]*\}`)
+
+// checkSourceTemplate validates a URL template from a go-source meta tag.
+func checkSourceTemplate(content, field, template string, allowed ...string) error {
+	if template == "_" {
+		return nil
+	}
+	if !isHTTPURL(template) {
+		return &GoSourceError{Content: content, Field: field, Message: "not an http or https URL"}
+	}
+	for _, s := range sourceSubstitutionPat.FindAllString(template, -1) {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || s == a
+		}
+		if !ok {
+			return &GoSourceError{Content: content, Field: field, Message: fmt.Sprintf("unknown substitution %s", s)}
+		}
+	}
+	if _, err := url.Parse(sourceSubstitutionPat.ReplaceAllString(template, "x")); err != nil {
+		return &GoSourceError{Content: content, Field: field, Message: "invalid URL"}
+	}
+	return nil
+}
+
+// parseSourceMeta parses the content attribute of a go-source meta tag.
+func parseSourceMeta(content string) (*sourceMeta, error) {
+	fields := strings.Fields(content)
+	if len(fields) != 4 {
+		return nil, &GoSourceError{Content: content, Message: fmt.Sprintf("have %d fields, want 4", len(fields))}
+	}
+	sm := &sourceMeta{
+		projectRoot:  fields[0],
+		projectURL:   fields[1],
+		dirTemplate:  fields[2],
+		fileTemplate: fields[3],
+	}
+	if err := checkSourceTemplate(content, "home", sm.projectURL); err != nil {
+		return nil, err
+	}
+	if err := checkSourceTemplate(content, "directory", sm.dirTemplate, "{dir}", "{/dir}"); err != nil {
+		return nil, err
+	}
+	if err := checkSourceTemplate(content, "file", sm.fileTemplate, "{dir}", "{/dir}", "{file}", "{line}"); err != nil {
+		return nil, err
+	}
+	if sm.fileTemplate == "_" {
+		return sm, nil
+	}
+
+	fileEnd := strings.LastIndex(sm.fileTemplate, "{file}")
+	if fileEnd < 0 {
+		return nil, &GoSourceError{Content: content, Field: "file", Message: "missing {file} substitution"}
+	}
+	fileEnd += len("{file}")
+	sm.fileHead = sm.fileTemplate
+	switch n := strings.Count(sm.fileTemplate, "{line}"); {
+	case n > 1:
+		return nil, &GoSourceError{Content: content, Field: "file", Message: "more than one {line} substitution"}
+	case n == 1:
+		line := strings.Index(sm.fileTemplate, "{line}")
+		if line < fileEnd {
+			return nil, &GoSourceError{Content: content, Field: "file", Message: "{line} before {file}"}
+		}
+		// The file URL ends at the start of the fragment holding the line
+		// number, or right after the file name if there is no such fragment.
+		cut := fileEnd
+		if hash := strings.Index(sm.fileTemplate[fileEnd:], "#"); hash >= 0 && fileEnd+hash < line {
+			cut = fileEnd + hash
+		}
+		sm.fileHead = sm.fileTemplate[:cut]
+		sm.lineTail = sm.fileTemplate[cut:]
+	}
+	return sm, nil
+}
+
+// expandSourceDir substitutes dir, a slash separated path relative to the
+// project root, into template.
+func expandSourceDir(template, dir string) string {
+	slashDir := ""
+	dir = strings.Trim(dir, "/")
+	if dir != "" {
+		slashDir = "/" + dir
+	}
+	template = strings.Replace(template, "{dir}", dir, -1)
+	return strings.Replace(template, "{/dir}", slashDir, -1)
+}
+
+// dirURL returns the URL of the directory page for dir or "" if the tag does
+// not specify one.
+func (sm *sourceMeta) dirURL(dir string) string {
+	if !isHTTPURL(sm.dirTemplate) {
+		return ""
+	}
+	return expandSourceDir(sm.dirTemplate, dir)
+}
+
+// fileURL returns the URL of the file in dir or "" if the tag does not
+// specify one.
+func (sm *sourceMeta) fileURL(dir, file string) string {
+	if sm.fileHead == "" {
+		return ""
+	}
+	return strings.Replace(expandSourceDir(sm.fileHead, dir), "{file}", file, -1)
+}
+
+// lineFmt returns the format for links to source lines in dir, as used by
+// Directory.LineFmt, or "" if the tag does not specify line links.
+func (sm *sourceMeta) lineFmt(dir string) string {
+	if sm.lineTail == "" {
+		return ""
+	}
+	s := strings.Replace(expandSourceDir(sm.lineTail, dir), "%", "%%", -1)
+	return "%s" + strings.Replace(s, "{line}", "%d", 1)
+}
+
+// apply sets the URLs in dir from the go-source meta tag. The dirName
+// argument is the path of dir relative to the project root.
+func (sm *sourceMeta) apply(dir *Directory, dirName string) {
+	dir.Errors = append(dir.Errors, sm.errors...)
+	if isHTTPURL(sm.projectURL) {
+		dir.ProjectURL = sm.projectURL
+	}
+	if u := sm.dirURL(dirName); u != "" {
+		dir.BrowseURL = u
+	}
+	if sm.fileHead == "" {
+		return
+	}
+	for _, f := range dir.Files {
+		f.BrowseURL = sm.fileURL(dirName, f.Name)
+	}
+	if sm.lineTail != "" {
+		dir.LineFmt = sm.lineFmt(dirName)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var goSourceFixtureTests = []struct {
+	file       string
+	importPath string
+	dir        *Directory
+}{
+	{"golang.org-x-net.html", "golang.org/x/net/html", &Directory{
+		ProjectURL: "https://github.com/golang/net/",
+		BrowseURL:  "https://github.com/golang/net/tree/master/html",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/golang/net/blob/master/html/x.go"}},
+	}},
+	{"gopkg.in-yaml.v2.html", "gopkg.in/yaml.v2", &Directory{
+		ProjectURL: "https://gopkg.in/yaml.v2",
+		BrowseURL:  "https://github.com/go-yaml/yaml/tree/v2.4.0",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/go-yaml/yaml/blob/v2.4.0/x.go"}},
+	}},
+	{"go.uber.org-zap.html", "go.uber.org/zap/zapcore", &Directory{
+		ProjectURL: "https://github.com/uber-go/zap",
+		BrowseURL:  "https://github.com/uber-go/zap/tree/master/zapcore",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/uber-go/zap/tree/master/zapcore/x.go"}},
+	}},
+	{"k8s.io-client-go.html", "k8s.io/client-go/tools/cache", &Directory{
+		ProjectURL: "https://github.com/kubernetes/client-go",
+		BrowseURL:  "https://github.com/kubernetes/client-go/tree/master/tools/cache",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/kubernetes/client-go/blob/master/tools/cache/x.go"}},
+	}},
+	{"google.golang.org-grpc.html", "google.golang.org/grpc/codes", &Directory{
+		ProjectURL: "https://github.com/grpc/grpc-go/",
+		BrowseURL:  "https://github.com/grpc/grpc-go/tree/master/codes",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/grpc/grpc-go/blob/master/codes/x.go"}},
+	}},
+	{"golang.zx2c4.com-wireguard.html", "golang.zx2c4.com/wireguard/tun", &Directory{
+		ProjectURL: "https://golang.zx2c4.com/wireguard",
+		BrowseURL:  "https://git.zx2c4.com/wireguard-go/tree/tun",
+		LineFmt:    "%s#n%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://git.zx2c4.com/wireguard-go/tree/tun/x.go"}},
+	}},
+	{"azul3d.org-engine.html", "azul3d.org/engine", &Directory{
+		ProjectURL: "https://github.com/azul3d/engine",
+		BrowseURL:  "https://gotools.org/azul3d.org/engine",
+		LineFmt:    "%s-L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://gotools.org/azul3d.org/engine#x.go"}},
+	}},
+	{"honnef.co-go-tools.html", "honnef.co/go/tools/simple", &Directory{
+		ProjectURL: "https://github.com/dominikh/go-tools",
+		BrowseURL:  "https://github.com/dominikh/go-tools/tree/master/simple",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/dominikh/go-tools/blob/master/simple/x.go"}},
+	}},
+	{"invalid-templates.html", "example.org/broken", &Directory{
+		ProjectURL: "https://example.org/broken",
+		BrowseURL:  "https://github.com/example/broken",
+		LineFmt:    "%s#L%d",
+		Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/example/broken/blob/master/x.go"}},
+		Errors: []string{
+			`go-source meta tag "example.org/broken https://github.com/example/broken": have 2 fields, want 4`,
+			`go-source meta tag "example.org/broken https://github.com/example/broken https://github.com/example/broken/tree/master/{path} https://github.com/example/broken/blob/master{/dir}/{file}#L{line}": directory field: unknown substitution {path}`,
+			`go-source meta tag "example.org/broken https://github.com/example/broken https://github.com/example/broken/tree/master{/dir} https://github.com/example/broken/blob/master{/dir}#L{line}": file field: missing {file} substitution`,
+			`go-source meta tag "example.org/broken https://github.com/example/broken ftp://example.org/broken{/dir} _": directory field: not an http or https URL`,
+		},
+	}},
+}
+
+func TestGoSourceFixtures(t *testing.T) {
+	for _, tt := range goSourceFixtureTests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "gosource", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			im, sm, _, err := parseMeta("https", tt.importPath, f)
+			if err != nil {
+				t.Fatalf("parseMeta returned error %v", err)
+			}
+			if sm == nil {
+				t.Fatal("parseMeta did not return a go-source meta tag")
+			}
+
+			// The directory starts with the values set by the version control
+			// service for a repository on GitHub.
+			dir := &Directory{
+				ProjectURL: "https://" + im.projectRoot,
+				BrowseURL:  "https://github.com/example/broken",
+				LineFmt:    "%s#L%d",
+				Files:      []*File{{Name: "x.go", BrowseURL: "https://github.com/example/broken/blob/master/x.go"}},
+			}
+			sm.apply(dir, tt.importPath[len(im.projectRoot):])
+			if diff := cmp.Diff(tt.dir, dir); diff != "" {
+				t.Errorf("directory mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+var parseSourceMetaTests = []struct {
+	content string
+	dir     string
+	dirURL  string
+	fileURL string
+	lineFmt string
+	err     *GoSourceError
+}{
+	{
+		content: "example.com/p https://example.com/p https://example.com/p{/dir} https://example.com/p{/dir}/{file}#L{line}",
+		dir:     "a/b",
+		dirURL:  "https://example.com/p/a/b",
+		fileURL: "https://example.com/p/a/b/f.go",
+		lineFmt: "%s#L%d",
+	},
+	{
+		content: "example.com/p _ https://example.com/p/{dir} https://example.com/p/{dir}/{file}",
+		dirURL:  "https://example.com/p/",
+		fileURL: "https://example.com/p//f.go",
+	},
+	{
+		content: "example.com/p _ _ https://example.com/p?f={file}&line={line}",
+		fileURL: "https://example.com/p?f=f.go",
+		lineFmt: "%s&line=%d",
+	},
+	{
+		content: "example.com/p _ _ https://example.com/view{/dir}/{file}?rev=100%25#L{line}",
+		dir:     "d",
+		fileURL: "https://example.com/view/d/f.go?rev=100%25",
+		lineFmt: "%s#L%d",
+	},
+	{
+		content: "example.com/p _ _ https://example.com/src{/dir}/{file}#{file}-{line}",
+		fileURL: "https://example.com/src/f.go#f.go",
+		lineFmt: "%s-%d",
+	},
+	{
+		content: "example.com/p _ _ _",
+	},
+	{
+		content: "example.com/p _ _",
+		err:     &GoSourceError{Message: "have 3 fields, want 4"},
+	},
+	{
+		content: "example.com/p example.com/p _ _",
+		err:     &GoSourceError{Field: "home", Message: "not an http or https URL"},
+	},
+	{
+		content: "example.com/p _ https://example.com/p{/dir}/{file} _",
+		err:     &GoSourceError{Field: "directory", Message: "unknown substitution {file}"},
+	},
+	{
+		content: "example.com/p _ _ https://example.com/p{/dir}",
+		err:     &GoSourceError{Field: "file", Message: "missing {file} substitution"},
+	},
+	{
+		content: "example.com/p _ _ https://example.com/p/{line}/{file}",
+		err:     &GoSourceError{Field: "file", Message: "{line} before {file}"},
+	},
+	{
+		content: "example.com/p _ _ https://example.com/p/{file}#L{line}-L{line}",
+		err:     &GoSourceError{Field: "file", Message: "more than one {line} substitution"},
+	},
+	{
+		content: "example.com/p _ _ https://example.com/p/{file}#L{Line}",
+		err:     &GoSourceError{Field: "file", Message: "unknown substitution {Line}"},
+	},
+}
+
+func TestParseSourceMeta(t *testing.T) {
+	for _, tt := range parseSourceMetaTests {
+		sm, err := parseSourceMeta(tt.content)
+		if tt.err != nil {
+			tt.err.Content = tt.content
+			if !cmp.Equal(err, tt.err) {
+				t.Errorf("parseSourceMeta(%q) returned error %v, want %v", tt.content, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSourceMeta(%q) returned error %v", tt.content, err)
+			continue
+		}
+		if got := sm.dirURL(tt.dir); got != tt.dirURL {
+			t.Errorf("parseSourceMeta(%q).dirURL(%q) = %q, want %q", tt.content, tt.dir, got, tt.dirURL)
+		}
+		if got := sm.fileURL(tt.dir, "f.go"); got != tt.fileURL {
+			t.Errorf("parseSourceMeta(%q).fileURL(%q, \"f.go\") = %q, want %q", tt.content, tt.dir, got, tt.fileURL)
+		}
+		if got := sm.lineFmt(tt.dir); got != tt.lineFmt {
+			t.Errorf("parseSourceMeta(%q).lineFmt(%q) = %q, want %q", tt.content, tt.dir, got, tt.lineFmt)
+		}
+	}
+}
@@ -77,6 +77,10 @@ type Directory struct {
 	// Version control: active or should be suppressed.
 	Status DirectoryStatus
 
+	// The version (tag, semantic version or commit) of the directory, or ""
+	// for the repository's default branch.
+	Version string
+
 	// Cache validation tag. This tag is not necessarily an HTTP entity tag.
 	// The tag is "" if there is no meaningful cache validation for the VCS.
 	Etag string
@@ -100,6 +104,31 @@ type Directory struct {
 
 	// How many stars (for a GitHub project) the repository of this directory has.
 	Stars int
+
+	// Module path declared in the go.mod file of the module containing this
+	// directory, or "" if the directory is not in a module.
+	ModulePath string
+
+	// Import path of this directory within the module declared by
+	// ModulePath. It differs from ImportPath when the directory is fetched
+	// through a path other than the module's.
+	ModuleImportPath string
+
+	// Go version from the go directive of the module's go.mod file.
+	GoVersion string
+
+	// For a standard package, the Go versions after Go 1.0 which added the
+	// exported declarations, keyed by name. The name of a method, struct
+	// field or interface method is Type.Name. Nil if not known.
+	Since map[string]string
+
+	// License files that apply to the directory, or nil if licenses were
+	// not checked.
+	Licenses []*License
+
+	// Problems found with the directory's metadata, such as invalid
+	// go-source meta tags.
+	Errors []string
 }
 
 // Project represents a repository.
@@ -135,6 +164,19 @@ func (e *RemoteError) Error() string {
 	return e.err.Error()
 }
 
+// RateLimitError indicates that the rate limit of a service's API is
+// exceeded. Requests to the service fail until the limit is reset.
+type RateLimitError struct {
+	Host string
+
+	// Time when the rate limit is reset.
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: API rate limit exceeded until %s", e.Host, e.Reset.UTC().Format(time.RFC1123))
+}
+
 type NotModifiedError struct {
 	Since  time.Time
 	Status DirectoryStatus
@@ -153,6 +195,8 @@ func (e NotModifiedError) Error() string {
 
 var errNoMatch = errors.New("no match")
 
+var errVersionsNotSupported = NotFoundError{Message: "Versioned documentation is not available for this repository."}
+
 // service represents a source code control service.
 type service struct {
 	pattern         *regexp.Regexp
@@ -160,6 +204,27 @@ type service struct {
 	get             func(context.Context, *http.Client, map[string]string, string) (*Directory, error)
 	getPresentation func(context.Context, *http.Client, map[string]string) (*Presentation, error)
 	getProject      func(context.Context, *http.Client, map[string]string) (*Project, error)
+
+	// getVersions lists the versions known to the service. Services that set
+	// getVersions also handle the "version" key in the match passed to get.
+	getVersions func(context.Context, *http.Client, map[string]string) ([]string, error)
+
+	// getFile gets a file by its path relative to the repository root. It is
+	// called with the match after get and is used to find the go.mod file of
+	// the module containing a directory.
+	getFile func(context.Context, *http.Client, map[string]string, string) ([]byte, error)
+
+	// getTree lists the paths of the files in the repository, relative to
+	// the repository root, at the revision read by getFile. It is called
+	// with the match after get. It returns errNoListing if the repository
+	// has too many files to list them. Without getTree, the go.mod and
+	// license files of parent directories are looked up by name and nested
+	// modules are not removed from the subdirectories.
+	getTree func(context.Context, *http.Client, map[string]string) ([]string, error)
+
+	// matchFn, if set, replaces matching with pattern and prefix. It is used
+	// for services registered with RegisterService.
+	matchFn func(importPath string) (map[string]string, error)
 }
 
 var services []*service
@@ -173,6 +238,13 @@ func addService(s *service) {
 }
 
 func (s *service) match(importPath string) (map[string]string, error) {
+	if s.matchFn != nil {
+		match, err := s.matchFn(importPath)
+		if match != nil {
+			match["importPath"] = importPath
+		}
+		return match, err
+	}
 	if !strings.HasPrefix(importPath, s.prefix) {
 		return nil, nil
 	}
@@ -199,29 +271,10 @@ type importMeta struct {
 	repo        string
 }
 
-// sourceMeta represents the values in a go-source meta tag.
-type sourceMeta struct {
-	projectRoot  string
-	projectURL   string
-	dirTemplate  string
-	fileTemplate string
-}
-
 func isHTTPURL(s string) bool {
 	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
 }
 
-func replaceDir(s string, dir string) string {
-	slashDir := ""
-	dir = strings.Trim(dir, "/")
-	if dir != "" {
-		slashDir = "/" + dir
-	}
-	s = strings.Replace(s, "{dir}", dir, -1)
-	s = strings.Replace(s, "{/dir}", slashDir, -1)
-	return s
-}
-
 func attrValue(attrs []xml.Attr, name string) string {
 	for _, a := range attrs {
 		if strings.EqualFold(a.Name.Local, name) {
@@ -261,6 +314,7 @@ var refreshToGodocPat = regexp.MustCompile(`(?i)^\d+; url=https?://godoc\.org/`)
 
 func parseMeta(scheme, importPath string, r io.Reader) (im *importMeta, sm *sourceMeta, redir bool, err error) {
 	errorMessage := "go-import meta tag not found"
+	var sources []string
 
 	d := xml.NewDecoder(r)
 	d.Strict = false
@@ -323,29 +377,45 @@ metaScan:
 					repo:        fields[2],
 				}
 			case "go-source":
-				if sm != nil {
-					// Ignore extra go-source meta tags.
-					continue metaScan
-				}
-				if len(fields) != 4 {
-					continue metaScan
-				}
-				sm = &sourceMeta{
-					projectRoot:  projectRoot,
-					projectURL:   fields[1],
-					dirTemplate:  fields[2],
-					fileTemplate: fields[3],
-				}
+				sources = append(sources, attrValue(t.Attr, "content"))
 			}
 		}
 	}
 	if im == nil {
 		return nil, nil, redir, NotFoundError{Message: fmt.Sprintf("%s at %s://%s", errorMessage, scheme, importPath)}
 	}
-	if sm != nil && sm.projectRoot != im.projectRoot {
-		sm = nil
+	return im, chooseSourceMeta(im.projectRoot, sources), redir, nil
+}
+
+// chooseSourceMeta returns the first valid go-source meta tag in sources
+// with the prefix projectRoot. Invalid tags are recorded in the errors field
+// of the result. Valid tags for other prefixes are reported only if no tag
+// matches projectRoot; a site may serve tags for several repositories. The
+// result is nil if no tag is valid and there are no problems to report.
+func chooseSourceMeta(projectRoot string, sources []string) *sourceMeta {
+	var sm *sourceMeta
+	var errs, mismatched []string
+	for _, content := range sources {
+		m, err := parseSourceMeta(content)
+		switch {
+		case err != nil:
+			errs = append(errs, err.Error())
+		case m.projectRoot != projectRoot:
+			err = &GoSourceError{Content: content, Field: "prefix", Message: fmt.Sprintf("does not match go-import prefix %s", projectRoot)}
+			mismatched = append(mismatched, err.Error())
+		case sm == nil:
+			sm = m
+		}
+	}
+	if sm == nil {
+		errs = append(errs, mismatched...)
+		if errs == nil {
+			return nil
+		}
+		sm = &sourceMeta{}
 	}
-	return im, sm, redir, nil
+	sm.errors = errs
+	return sm
 }
 
 // getVCSDirFn is called by getDynamic to fetch source using VCS commands. The
@@ -356,7 +426,7 @@ var getVCSDirFn = func(ctx context.Context, client *http.Client, m map[string]st
 }
 
 // getDynamic gets a directory from a service that is not statically known.
-func getDynamic(ctx context.Context, client *http.Client, importPath, etag string) (*Directory, error) {
+func getDynamic(ctx context.Context, client *http.Client, importPath, version, etag string) (*Directory, error) {
 	metaProto, im, sm, redir, err := fetchMeta(ctx, client, importPath)
 	if err != nil {
 		return nil, err
@@ -390,8 +460,10 @@ func getDynamic(ctx context.Context, client *http.Client, importPath, etag strin
 	dirName := importPath[len(im.projectRoot):]
 
 	resolvedPath := repo + dirName
-	dir, err := getStatic(ctx, client, resolvedPath, etag)
-	if err == errNoMatch {
+	dir, err := getStatic(ctx, client, resolvedPath, version, etag)
+	if err == errNoMatch && version != "" {
+		err = errVersionsNotSupported
+	} else if err == errNoMatch {
 		resolvedPath = repo + "." + im.vcs + dirName
 		match := map[string]string{
 			"dir":        dirName,
@@ -419,44 +491,68 @@ func getDynamic(ctx context.Context, client *http.Client, importPath, etag strin
 		return dir, nil
 	}
 
-	if isHTTPURL(sm.projectURL) {
-		dir.ProjectURL = sm.projectURL
-	}
-
-	if isHTTPURL(sm.dirTemplate) {
-		dir.BrowseURL = replaceDir(sm.dirTemplate, dirName)
-	}
-
-	// TODO: Refactor this to be simpler, implement the go-source meta tag spec fully.
-	if isHTTPURL(sm.fileTemplate) {
-		fileTemplate := replaceDir(sm.fileTemplate, dirName)
-		if strings.Contains(fileTemplate, "{file}") {
-			cut := strings.LastIndex(fileTemplate, "{file}") + len("{file}") // Cut point is right after last {file} section.
-			switch hash := strings.Index(fileTemplate, "#"); {
-			case hash == -1: // If there's no '#', place cut at the end.
-				cut = len(fileTemplate)
-			case hash > cut: // If a '#' comes after last {file}, use it as cut point.
-				cut = hash
-			}
-			head, tail := fileTemplate[:cut], fileTemplate[cut:]
-			for _, f := range dir.Files {
-				f.BrowseURL = strings.Replace(head, "{file}", f.Name, -1)
-			}
+	sm.apply(dir, dirName)
+	return dir, nil
+}
 
-			if strings.Contains(tail, "{line}") {
-				s := strings.Replace(tail, "%", "%%", -1)
-				s = strings.Replace(s, "{line}", "%d", 1)
-				dir.LineFmt = "%s" + s
-			}
+// getStatic gets a directory from a statically known service. getStatic
+// returns errNoMatch if the import path is not recognized.
+func getStatic(ctx context.Context, client *http.Client, importPath, version, etag string) (*Directory, error) {
+	dir, err := getStaticDir(ctx, client, importPath, version, etag)
+	if _, ok := err.(NotFoundError); ok {
+		d, majorErr := getMajorBranchDir(ctx, client, importPath, version, etag)
+		if _, ok := majorErr.(NotFoundError); !ok && majorErr != errNoMatch {
+			return d, majorErr
 		}
 	}
+	return dir, err
+}
 
+// getMajorBranchDir gets a directory of a module using the major branch
+// layout, where the go.mod file of major version 2 or higher is in the same
+// directory as that of version 1 and declares the module path with a /vN
+// suffix. The directory is fetched at the import path without the last /vN
+// element, which does not exist in the repository. getMajorBranchDir returns
+// errNoMatch if the import path has no such element or if the go.mod file
+// does not declare the module path.
+func getMajorBranchDir(ctx context.Context, client *http.Client, importPath, version, etag string) (*Directory, error) {
+	loc := majorVersionElemPat.FindAllStringIndex(importPath, -1)
+	if loc == nil {
+		return nil, errNoMatch
+	}
+	i, j := loc[len(loc)-1][0], loc[len(loc)-1][1]
+	if importPath[j-1] == '/' {
+		j--
+	}
+	resolvedPath := importPath[:i] + importPath[j:]
+	dir, err := getStaticDir(ctx, client, resolvedPath, version, etag)
+	if err != nil {
+		return nil, err
+	}
+	if dir.ModuleImportPath != importPath {
+		return nil, errNoMatch
+	}
+	dir.ImportPath = importPath
 	return dir, nil
 }
 
-// getStatic gets a directory from a statically known service. getStatic
-// returns errNoMatch if the import path is not recognized.
-func getStatic(ctx context.Context, client *http.Client, importPath, etag string) (*Directory, error) {
+// hasModuleAndLicense returns true if the files of dir include a go.mod file
+// and a license file.
+func hasModuleAndLicense(dir *Directory) bool {
+	mod, lic := false, false
+	for _, f := range dir.Files {
+		mod = mod || f.Name == "go.mod"
+		lic = lic || IsLicenseFile(f.Name)
+	}
+	return mod && lic
+}
+
+// majorVersionElemPat matches the /vN elements of an import path with N >= 2.
+var majorVersionElemPat = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)(?:$|/)`)
+
+// getStaticDir gets a directory from a statically known service without
+// mapping major branch import paths.
+func getStaticDir(ctx context.Context, client *http.Client, importPath, version, etag string) (*Directory, error) {
 	for _, s := range services {
 		if s.get == nil {
 			continue
@@ -466,10 +562,52 @@ func getStatic(ctx context.Context, client *http.Client, importPath, etag string
 			return nil, err
 		}
 		if match != nil {
+			if version != "" {
+				if s.getVersions == nil {
+					return nil, errVersionsNotSupported
+				}
+				match["version"] = version
+			}
 			dir, err := s.get(ctx, client, match, etag)
-			if dir != nil {
-				dir.ImportPath = importPath
-				dir.ResolvedPath = importPath
+			if dir == nil {
+				return nil, err
+			}
+			dir.ImportPath = importPath
+			dir.ResolvedPath = importPath
+			var fs repoFS
+			if s.getFile != nil {
+				getFile := func(name string) ([]byte, error) {
+					return s.getFile(ctx, client, match, name)
+				}
+				// The files of the repository are cached by the commit
+				// of the directory, if known.
+				key := dir.ProjectRoot + "@" + version
+				if dir.Etag != "" {
+					key = dir.ProjectRoot + "@" + dir.Etag
+				}
+				sfs := &serviceFS{key: key, getFile: getFile}
+				// The files of a directory with its own go.mod and
+				// license files are not listed. Nested modules are then
+				// kept in the subdirectories.
+				if s.getTree != nil && !hasModuleAndLicense(dir) {
+					sfs.getTree = func() ([]string, error) {
+						return s.getTree(ctx, client, match)
+					}
+				}
+				fs = sfs
+			}
+			if dir.ModulePath == "" {
+				if err := setModule(dir, match["dir"], fs); err != nil {
+					return nil, err
+				}
+			}
+			if err := removeNestedModules(dir, match["dir"], fs); err != nil {
+				return nil, err
+			}
+			if dir.Licenses == nil {
+				if err := setLicenses(dir, match["dir"], fs); err != nil {
+					return nil, err
+				}
 			}
 			return dir, err
 		}
@@ -478,15 +616,31 @@ func getStatic(ctx context.Context, client *http.Client, importPath, etag string
 }
 
 func Get(ctx context.Context, client *http.Client, importPath string, etag string) (dir *Directory, err error) {
+	return GetVersion(ctx, client, importPath, "", etag)
+}
+
+// GetVersion gets the directory at the given version, a tag, semantic version
+// or commit. The empty version selects the repository's default branch.
+// Versions are supported for packages fetched from a module proxy and from
+// services that can list versions; other packages return NotFoundError.
+func GetVersion(ctx context.Context, client *http.Client, importPath, version, etag string) (dir *Directory, err error) {
 	switch {
-	case localPath != "":
+	case version != "" && (isLocalDevMode() || IsGoRepoPath(importPath)):
+		err = errVersionsNotSupported
+	case isLocalDevMode():
 		dir, err = getLocal(importPath)
 	case IsGoRepoPath(importPath):
 		dir, err = getStandardDir(ctx, client, importPath, etag)
 	case IsValidRemotePath(importPath):
-		dir, err = getStatic(ctx, client, importPath, etag)
+		err = errNoMatch
+		if len(moduleProxies) > 0 {
+			dir, err = getProxyDir(ctx, client, importPath, version, etag)
+		}
+		if err == errNoMatch {
+			dir, err = getStatic(ctx, client, importPath, version, etag)
+		}
 		if err == errNoMatch {
-			dir, err = getDynamic(ctx, client, importPath, etag)
+			dir, err = getDynamic(ctx, client, importPath, version, etag)
 		}
 	default:
 		err = errNoMatch
@@ -495,10 +649,51 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 	if err == errNoMatch {
 		err = NotFoundError{Message: "Import path not valid:"}
 	}
+	if dir != nil {
+		dir.Version = version
+		applyModule(dir)
+	}
 
 	return dir, err
 }
 
+// maxVersionPages is the number of pages of a paginated list of tags read
+// when listing the versions of a repository. Tags on later pages are not
+// listed.
+const maxVersionPages = 10
+
+// GetVersions returns the versions known for the repository or module
+// containing importPath, newest first.
+func GetVersions(ctx context.Context, client *http.Client, importPath string) ([]string, error) {
+	if isLocalDevMode() || IsGoRepoPath(importPath) || !IsValidRemotePath(importPath) {
+		return nil, errVersionsNotSupported
+	}
+	if len(moduleProxies) > 0 {
+		versions, err := getProxyVersions(ctx, client, importPath)
+		if err != errNoMatch {
+			return versions, err
+		}
+	}
+	for _, s := range services {
+		if s.getVersions == nil {
+			continue
+		}
+		match, err := s.match(importPath)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			versions, err := s.getVersions(ctx, client, match)
+			if err != nil {
+				return nil, err
+			}
+			sortVersions(versions)
+			return versions, nil
+		}
+	}
+	return nil, errVersionsNotSupported
+}
+
 // GetPresentation gets a presentation from the the given path.
 func GetPresentation(ctx context.Context, client *http.Client, importPath string) (*Presentation, error) {
 	ext := path.Ext(importPath)
@@ -157,6 +157,7 @@ var getDynamicTests = []struct {
 		ResolvedPath: "github.com/alice/pkg/ignore",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "http://alice.org/pkg/ignore?f=main.go"}},
+		Errors:       []string{`go-source meta tag "alice.org/pkg blah": have 2 fields, want 4`},
 	}},
 	{"alice.org/pkg/mismatch", nil},
 	{"alice.org/pkg/multiple", nil},
@@ -223,6 +224,7 @@ var getDynamicTests = []struct {
 		ResolvedPath: "github.com/myitcv/x",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/myitcv/x/blob/master/main.go"}},
+		Errors:       []string{`go-source meta tag "myitcv.io https://github.com/myitcv/x/wiki https://github.com/myitcv/x/tree/master{/dir} https://github.com/myitcv/x/blob/master{/dir}/{file}#L{line}": prefix field: does not match go-import prefix myitcv.io/blah2`},
 	}},
 	{"my.host/pkg", nil},
 }
@@ -239,6 +241,7 @@ func (t testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	resp := &http.Response{
 		StatusCode: statusCode,
 		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
 	}
 	return resp, nil
 }
@@ -293,7 +296,7 @@ func TestGetDynamic(t *testing.T) {
 	client := &http.Client{Transport: testTransport(testWeb)}
 
 	for _, tt := range getDynamicTests {
-		dir, err := getDynamic(context.Background(), client, tt.importPath, "")
+		dir, err := getDynamic(context.Background(), client, tt.importPath, "", "")
 
 		if tt.dir == nil {
 			if err == nil {
@@ -0,0 +1,133 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/gddo/internal/license"
+)
+
+// License is a license file that applies to a directory.
+type License struct {
+	// Path of the file relative to the repository root, or to the module
+	// root for modules fetched from a proxy.
+	Path string
+
+	// SPDX identifiers of the licenses found in the file, or nil if the file
+	// was not recognized.
+	Types []string
+}
+
+var licenseFilePat = regexp.MustCompile(`(?i)^(?:licen[cs]e|copying)(?:$|[.\-_])`)
+
+// licenseFileNames are the names of license files looked for in the parent
+// directories of a directory if the directories cannot be listed.
+var licenseFileNames = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"}
+
+// IsLicenseFile returns true if a file with name n is a license file.
+func IsLicenseFile(n string) bool {
+	return licenseFilePat.MatchString(n)
+}
+
+// setLicenses records the licenses that apply to dir. License files are
+// looked for in the directory's files and then in each parent directory up to
+// the repository root in fs, stopping at the first directory that contains
+// any. The dirPath and fs arguments are as for setModule. dir.Licenses is left
+// nil if licenses could not be checked.
+func setLicenses(dir *Directory, dirPath string, fs repoFS) error {
+	var licenses []*License
+	for _, f := range dir.Files {
+		if IsLicenseFile(f.Name) {
+			licenses = append(licenses, &License{
+				Path:  strings.TrimPrefix(path.Join(dirPath, f.Name), "/"),
+				Types: license.Detect(f.Data),
+			})
+		}
+	}
+	if len(licenses) > 0 || fs == nil {
+		// Without fs, the absence of license files in the directory does
+		// not mean the directory is unlicensed.
+		dir.Licenses = licenses
+		return nil
+	}
+	dir.Licenses = []*License{}
+	for d := dirPath; d != "" && d != "/"; {
+		d = path.Dir(d)
+		rel := strings.TrimPrefix(d, "/")
+		names, err := fs.readDir(rel)
+		switch {
+		case err == errNoListing:
+			names = licenseFileNames
+		case IsNotFound(err):
+			continue
+		case err != nil:
+			return err
+		}
+		for _, n := range names {
+			if !IsLicenseFile(n) {
+				continue
+			}
+			name := path.Join(rel, n)
+			data, err := fs.readFile(name)
+			if _, ok := err.(NotFoundError); ok {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			dir.Licenses = append(dir.Licenses, &License{Path: name, Types: license.Detect(data)})
+		}
+		if len(dir.Licenses) > 0 {
+			sort.Slice(dir.Licenses, func(i, j int) bool { return dir.Licenses[i].Path < dir.Licenses[j].Path })
+			return nil
+		}
+	}
+	return nil
+}
+
+// IsRedistributable returns true if the licenses permit redistributing the
+// documentation and source of a package: there is at least one license and
+// every license is recognized.
+func IsRedistributable(licenses []*License) bool {
+	if len(licenses) == 0 {
+		return false
+	}
+	for _, l := range licenses {
+		if len(l.Types) == 0 {
+			return false
+		}
+		for _, t := range l.Types {
+			if !license.Known(t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// LicenseTypes returns the sorted SPDX identifiers of licenses.
+func LicenseTypes(licenses []*License) []string {
+	set := make(map[string]bool)
+	for _, l := range licenses {
+		for _, t := range l.Types {
+			set[t] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	types := make([]string, 0, len(set))
+	for t := range set {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testMITLicense = `Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+var setLicensesTests = []struct {
+	name    string
+	dirPath string
+	files   map[string]string
+	want    []*License
+}{
+	{
+		name:    "in directory",
+		dirPath: "/a",
+		files:   map[string]string{"a/LICENSE": testMITLicense, "LICENSE": "ignored"},
+		want:    []*License{{Path: "a/LICENSE", Types: []string{"MIT"}}},
+	},
+	{
+		name:    "root",
+		dirPath: "/a/b",
+		files:   map[string]string{"COPYING": testMITLicense, "LICENSE.md": "Proprietary."},
+		want:    []*License{{Path: "COPYING", Types: []string{"MIT"}}, {Path: "LICENSE.md"}},
+	},
+	{
+		name:    "nearest parent",
+		dirPath: "/a/b",
+		files:   map[string]string{"LICENSE": "Proprietary.", "a/LICENSE.txt": "SPDX-License-Identifier: Apache-2.0\n"},
+		want:    []*License{{Path: "a/LICENSE.txt", Types: []string{"Apache-2.0"}}},
+	},
+	{
+		name:    "none",
+		dirPath: "/a",
+		files:   map[string]string{},
+		want:    []*License{},
+	},
+}
+
+// countingFS counts the calls to the methods of a repoFS.
+type countingFS struct {
+	repoFS
+	reads, lists int
+}
+
+func (fs *countingFS) readFile(name string) ([]byte, error) {
+	fs.reads++
+	return fs.repoFS.readFile(name)
+}
+
+func (fs *countingFS) readDir(name string) ([]string, error) {
+	fs.lists++
+	return fs.repoFS.readDir(name)
+}
+
+func TestSetLicenses(t *testing.T) {
+	for _, tt := range setLicensesTests {
+		for _, fs := range []repoFS{mapFS(tt.files), noListFS{mapFS(tt.files)}} {
+			var dir Directory
+			if data, ok := tt.files[tt.dirPath[1:]+"/LICENSE"]; ok {
+				dir.Files = []*File{{Name: "LICENSE", Data: []byte(data)}}
+			}
+			if err := setLicenses(&dir, tt.dirPath, fs); err != nil {
+				t.Errorf("%s: setLicenses(%T) returned error %v", tt.name, fs, err)
+				continue
+			}
+			if diff := cmp.Diff(tt.want, dir.Licenses); diff != "" {
+				t.Errorf("%s: setLicenses(%T) licenses mismatch (-want +got):\n%s", tt.name, fs, diff)
+			}
+		}
+	}
+
+	// Without fs, only the directory's files are checked.
+	dir := Directory{Files: []*File{{Name: "x.go"}}}
+	if err := setLicenses(&dir, "/a", nil); err != nil || dir.Licenses != nil {
+		t.Errorf("setLicenses with nil fs set %v, %v; want nil licenses", dir.Licenses, err)
+	}
+}
+
+func TestSetLicensesListing(t *testing.T) {
+	// Listing the parent directories finds license files with any name and
+	// reads only the files which exist.
+	fs := &countingFS{repoFS: mapFS{
+		"LICENSE-MIT":    testMITLicense,
+		"LICENCE":        "Proprietary.",
+		"README.md":      "",
+		"a/b/c/x.go":     "package c",
+		"a/b/notice.txt": "",
+	}}
+	dir := Directory{Files: []*File{{Name: "x.go"}}}
+	if err := setLicenses(&dir, "/a/b/c", fs); err != nil {
+		t.Fatalf("setLicenses returned error %v", err)
+	}
+	want := []*License{{Path: "LICENCE"}, {Path: "LICENSE-MIT", Types: []string{"MIT"}}}
+	if diff := cmp.Diff(want, dir.Licenses); diff != "" {
+		t.Errorf("licenses mismatch (-want +got):\n%s", diff)
+	}
+	if fs.lists != 3 || fs.reads != 2 {
+		t.Errorf("setLicenses listed %d directories and read %d files, want 3 and 2", fs.lists, fs.reads)
+	}
+}
+
+func TestIsRedistributable(t *testing.T) {
+	for _, tt := range []struct {
+		licenses []*License
+		want     bool
+	}{
+		{nil, false},
+		{[]*License{{Types: []string{"MIT"}}}, true},
+		{[]*License{{Types: []string{"MIT"}}, {Types: []string{"BSD-3-Clause", "Apache-2.0"}}}, true},
+		{[]*License{{Types: []string{"MIT"}}, {}}, false},
+		{[]*License{{Types: []string{"Commercial"}}}, false},
+	} {
+		if got := IsRedistributable(tt.licenses); got != tt.want {
+			t.Errorf("IsRedistributable(%v) = %v, want %v", LicenseTypes(tt.licenses), got, tt.want)
+		}
+	}
+}
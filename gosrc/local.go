@@ -7,15 +7,30 @@
 package gosrc
 
 import (
+	"fmt"
 	"go/build"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var localPath string
 
+// localModule is a module in a directory on the local file system.
+type localModule struct {
+	path string // module path
+	dir  string // module root directory
+}
+
+// localModules are the modules used in local development mode, longest
+// module path first.
+var localModules []*localModule
+
 // SetLocalDevMode sets the package to local development mode. In this mode,
 // the GOPATH specified by path is used to find directories instead of version
 // control services.
@@ -23,41 +38,205 @@ func SetLocalDevMode(path string) {
 	localPath = path
 }
 
+// SetLocalModules sets the package to module-aware local development mode.
+// Each root is the root directory of a module, a go.work file or a directory
+// containing one. Import paths are resolved through the module paths declared
+// in the go.mod files and through replace directives with local directories.
+// If local development mode is also set with SetLocalDevMode, import paths
+// outside of the modules are looked up in the GOPATH.
+func SetLocalModules(roots ...string) error {
+	var modules []*localModule
+	seen := make(map[string]bool)
+	var add func(dir, modulePath string) error
+	add = func(dir, modulePath string) error {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return err
+		}
+		mod := parseGoMod(data)
+		if modulePath == "" {
+			modulePath = mod.Module
+		}
+		if modulePath == "" {
+			return fmt.Errorf("gosrc: no module directive in %s", filepath.Join(dir, "go.mod"))
+		}
+		if seen[modulePath] {
+			return nil
+		}
+		seen[modulePath] = true
+		modules = append(modules, &localModule{path: modulePath, dir: dir})
+		return addReplacements(dir, mod, add)
+	}
+
+	for _, root := range roots {
+		work := root
+		if fi, err := os.Stat(root); err == nil && fi.IsDir() {
+			work = filepath.Join(root, "go.work")
+			if _, err := os.Stat(work); err != nil {
+				if err := add(root, ""); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		data, err := ioutil.ReadFile(work)
+		if err != nil {
+			return err
+		}
+		ws := parseGoMod(data)
+		dir := filepath.Dir(work)
+		// Replacements in the go.work file take precedence over those in
+		// the go.mod files of the workspace modules.
+		if err := addReplacements(dir, ws, add); err != nil {
+			return err
+		}
+		for _, use := range ws.Use {
+			if err := add(localDir(dir, use), ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.SliceStable(modules, func(i, j int) bool { return len(modules[i].path) > len(modules[j].path) })
+	localModules = modules
+	return nil
+}
+
+// addReplacements calls add for the replace directives of mod with a local
+// directory as the replacement. Relative directories are relative to dir.
+func addReplacements(dir string, mod *goMod, add func(dir, modulePath string) error) error {
+	paths := make([]string, 0, len(mod.Replace))
+	for p := range mod.Replace {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := add(localDir(dir, mod.Replace[p]), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localDir returns the directory of the slash-separated path p relative to
+// dir.
+func localDir(dir, p string) string {
+	p = filepath.FromSlash(p)
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+func isLocalDevMode() bool {
+	return localPath != "" || len(localModules) > 0
+}
+
 func getLocal(importPath string) (*Directory, error) {
-	ctx := build.Default
-	if localPath != "" {
-		ctx.GOPATH = localPath
+	for _, m := range localModules {
+		if importPath == m.path || strings.HasPrefix(importPath, m.path+"/") {
+			return getLocalModuleDir(m, importPath)
+		}
+	}
+	if localPath == "" {
+		return nil, NotFoundError{Message: "Import path not in a local module."}
 	}
+
+	ctx := build.Default
+	ctx.GOPATH = localPath
 	bpkg, err := ctx.Import(importPath, ".", build.FindOnly)
 	if err != nil {
 		return nil, err
 	}
-	dir := filepath.Join(bpkg.SrcRoot, filepath.FromSlash(importPath))
-	fis, err := ioutil.ReadDir(dir)
+	files, subdirs, modTime, err := readLocalDir(filepath.Join(bpkg.SrcRoot, filepath.FromSlash(importPath)))
+	if err != nil {
+		return nil, err
+	}
+	d := &Directory{
+		ImportPath:     importPath,
+		Etag:           strconv.FormatInt(modTime.Unix(), 16),
+		Files:          files,
+		Subdirectories: subdirs,
+	}
+	fs := diskFS(bpkg.SrcRoot)
+	if err := setModule(d, "/"+importPath, fs); err != nil {
+		return nil, err
+	}
+	if err := removeNestedModules(d, "/"+importPath, fs); err != nil {
+		return nil, err
+	}
+	if err := setLicenses(d, "/"+importPath, fs); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func getLocalModuleDir(m *localModule, importPath string) (*Directory, error) {
+	rel := strings.TrimPrefix(importPath, m.path)
+	files, subdirs, modTime, err := readLocalDir(filepath.Join(m.dir, filepath.FromSlash(rel)))
 	if err != nil {
 		return nil, err
 	}
+	d := &Directory{
+		ImportPath:       importPath,
+		ResolvedPath:     importPath,
+		ProjectRoot:      m.path,
+		ProjectName:      path.Base(majorVersionSuffixPat.ReplaceAllString(m.path, "")),
+		Etag:             strconv.FormatInt(modTime.Unix(), 16),
+		Files:            files,
+		Subdirectories:   subdirs,
+		ModulePath:       m.path,
+		ModuleImportPath: importPath,
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(m.dir, "go.mod")); err == nil {
+		d.GoVersion = parseGoMod(data).Go
+	}
+	if err := removeNestedModules(d, rel, diskFS(m.dir)); err != nil {
+		return nil, err
+	}
+	if err := setLicenses(d, rel, diskFS(m.dir)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// readLocalDir reads the documentation files and subdirectories of the
+// directory dir. It also returns the latest modification time of the files,
+// which is used as the cache validation tag of the directory.
+func readLocalDir(dir string) ([]*File, []string, time.Time, error) {
 	var modTime time.Time
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, modTime, NotFoundError{Message: err.Error()}
+	}
+	if err != nil {
+		return nil, nil, modTime, err
+	}
 	var files []*File
+	var subdirs []string
 	for _, fi := range fis {
-		if fi.IsDir() || !isDocFile(fi.Name()) {
-			continue
-		}
-		if fi.ModTime().After(modTime) {
-			modTime = fi.ModTime()
+		switch {
+		case fi.IsDir():
+			if isValidPathElement(fi.Name()) && fi.Name() != "testdata" && fi.Name() != "vendor" {
+				subdirs = append(subdirs, fi.Name())
+			}
+		case isDocFile(fi.Name()):
+			if fi.ModTime().After(modTime) {
+				modTime = fi.ModTime()
+			}
+			b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+			if err != nil {
+				return nil, nil, modTime, err
+			}
+			files = append(files, &File{
+				Name: fi.Name(),
+				Data: b,
+			})
 		}
-		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, &File{
-			Name: fi.Name(),
-			Data: b,
-		})
-	}
-	return &Directory{
-		ImportPath: importPath,
-		Etag:       strconv.FormatInt(modTime.Unix(), 16),
-		Files:      files,
-	}, nil
+	}
+	return files, subdirs, modTime, nil
 }
@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeLocalFiles(t *testing.T, root string, files map[string]string) {
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetLocalModules(t *testing.T) {
+	root, err := ioutil.TempDir("", "gosrc-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeLocalFiles(t, root, map[string]string{
+		"go.work":             "go 1.18\n\nuse ./app\n",
+		"app/go.mod":          "module example.com/app\n\ngo 1.16\n\nreplace example.com/lib => ../lib\n",
+		"app/LICENSE":         testMITLicense,
+		"app/main.go":         "package main",
+		"app/cmd/tool/x.go":   "package main",
+		"app/testdata/x.go":   "package x",
+		"lib/go.mod":          "module example.com/lib\n",
+		"lib/lib.go":          "package lib",
+		"lib/v2/go.mod":       "module example.com/lib/v2\n",
+		"lib/internal/util.x": "not a doc file",
+	})
+	defer func(p string, m []*localModule) { localPath, localModules = p, m }(localPath, localModules)
+	localPath = ""
+	if err := SetLocalModules(root); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := getLocal("example.com/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.ProjectRoot != "example.com/app" || dir.ModulePath != "example.com/app" || dir.GoVersion != "1.16" {
+		t.Errorf("getLocal(example.com/app) = %+v, want module example.com/app with go 1.16", dir)
+	}
+	if want := []string{"cmd"}; !reflect.DeepEqual(dir.Subdirectories, want) {
+		t.Errorf("Subdirectories = %v, want %v", dir.Subdirectories, want)
+	}
+	if len(dir.Licenses) != 1 || dir.Licenses[0].Path != "LICENSE" {
+		t.Errorf("Licenses = %v, want LICENSE", dir.Licenses)
+	}
+	if dir.Etag == "" {
+		t.Error("Etag is empty")
+	}
+
+	dir, err = getLocal("example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dir.Files) != 2 || dir.ModulePath != "example.com/lib" {
+		t.Errorf("getLocal(example.com/lib) = %+v, want replaced module with 2 files", dir)
+	}
+	if want := []string{"internal"}; !reflect.DeepEqual(dir.Subdirectories, want) {
+		t.Errorf("Subdirectories = %v, want %v", dir.Subdirectories, want)
+	}
+
+	if _, err := getLocal("example.com/app/cmd/missing"); !IsNotFound(err) {
+		t.Errorf("getLocal(missing) returned error %v, want NotFoundError", err)
+	}
+	if _, err := getLocal("example.com/other"); !IsNotFound(err) {
+		t.Errorf("getLocal(example.com/other) returned error %v, want NotFoundError", err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 var (
 	etag    = flag.String("etag", "", "Etag")
 	local   = flag.String("local", "", "Get package from local workspace.")
+	modules = flag.String("modules", "", "Get package from comma-separated local module roots or go.work files.")
 	present = flag.Bool("present", false, "Get presentation.")
 )
 
@@ -46,6 +47,11 @@ func printDir(path string) {
 	if *local != "" {
 		gosrc.SetLocalDevMode(*local)
 	}
+	if *modules != "" {
+		if err := gosrc.SetLocalModules(strings.Split(*modules, ",")...); err != nil {
+			log.Fatal(err)
+		}
+	}
 	c := &http.Client{
 		Transport: &httputil.AuthTransport{
 			Base:               http.DefaultTransport,
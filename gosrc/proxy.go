@@ -0,0 +1,397 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxModuleZipSize is the largest module zip file accepted from a proxy. The
+// go command enforces the same limit. It is a variable for testing.
+var maxModuleZipSize int64 = 500 << 20
+
+var moduleProxies []*url.URL
+
+// SetModuleProxy configures the Go module proxies used to fetch packages. The
+// value has the syntax of the GOPROXY environment variable: a comma separated
+// list of proxy URLs that are tried in order. The "direct" and "off" keywords
+// are ignored. Proxies may use the http, https or file schemes. An empty value
+// disables fetching from module proxies.
+func SetModuleProxy(proxy string) error {
+	var proxies []*url.URL
+	for _, s := range strings.FieldsFunc(proxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		s = strings.TrimSpace(s)
+		if s == "" || s == "direct" || s == "off" {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		switch u.Scheme {
+		case "http", "https", "file":
+		default:
+			return fmt.Errorf("gosrc: unsupported module proxy URL %q", s)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		proxies = append(proxies, u)
+	}
+	moduleProxies = proxies
+	return nil
+}
+
+// escapeModulePath escapes a module path or version for use in a module
+// proxy URL. Each upper case letter is replaced with an exclamation mark
+// followed by the letter's lower case equivalent.
+func escapeModulePath(p string) string {
+	var buf strings.Builder
+	for _, r := range p {
+		if 'A' <= r && r <= 'Z' {
+			buf.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func proxyError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusGone {
+		return NotFoundError{Message: "Module not found: " + resp.Request.URL.String()}
+	}
+	return &RemoteError{resp.Request.URL.Host, fmt.Errorf("%d: (%s)", resp.StatusCode, resp.Request.URL.String())}
+}
+
+// proxyGet fetches the file at the escaped path p from the module proxy base.
+// Module zip files larger than maxModuleZipSize are rejected without reading
+// more than the limit.
+func proxyGet(ctx context.Context, client *http.Client, base *url.URL, p string) ([]byte, error) {
+	var r io.ReadCloser
+	if base.Scheme == "file" {
+		f, err := os.Open(filepath.Join(filepath.FromSlash(base.Path), filepath.FromSlash(p)))
+		if os.IsNotExist(err) {
+			return nil, NotFoundError{Message: "Module not found: " + base.String() + "/" + p}
+		}
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	} else {
+		c := &httpClient{client: client, errFn: proxyError}
+		var err error
+		r, err = c.getReader(ctx, base.String()+"/"+p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer r.Close()
+	if !strings.HasSuffix(p, ".zip") {
+		return ioutil.ReadAll(r)
+	}
+	// Stop reading module zip files once they exceed the size limit.
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxModuleZipSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxModuleZipSize {
+		return nil, fmt.Errorf("gosrc: module zip %s/%s larger than %d bytes", base, p, maxModuleZipSize)
+	}
+	return data, nil
+}
+
+// moduleVersion is the version information returned by the .info and @latest
+// proxy endpoints.
+type moduleVersion struct {
+	Version string
+	Time    time.Time
+}
+
+// proxyVersionList returns the semantic versions in the version list of
+// modulePath.
+func proxyVersionList(ctx context.Context, client *http.Client, base *url.URL, modulePath string) ([]string, error) {
+	p, err := proxyGet(ctx, client, base, escapeModulePath(modulePath)+"/@v/list")
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, v := range strings.Fields(string(p)) {
+		if isSemver(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// moduleVersionInfo returns the version of modulePath to document. If
+// version is empty, the highest release version in the version list is
+// preferred, followed by the highest prerelease version. If the list is empty,
+// the proxy is asked for the latest pseudo-version.
+func moduleVersionInfo(ctx context.Context, client *http.Client, base *url.URL, modulePath, version string) (*moduleVersion, error) {
+	escaped := escapeModulePath(modulePath)
+	if version == "" {
+		versions, err := proxyVersionList(ctx, client, base, modulePath)
+		if err != nil {
+			return nil, err
+		}
+		var release, prerelease string
+		for _, v := range versions {
+			if isPrerelease(v) {
+				if compareSemver(v, prerelease) > 0 {
+					prerelease = v
+				}
+			} else if compareSemver(v, release) > 0 {
+				release = v
+			}
+		}
+		version = release
+		if version == "" {
+			version = prerelease
+		}
+	}
+
+	infoPath := escaped + "/@latest"
+	if version != "" {
+		infoPath = escaped + "/@v/" + escapeModulePath(version) + ".info"
+	}
+	p, err := proxyGet(ctx, client, base, infoPath)
+	if err != nil {
+		return nil, err
+	}
+	var info moduleVersion
+	if err := json.Unmarshal(p, &info); err != nil {
+		return nil, err
+	}
+	if !isSemver(info.Version) {
+		return nil, fmt.Errorf("gosrc: invalid version %q for module %s", info.Version, modulePath)
+	}
+	return &info, nil
+}
+
+var pseudoVersionRevPat = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-(?:.*\.)?[0-9]{14}-([0-9a-f]{12})(?:\+incompatible)?$`)
+
+// proxyVersionRef returns the VCS revision for a module version: the commit
+// hash for pseudo-versions and the tag otherwise.
+func proxyVersionRef(version string) string {
+	if m := pseudoVersionRevPat.FindStringSubmatch(version); m != nil {
+		return m[1]
+	}
+	return strings.TrimSuffix(version, "+incompatible")
+}
+
+var proxyGitHubModulePat = regexp.MustCompile(`^github\.com/[a-zA-Z0-9_.\-]+/[a-zA-Z0-9_.\-]+$`)
+
+// getProxyDir gets the directory for importPath at version from the
+// configured module proxies. The module containing importPath is found by
+// querying the proxy for each prefix of the import path, longest first.
+// getProxyDir returns errNoMatch if no proxy has a module containing the
+// import path.
+func getProxyDir(ctx context.Context, client *http.Client, importPath, version, savedEtag string) (*Directory, error) {
+	for _, base := range moduleProxies {
+		for modulePath := importPath; strings.Contains(modulePath, "/"); modulePath = path.Dir(modulePath) {
+			dir, err := getProxyModuleDir(ctx, client, base, modulePath, importPath, version, savedEtag)
+			if _, ok := err.(NotFoundError); ok {
+				continue
+			}
+			return dir, err
+		}
+	}
+	return nil, errNoMatch
+}
+
+// getProxyVersions returns the versions of the module containing importPath
+// from the configured module proxies, newest first. getProxyVersions returns
+// errNoMatch if no proxy has a module containing the import path.
+func getProxyVersions(ctx context.Context, client *http.Client, importPath string) ([]string, error) {
+	for _, base := range moduleProxies {
+		for modulePath := importPath; strings.Contains(modulePath, "/"); modulePath = path.Dir(modulePath) {
+			versions, err := proxyVersionList(ctx, client, base, modulePath)
+			if _, ok := err.(NotFoundError); ok {
+				continue
+			}
+			sortVersions(versions)
+			return versions, err
+		}
+	}
+	return nil, errNoMatch
+}
+
+func getProxyModuleDir(ctx context.Context, client *http.Client, base *url.URL, modulePath, importPath, version, savedEtag string) (*Directory, error) {
+	info, err := moduleVersionInfo(ctx, client, base, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	prefix := escapeModulePath(modulePath) + "/@v/" + escapeModulePath(info.Version)
+
+	mod, err := proxyGet(ctx, client, base, prefix+".mod")
+	if err != nil {
+		return nil, err
+	}
+	if p := goModModulePath(mod); p != "" && p != modulePath {
+		return nil, NotFoundError{Message: fmt.Sprintf("go.mod declares module %s, not %s", p, modulePath)}
+	}
+
+	status := Active
+	if info.Time.Add(ExpiresAfter).Before(time.Now()) {
+		status = NoRecentCommits
+	}
+	if info.Version == savedEtag {
+		return nil, NotModifiedError{
+			Since:  info.Time,
+			Status: status,
+		}
+	}
+
+	p, err := proxyGet(ctx, client, base, prefix+".zip")
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(p), int64(len(p)))
+	if err != nil {
+		return nil, err
+	}
+
+	dirPrefix := modulePath + "@" + info.Version + "/"
+	if importPath != modulePath {
+		dirPrefix += strings.TrimPrefix(importPath, modulePath+"/") + "/"
+	}
+
+	var browseURL, fileURL, lineFmt string
+	if proxyGitHubModulePat.MatchString(modulePath) {
+		dir := strings.TrimPrefix(strings.TrimPrefix(importPath, modulePath), "/")
+		ref := proxyVersionRef(info.Version)
+		browseURL = strings.TrimSuffix("https://"+modulePath+"/tree/"+ref+"/"+dir, "/")
+		fileURL = "https://" + modulePath + "/blob/" + ref + "/" + dir
+		if dir != "" {
+			fileURL += "/"
+		}
+		lineFmt = "%s#L%d"
+	}
+
+	var files []*File
+	subdirSet := make(map[string]bool)
+	found := false
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, dirPrefix) {
+			continue
+		}
+		found = true
+		name := zf.Name[len(dirPrefix):]
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			if name = name[:i]; isValidPathElement(name) {
+				subdirSet[name] = true
+			}
+			continue
+		}
+		if !isDocFile(name) {
+			continue
+		}
+		f := &File{Name: name}
+		if fileURL != "" {
+			f.BrowseURL = fileURL + name
+		}
+		if f.Data, err = readZipFile(zf); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if !found {
+		return nil, NotFoundError{Message: fmt.Sprintf("Directory not found in module %s@%s.", modulePath, info.Version)}
+	}
+
+	subdirs := make([]string, 0, len(subdirSet))
+	for name := range subdirSet {
+		subdirs = append(subdirs, name)
+	}
+	sort.Strings(subdirs)
+
+	dir := &Directory{
+		ImportPath:     importPath,
+		ResolvedPath:   importPath,
+		BrowseURL:      browseURL,
+		Etag:           info.Version,
+		Files:          files,
+		LineFmt:        lineFmt,
+		ProjectName:    path.Base(modulePath),
+		ProjectRoot:    modulePath,
+		ProjectURL:     "https://" + modulePath,
+		Subdirectories: subdirs,
+		Status:         status,
+	}
+	setGoMod(dir, mod, strings.TrimPrefix(importPath, modulePath))
+	if err := setLicenses(dir, strings.TrimPrefix(importPath, modulePath), zipFS{zr, modulePath + "@" + info.Version + "/"}); err != nil {
+		return nil, err
+	}
+	if dir.ModulePath == "" {
+		// Modules without a go.mod file have a synthesized module directive,
+		// but old proxies may serve an empty file.
+		dir.ModulePath = modulePath
+		dir.ModuleImportPath = importPath
+	}
+	return dir, nil
+}
+
+// zipFS is a repoFS for the files of a module zip. The prefix is the path of
+// the module root in the zip.
+type zipFS struct {
+	zr     *zip.Reader
+	prefix string
+}
+
+func (fs zipFS) readFile(name string) ([]byte, error) {
+	for _, zf := range fs.zr.File {
+		if zf.Name == fs.prefix+name {
+			return readZipFile(zf)
+		}
+	}
+	return nil, NotFoundError{Message: name + " not found in module zip."}
+}
+
+func (fs zipFS) readDir(name string) ([]string, error) {
+	prefix := fs.prefix
+	if name != "" {
+		prefix += name + "/"
+	}
+	var names []string
+	found := false
+	for _, zf := range fs.zr.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			continue
+		}
+		found = true
+		if n := zf.Name[len(prefix):]; n != "" && !strings.Contains(n, "/") {
+			names = append(names, n)
+		}
+	}
+	if !found {
+		return nil, NotFoundError{Message: name + " not found in module zip."}
+	}
+	return names, nil
+}
+
+func readZipFile(zf *zip.File) ([]byte, error) {
+	r, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
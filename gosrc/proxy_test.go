@@ -0,0 +1,221 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeTestProxy writes a file system module proxy to dir holding the given
+// module versions. Each version maps file names, relative to the module root,
+// to their contents.
+func writeTestProxy(t *testing.T, dir, modulePath string, list string, versions map[string]map[string]string) {
+	t.Helper()
+	vdir := filepath.Join(dir, filepath.FromSlash(escapeModulePath(modulePath)), "@v")
+	if err := os.MkdirAll(vdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(vdir, "list"), []byte(list), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for version, files := range versions {
+		base := filepath.Join(vdir, escapeModulePath(version))
+		info := `{"Version": "` + version + `", "Time": "2099-01-01T00:00:00Z"}`
+		if err := ioutil.WriteFile(base+".info", []byte(info), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(base+".mod", []byte(files["go.mod"]), 0666); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(base + ".zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := zip.NewWriter(f)
+		for name, data := range files {
+			w, err := zw.Create(modulePath + "@" + version + "/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetProxyDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosrc-proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestProxy(t, dir, "example.com/Mod", "v1.0.0\nv1.1.0\nv1.2.0-beta\n", map[string]map[string]string{
+		"v1.0.0": {
+			"go.mod":      "module example.com/Mod\n",
+			"LICENSE-MIT": testMITLicense,
+			"sub/sub.go":  "package sub // v1.0.0",
+		},
+		"v1.1.0": {
+			"go.mod":          "module example.com/Mod\n",
+			"mod.go":          "package mod",
+			"sub/sub.go":      "package sub",
+			"sub/sub_test.go": "package sub",
+			"sub/x.c":         "int x;",
+			"sub/inner/a.go":  "package inner",
+		},
+	})
+
+	if err := SetModuleProxy("file://" + filepath.ToSlash(dir) + ",direct"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetModuleProxy("")
+
+	client := &http.Client{Transport: testTransport(map[string]string{})}
+	ctx := context.Background()
+
+	got, err := Get(ctx, client, "example.com/Mod/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "example.com/Mod/sub",
+		ResolvedPath: "example.com/Mod/sub",
+		Etag:         "v1.1.0",
+		Files: []*File{
+			{Name: "sub.go", Data: []byte("package sub")},
+			{Name: "sub_test.go", Data: []byte("package sub")},
+		},
+		ProjectName:      "Mod",
+		ProjectRoot:      "example.com/Mod",
+		ProjectURL:       "https://example.com/Mod",
+		Subdirectories:   []string{"inner"},
+		ModulePath:       "example.com/Mod",
+		ModuleImportPath: "example.com/Mod/sub",
+		Licenses:         []*License{},
+	}
+	if diff := cmp.Diff(want, got, cmp.Transformer("filesByName", filesByName)); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Get(ctx, client, "example.com/Mod/sub", "v1.1.0"); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError", err)
+	}
+
+	got, err = GetVersion(ctx, client, "example.com/Mod/sub", "v1.0.0", "")
+	if err != nil {
+		t.Fatalf("GetVersion returned error %v", err)
+	}
+	if got.Version != "v1.0.0" || got.Etag != "v1.0.0" || len(got.Files) != 1 || string(got.Files[0].Data) != "package sub // v1.0.0" {
+		t.Errorf("GetVersion returned version %q, etag %q, files %v; want v1.0.0 source", got.Version, got.Etag, got.Files)
+	}
+	if want := []*License{{Path: "LICENSE-MIT", Types: []string{"MIT"}}}; !cmp.Equal(got.Licenses, want) {
+		t.Errorf("GetVersion returned licenses %v, want %v", got.Licenses, want)
+	}
+
+	versions, err := GetVersions(ctx, client, "example.com/Mod/sub")
+	if err != nil {
+		t.Fatalf("GetVersions returned error %v", err)
+	}
+	if want := []string{"v1.2.0-beta", "v1.1.0", "v1.0.0"}; !cmp.Equal(versions, want) {
+		t.Errorf("GetVersions returned %v, want %v", versions, want)
+	}
+
+	if _, err := Get(ctx, client, "example.com/Mod/missing", ""); err == nil {
+		t.Error("Get of missing directory did not return an error")
+	} else if !IsNotFound(err) {
+		t.Errorf("Get of missing directory returned %v, want NotFoundError", err)
+	}
+}
+
+func filesByName(files []*File) map[string]string {
+	m := make(map[string]string)
+	for _, f := range files {
+		m[f.Name] = string(f.Data)
+	}
+	return m
+}
+
+var compareSemverTests = []struct {
+	v, w string
+	want int
+}{
+	{"v1.0.0", "v1.0.0", 0},
+	{"v1.0.0", "v1.0.1", -1},
+	{"v1.10.0", "v1.9.0", 1},
+	{"v1.0.0-beta", "v1.0.0", -1},
+	{"v1.0.0-alpha.2", "v1.0.0-alpha.10", -1},
+	{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+	{"v1.0.0-rc.1", "v1.0.0-beta.9", 1},
+	{"v2", "v2.0.0", 0},
+	{"bad", "v0.0.0", -1},
+}
+
+func TestCompareSemver(t *testing.T) {
+	for _, tt := range compareSemverTests {
+		if got := compareSemver(tt.v, tt.w); got != tt.want {
+			t.Errorf("compareSemver(%q, %q) = %d, want %d", tt.v, tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeModulePath(t *testing.T) {
+	if got, want := escapeModulePath("github.com/Azure/azure-sdk"), "github.com/!azure/azure-sdk"; got != want {
+		t.Errorf("escapeModulePath = %q, want %q", got, want)
+	}
+}
+
+// endlessTransport responds to all requests with an endless body and counts
+// the bytes read from it.
+type endlessTransport struct {
+	n int64
+}
+
+func (t *endlessTransport) Read(p []byte) (int, error) {
+	t.n += int64(len(p))
+	return len(p), nil
+}
+
+func (t *endlessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(t), Request: req}, nil
+}
+
+func TestProxyGetZipLimit(t *testing.T) {
+	defer func(n int64) { maxModuleZipSize = n }(maxModuleZipSize)
+	maxModuleZipSize = 1 << 10
+
+	base, err := url.Parse("https://proxy.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &endlessTransport{}
+	client := &http.Client{Transport: transport}
+	if _, err := proxyGet(context.Background(), client, base, "example.com/m/@v/v1.0.0.zip"); err == nil {
+		t.Error("proxyGet of large zip returned nil error")
+	}
+	if transport.n > maxModuleZipSize+1 {
+		t.Errorf("proxyGet read %d bytes of zip, limit %d", transport.n, maxModuleZipSize)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repoFS reads the files of a repository by their slash-separated paths
+// relative to the repository root. It is used to find the go.mod and license
+// files that apply to a directory.
+type repoFS interface {
+	// readFile returns the contents of the file name. It returns
+	// NotFoundError if the file does not exist.
+	readFile(name string) ([]byte, error)
+
+	// readDir returns the names of the files, not including directories, in
+	// the directory name, "" for the root. It returns NotFoundError if the
+	// directory does not exist and errNoListing if directories cannot be
+	// listed.
+	readDir(name string) ([]string, error)
+}
+
+// errNoListing is returned by repoFS.readDir if directories cannot be listed.
+// Files are then looked up by name.
+var errNoListing = errors.New("gosrc: directory listing not available")
+
+// diskFS is a repoFS for a directory on the local file system.
+type diskFS string
+
+func (fs diskFS) readFile(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(fs), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, NotFoundError{Message: err.Error()}
+	}
+	return data, err
+}
+
+func (fs diskFS) readDir(name string) ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(string(fs), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, NotFoundError{Message: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// tree returns the slash-separated paths of all files in fs.
+func (fs diskFS) tree() ([]string, error) {
+	var paths []string
+	err := filepath.Walk(string(fs), func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(string(fs), p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
+// maxTreePages is the number of pages of a paginated list of the files of a
+// repository read before giving up on listing them.
+const maxTreePages = 10
+
+// repoTreeTTL is how long the list of files of a repository is reused. The
+// lists are cached by the commit of the crawled directory, so crawling the
+// packages of a project at the same commit lists the files of the repository
+// once.
+const repoTreeTTL = 10 * time.Minute
+
+// maxRepoTrees is the number of lists of files of repositories cached. The
+// oldest list is evicted first.
+const maxRepoTrees = 100
+
+// repoTree is the list of files of a repository.
+type repoTree struct {
+	dirs    map[string][]string // file names by directory, "" for the root
+	err     error               // errNoListing if the files could not be listed
+	fetched time.Time
+}
+
+var repoTrees = struct {
+	sync.Mutex
+	m map[string]*repoTree
+}{m: make(map[string]*repoTree)}
+
+// serviceFS is a repoFS for a repository hosted by a service. Files are read
+// with the service's getFile function and directories are listed from the
+// paths of all files in the repository returned by getTree. The paths are
+// cached by key for repoTreeTTL.
+type serviceFS struct {
+	key     string
+	getFile func(name string) ([]byte, error)
+	getTree func() ([]string, error) // nil if the service cannot list files
+	tree    *repoTree
+}
+
+func (fs *serviceFS) readFile(name string) ([]byte, error) {
+	return fs.getFile(name)
+}
+
+func (fs *serviceFS) readDir(name string) ([]string, error) {
+	if fs.getTree == nil {
+		return nil, errNoListing
+	}
+	if fs.tree == nil {
+		tree, err := fs.loadTree()
+		if err != nil {
+			return nil, err
+		}
+		fs.tree = tree
+	}
+	if fs.tree.err != nil {
+		return nil, fs.tree.err
+	}
+	names, ok := fs.tree.dirs[name]
+	if !ok {
+		return nil, NotFoundError{Message: "Directory not found: " + name}
+	}
+	return names, nil
+}
+
+// loadTree returns the cached tree of the repository or lists its files.
+func (fs *serviceFS) loadTree() (*repoTree, error) {
+	now := time.Now()
+	repoTrees.Lock()
+	tree := repoTrees.m[fs.key]
+	repoTrees.Unlock()
+	if tree != nil && now.Sub(tree.fetched) < repoTreeTTL {
+		return tree, nil
+	}
+
+	tree = &repoTree{dirs: map[string][]string{"": nil}, fetched: now}
+	paths, err := fs.getTree()
+	switch {
+	case err == errNoListing || IsNotFound(err):
+		// Repositories with too many files and services which do not
+		// support listing them are not listed again until the cached tree
+		// expires.
+		tree.err = errNoListing
+	case err != nil:
+		return nil, err
+	}
+	for _, p := range paths {
+		dir, name := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		tree.dirs[dir] = append(tree.dirs[dir], name)
+		// Add the parent directories, which may not contain files.
+		for dir != "" {
+			dir = path.Dir(dir)
+			if dir == "." {
+				dir = ""
+			}
+			if _, ok := tree.dirs[dir]; ok {
+				break
+			}
+			tree.dirs[dir] = nil
+		}
+	}
+
+	repoTrees.Lock()
+	oldest := ""
+	for k, t := range repoTrees.m {
+		if now.Sub(t.fetched) >= repoTreeTTL {
+			delete(repoTrees.m, k)
+		} else if oldest == "" || t.fetched.Before(repoTrees.m[oldest].fetched) {
+			oldest = k
+		}
+	}
+	if len(repoTrees.m) >= maxRepoTrees {
+		delete(repoTrees.m, oldest)
+	}
+	repoTrees.m[fs.key] = tree
+	repoTrees.Unlock()
+	return tree, nil
+}
+
+// hasFile returns true if the directory dir of fs may contain a file named
+// name. If directories cannot be listed, the file is assumed to exist.
+func hasFile(fs repoFS, dir, name string) (bool, error) {
+	names, err := fs.readDir(dir)
+	switch {
+	case err == errNoListing:
+		return true, nil
+	case IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	for _, n := range names {
+		if n == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
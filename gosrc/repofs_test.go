@@ -0,0 +1,57 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServiceFS(t *testing.T) {
+	calls := 0
+	getTree := func() ([]string, error) {
+		calls++
+		return []string{"go.mod", "a/b/c.go", "a/b/go.mod", "d/e.go"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		fs := &serviceFS{key: "example.com/tree@", getTree: getTree}
+		for _, tt := range []struct {
+			dir  string
+			want []string
+		}{
+			{"", []string{"go.mod"}},
+			{"a", nil},
+			{"a/b", []string{"c.go", "go.mod"}},
+			{"d", []string{"e.go"}},
+		} {
+			names, err := fs.readDir(tt.dir)
+			if err != nil {
+				t.Errorf("readDir(%q) returned error %v", tt.dir, err)
+			} else if !cmp.Equal(names, tt.want) {
+				t.Errorf("readDir(%q) = %v, want %v", tt.dir, names, tt.want)
+			}
+		}
+		if _, err := fs.readDir("x"); !IsNotFound(err) {
+			t.Errorf("readDir(x) returned error %v, want NotFoundError", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("getTree called %d times, want 1", calls)
+	}
+
+	fs := &serviceFS{key: "example.com/large@", getTree: func() ([]string, error) { return nil, errNoListing }}
+	if _, err := fs.readDir(""); err != errNoListing {
+		t.Errorf("readDir of unlisted repository returned error %v, want errNoListing", err)
+	}
+	if ok, err := hasFile(fs, "a", "go.mod"); !ok || err != nil {
+		t.Errorf("hasFile of unlisted repository = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := (&serviceFS{}).readDir(""); err != errNoListing {
+		t.Errorf("readDir without getTree returned error %v, want errNoListing", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"sort"
+	"strings"
+)
+
+// semver is a parsed semantic version of the form vMAJOR[.MINOR[.PATCH[-PRERELEASE][+BUILD]]].
+type semver struct {
+	major, minor, patch string
+	prerelease          string
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s == "0" || s[0] != '0'
+}
+
+// parseSemver parses v and reports whether it is a valid semantic version.
+// Shorthands like "v1" and "v1.2" are accepted as in the go command.
+func parseSemver(v string) (semver, bool) {
+	var sv semver
+	if !strings.HasPrefix(v, "v") {
+		return sv, false
+	}
+	v = v[1:]
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		sv.prerelease = v[i+1:]
+		if sv.prerelease == "" {
+			return sv, false
+		}
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return sv, false
+	}
+	for _, p := range parts {
+		if !isNumeric(p) {
+			return sv, false
+		}
+	}
+	for len(parts) < 3 {
+		if sv.prerelease != "" {
+			// Shorthands may not carry a prerelease suffix.
+			return sv, false
+		}
+		parts = append(parts, "0")
+	}
+	sv.major, sv.minor, sv.patch = parts[0], parts[1], parts[2]
+	return sv, true
+}
+
+// isSemver reports whether v is a valid semantic version.
+func isSemver(v string) bool {
+	_, ok := parseSemver(v)
+	return ok
+}
+
+// isPrerelease reports whether v is a semantic version with a prerelease
+// suffix. Pseudo-versions are prereleases.
+func isPrerelease(v string) bool {
+	sv, ok := parseSemver(v)
+	return ok && sv.prerelease != ""
+}
+
+func compareNum(x, y string) int {
+	switch {
+	case len(x) < len(y):
+		return -1
+	case len(x) > len(y):
+		return 1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func comparePrerelease(x, y string) int {
+	switch {
+	case x == y:
+		return 0
+	case x == "":
+		return 1
+	case y == "":
+		return -1
+	}
+	xs, ys := strings.Split(x, "."), strings.Split(y, ".")
+	for i := 0; i < len(xs) && i < len(ys); i++ {
+		if xs[i] == ys[i] {
+			continue
+		}
+		xn, yn := isNumeric(xs[i]), isNumeric(ys[i])
+		switch {
+		case xn && yn:
+			return compareNum(xs[i], ys[i])
+		case xn:
+			return -1
+		case yn:
+			return 1
+		case xs[i] < ys[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return compareNum(strings.Repeat("0", len(xs)), strings.Repeat("0", len(ys)))
+}
+
+// compareSemver returns -1, 0 or 1 when v is less than, equal to or greater
+// than w. Invalid versions compare less than valid ones.
+func compareSemver(v, w string) int {
+	sv, vok := parseSemver(v)
+	sw, wok := parseSemver(w)
+	switch {
+	case !vok && !wok:
+		return 0
+	case !vok:
+		return -1
+	case !wok:
+		return 1
+	}
+	if c := compareNum(sv.major, sw.major); c != 0 {
+		return c
+	}
+	if c := compareNum(sv.minor, sw.minor); c != 0 {
+		return c
+	}
+	if c := compareNum(sv.patch, sw.patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(sv.prerelease, sw.prerelease)
+}
+
+// sortVersions sorts semantic versions newest first, followed by other
+// versions such as branch names in their original order.
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareSemver(versions[i], versions[j]) > 0
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Service is a source code hosting service. Services are registered with
+// RegisterService to fetch import paths that the package does not know about,
+// such as paths on an organization's internal Git server.
+type Service interface {
+	// Match reports whether the service handles importPath. It returns nil
+	// if the path belongs to another service or a map of values extracted
+	// from the path otherwise. The map is passed to the other methods. The
+	// "dir" key, if set, holds the path of the directory relative to the
+	// repository root, either empty or starting with a slash.
+	Match(importPath string) (map[string]string, error)
+
+	// Get fetches the directory. If the directory has not changed since the
+	// cache validation tag etag, Get returns NotModifiedError. If the
+	// directory does not exist, Get returns NotFoundError.
+	Get(ctx context.Context, client *http.Client, match map[string]string, etag string) (*Directory, error)
+
+	// GetPresentation fetches the presentation named by match["file"] in the
+	// directory. Services without presentations return NotFoundError.
+	GetPresentation(ctx context.Context, client *http.Client, match map[string]string) (*Presentation, error)
+
+	// GetProject fetches information about the repository.
+	GetProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error)
+}
+
+// RegisterService adds s to the services used to fetch import paths.
+// Registered services are tried before the built-in services, most recently
+// registered first. RegisterService is not safe to call concurrently with the
+// functions that fetch import paths and is intended to be called during
+// initialization.
+func RegisterService(s Service) {
+	services = append([]*service{{
+		matchFn:         s.Match,
+		get:             s.Get,
+		getPresentation: s.GetPresentation,
+		getProject:      s.GetProject,
+	}}, services...)
+}
+
+// URLTemplates describes the web pages of repositories on a host that are
+// fetched with version control commands. The templates are expanded with the
+// named groups of Pattern and the keys "tag", the version control tag or
+// branch, and "dir", the directory relative to the repository root with a
+// trailing slash or empty for the root. The File template also substitutes
+// the file name for "{0}".
+type URLTemplates struct {
+	// Regular expression matching the repository path, the repository URL
+	// without scheme and version control suffix.
+	Pattern string
+
+	// Template for the URL of a directory.
+	Dir string
+
+	// Template for the URL of a file.
+	File string
+
+	// Template for the URL of the project home page.
+	Project string
+
+	// Format for links to source lines as used by Directory.LineFmt, for
+	// example "%s#L%d".
+	Line string
+}
+
+// RegisterURLTemplates adds templates for repositories matching t.Pattern.
+// Registered templates take precedence over the built-in ones.
+func RegisterURLTemplates(t URLTemplates) error {
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return err
+	}
+	if t.Line != "" && (strings.Count(t.Line, "%s") != 1 || strings.Count(t.Line, "%d") != 1 ||
+		strings.Index(t.Line, "%d") < strings.Index(t.Line, "%s")) {
+		return fmt.Errorf("gosrc: line format %q must contain one %%s followed by one %%d", t.Line)
+	}
+	keys := map[string]bool{"tag": true, "dir": true}
+	for _, name := range re.SubexpNames() {
+		keys[name] = name != ""
+	}
+	for _, template := range []string{t.Dir, t.File, t.Project} {
+		if strings.ContainsAny(urlTemplateKeyPat.ReplaceAllString(template, ""), "{}") {
+			return fmt.Errorf("gosrc: unbalanced braces in URL template %q", template)
+		}
+		for _, m := range urlTemplateKeyPat.FindAllStringSubmatch(template, -1) {
+			if !keys[m[1]] && !(m[1] == "0" && template == t.File) {
+				return fmt.Errorf("gosrc: unknown key {%s} in URL template %q", m[1], template)
+			}
+		}
+	}
+	vcsServices = append([]*urlTemplates{{
+		re:         re,
+		fileBrowse: t.File,
+		project:    t.Project,
+		line:       t.Line,
+		dirBrowse:  t.Dir,
+	}}, vcsServices...)
+	return nil
+}
+
+var urlTemplateKeyPat = regexp.MustCompile(`\{([^{}]*)\}`)
+
+type urlTemplates struct {
+	re         *regexp.Regexp
+	fileBrowse string
+	project    string
+	line       string
+	dirBrowse  string
+}
+
+var vcsServices = []*urlTemplates{
+	{
+		regexp.MustCompile(`^git\.gitorious\.org/(?P<repo>[^/]+/[^/]+)$`),
+		"https://gitorious.org/{repo}/blobs/{tag}/{dir}{0}",
+		"https://gitorious.org/{repo}",
+		"%s#line%d",
+		"https://gitorious.org/{repo}/trees/{tag}/{dir}",
+	},
+	{
+		regexp.MustCompile(`^git\.oschina\.net/(?P<repo>[^/]+/[^/]+)$`),
+		"http://git.oschina.net/{repo}/blob/{tag}/{dir}{0}",
+		"http://git.oschina.net/{repo}",
+		"%s#L%d",
+		"http://git.oschina.net/{repo}/tree/{tag}/{dir}",
+	},
+	{
+		regexp.MustCompile(`^(?P<r1>[^.]+)\.googlesource.com/(?P<r2>[^./]+)$`),
+		"https://{r1}.googlesource.com/{r2}/+/{tag}/{dir}{0}",
+		"https://{r1}.googlesource.com/{r2}/+/{tag}",
+		"%s#%d",
+		"https://{r1}.googlesource.com/{r2}/+/{tag}/{dir}",
+	},
+	{
+		regexp.MustCompile(`^gitcafe.com/(?P<repo>[^/]+/.[^/]+)$`),
+		"https://gitcafe.com/{repo}/tree/{tag}/{dir}{0}",
+		"https://gitcafe.com/{repo}",
+		"",
+		"https://gitcafe.com/{repo}/tree/{tag}/{dir}",
+	},
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// internalService serves import paths on git.internal.example.com.
+type internalService struct{}
+
+func (internalService) Match(importPath string) (map[string]string, error) {
+	const host = "git.internal.example.com/"
+	if !strings.HasPrefix(importPath, host) {
+		return nil, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(importPath, host), "/", 2)
+	match := map[string]string{"repo": parts[0], "dir": ""}
+	if len(parts) == 2 {
+		match["dir"] = "/" + parts[1]
+	}
+	return match, nil
+}
+
+func (internalService) Get(ctx context.Context, client *http.Client, match map[string]string, etag string) (*Directory, error) {
+	if match["repo"] == "missing" {
+		return nil, NotFoundError{Message: "repository not found"}
+	}
+	return &Directory{
+		ProjectRoot: "git.internal.example.com/" + match["repo"],
+		ProjectName: match["repo"],
+		ProjectURL:  "https://git.internal.example.com/" + match["repo"],
+		BrowseURL:   "https://git.internal.example.com/" + match["repo"] + "/tree" + match["dir"],
+		Files:       []*File{{Name: "x.go", Data: []byte("package x")}},
+	}, nil
+}
+
+func (internalService) GetPresentation(ctx context.Context, client *http.Client, match map[string]string) (*Presentation, error) {
+	return nil, NotFoundError{Message: "presentations not supported"}
+}
+
+func (internalService) GetProject(ctx context.Context, client *http.Client, match map[string]string) (*Project, error) {
+	return &Project{Description: "Internal repository " + match["repo"] + "."}, nil
+}
+
+func TestRegisterService(t *testing.T) {
+	savedServices := services
+	defer func() { services = savedServices }()
+	RegisterService(internalService{})
+
+	ctx := context.Background()
+	client := &http.Client{Transport: testTransport(map[string]string{})}
+
+	dir, err := Get(ctx, client, "git.internal.example.com/tools/cmd/lint", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "git.internal.example.com/tools/cmd/lint",
+		ResolvedPath: "git.internal.example.com/tools/cmd/lint",
+		ProjectRoot:  "git.internal.example.com/tools",
+		ProjectName:  "tools",
+		ProjectURL:   "https://git.internal.example.com/tools",
+		BrowseURL:    "https://git.internal.example.com/tools/tree/cmd/lint",
+		Files:        []*File{{Name: "x.go", Data: []byte("package x")}},
+	}
+	if diff := cmp.Diff(want, dir); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Get(ctx, client, "git.internal.example.com/missing", ""); !IsNotFound(err) {
+		t.Errorf("Get of missing repository returned %v, want NotFoundError", err)
+	}
+
+	project, err := GetProject(ctx, client, "git.internal.example.com/tools")
+	if err != nil {
+		t.Fatalf("GetProject returned error %v", err)
+	}
+	if want := "Internal repository tools."; project.Description != want {
+		t.Errorf("GetProject returned description %q, want %q", project.Description, want)
+	}
+
+	// Paths not matched by the registered service use the built-in services.
+	if _, err := GetProject(ctx, client, "example.com/other"); !IsNotFound(err) {
+		t.Errorf("GetProject of unmatched path returned %v, want NotFoundError", err)
+	}
+}
+
+var registerURLTemplatesTests = []struct {
+	templates URLTemplates
+	ok        bool
+}{
+	{URLTemplates{
+		Pattern: `^git\.example\.com/(?P<repo>[^/]+/[^/]+)$`,
+		Dir:     "https://git.example.com/{repo}/tree/{tag}/{dir}",
+		File:    "https://git.example.com/{repo}/blob/{tag}/{dir}{0}",
+		Project: "https://git.example.com/{repo}",
+		Line:    "%s#L%d",
+	}, true},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>[^/]+`}, false},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>.+)$`, Line: "%d#L%s"}, false},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>.+)$`, Line: "#L%d"}, false},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>.+)$`, Project: "https://git.example.com/{owner}"}, false},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>.+)$`, Project: "https://git.example.com/{repo}/{0}"}, false},
+	{URLTemplates{Pattern: `^git\.example\.com/(?P<repo>.+)$`, Dir: "https://git.example.com/{repo"}, false},
+}
+
+func TestRegisterURLTemplates(t *testing.T) {
+	savedVCSServices := vcsServices
+	defer func() { vcsServices = savedVCSServices }()
+
+	for _, tt := range registerURLTemplatesTests {
+		vcsServices = savedVCSServices
+		err := RegisterURLTemplates(tt.templates)
+		if (err == nil) != tt.ok {
+			t.Errorf("RegisterURLTemplates(%+v) returned error %v, want ok %v", tt.templates, err, tt.ok)
+		}
+	}
+
+	vcsServices = savedVCSServices
+	if err := RegisterURLTemplates(registerURLTemplatesTests[0].templates); err != nil {
+		t.Fatal(err)
+	}
+	template, match := lookupURLTemplate("git.example.com/team/repo", "/sub", "master")
+	if got, want := expand(template.dirBrowse, match), "https://git.example.com/team/repo/tree/master/sub/"; got != want {
+		t.Errorf("directory URL = %q, want %q", got, want)
+	}
+	if got, want := expand(template.fileBrowse, match, "x.go"), "https://git.example.com/team/repo/blob/master/sub/x.go"; got != want {
+		t.Errorf("file URL = %q, want %q", got, want)
+	}
+	if got, want := template.line, "%s#L%d"; got != want {
+		t.Errorf("line format = %q, want %q", got, want)
+	}
+}
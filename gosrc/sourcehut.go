@@ -0,0 +1,301 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	addSourcehutService("git")
+	addSourcehutService("hg")
+}
+
+// addSourcehutService adds the service for repositories of the given version
+// control system hosted on sourcehut. The sourcehut APIs require
+// authentication, so the service resolves commits with the protocols used by
+// the version control commands, reads directories from repository archives
+// and links to the predictable tree and blob URLs of the web interface.
+func addSourcehutService(vcs string) {
+	s := &service{
+		pattern: regexp.MustCompile(`^(?P<host>` + vcs + `\.sr\.ht)/(?P<owner>~[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/.*)?$`),
+		prefix:  vcs + ".sr.ht/",
+		get:     getSourcehutDir,
+		getFile: getSourcehutFile,
+	}
+	if vcs == "git" {
+		s.getVersions = getSourcehutVersions
+	}
+	addService(s)
+}
+
+var sourcehutURLs = map[string]struct {
+	// Templates for directory and file browse URLs and for the raw file with
+	// path {0} at commit {commit}.
+	dir, file, raw string
+}{
+	"git": {
+		dir:  "https://{host}/{owner}/{repo}/tree/{tag}/item{dir}",
+		file: "https://{host}/{owner}/{repo}/tree/{tag}/item{dir}/{0}",
+		raw:  "https://{host}/{owner}/{repo}/blob/{commit}/{0}",
+	},
+	"hg": {
+		dir:  "https://{host}/{owner}/{repo}/browse{dir}?rev={tag}",
+		file: "https://{host}/{owner}/{repo}/browse{dir}/{0}?rev={tag}",
+		raw:  "https://{host}/{owner}/{repo}/raw/{0}?rev={commit}",
+	},
+}
+
+// sourcehutVCS returns the version control system of the matched repository.
+func sourcehutVCS(match map[string]string) string {
+	return strings.TrimSuffix(match["host"], ".sr.ht")
+}
+
+func getSourcehutDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
+	c := &httpClient{client: client}
+	vcs := sourcehutVCS(match)
+
+	var err error
+	switch vcs {
+	case "git":
+		err = resolveSourcehutGitCommit(ctx, c, match)
+	case "hg":
+		err = resolveSourcehutHgCommit(ctx, c, match)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if match["commit"] == savedEtag {
+		return nil, NotModifiedError{Status: Active}
+	}
+
+	archiveURL := expand("https://{host}/{owner}/{repo}/archive/{commit}.tar.gz", match)
+	var files []*File
+	var subdirs []string
+	if archiveCacheDir != "" {
+		_, files, subdirs, err = getArchiveDir(ctx, c, expand("{host}/{owner}/{repo}", match), match["commit"], archiveURL, match["dir"])
+	} else {
+		var r io.ReadCloser
+		r, err = c.getReader(ctx, archiveURL)
+		if err == nil {
+			files, subdirs, err = readArchive(r, match["dir"])
+			r.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && len(subdirs) == 0 {
+		return nil, NotFoundError{Message: "No files in directory."}
+	}
+
+	urls := sourcehutURLs[vcs]
+	for _, f := range files {
+		f.BrowseURL = expand(urls.file, match, f.Name)
+	}
+	browseURL := expand("https://{host}/{owner}/{repo}", match)
+	if match["dir"] != "" || match["version"] != "" {
+		browseURL = expand(urls.dir, match)
+	}
+
+	return &Directory{
+		BrowseURL:      browseURL,
+		Etag:           match["commit"],
+		Files:          files,
+		LineFmt:        "%s#L%d",
+		ProjectName:    match["repo"],
+		ProjectRoot:    expand("{host}/{owner}/{repo}", match),
+		ProjectURL:     expand("https://{host}/{owner}/{repo}", match),
+		Subdirectories: subdirs,
+		VCS:            vcs,
+		Status:         Active,
+	}, nil
+}
+
+// resolveSourcehutGitCommit sets the "tag" and "commit" keys of match to the
+// requested version, or the default branch, of a Git repository and the commit
+// it refers to.
+func resolveSourcehutGitCommit(ctx context.Context, c *httpClient, match map[string]string) error {
+	head, refs, err := getGitRefs(ctx, c, expand("https://{host}/{owner}/{repo}", match))
+	if err != nil {
+		return err
+	}
+	version := match["version"]
+	switch {
+	case version == "":
+		match["tag"] = strings.TrimPrefix(head, "refs/heads/")
+		match["commit"] = refs[head]
+	case refs["refs/tags/"+version] != "":
+		match["tag"] = version
+		match["commit"] = refs["refs/tags/"+version]
+	case refs["refs/heads/"+version] != "":
+		match["tag"] = version
+		match["commit"] = refs["refs/heads/"+version]
+	case isCommitHash(version):
+		match["tag"] = version
+		match["commit"] = version
+	}
+	if match["commit"] == "" {
+		return NotFoundError{Message: "Version not found in repository."}
+	}
+	return nil
+}
+
+// resolveSourcehutHgCommit sets the "tag" and "commit" keys of match to the
+// requested version, or the default branch, of a Mercurial repository and the
+// changeset it refers to.
+func resolveSourcehutHgCommit(ctx context.Context, c *httpClient, match map[string]string) error {
+	match["tag"] = defaultTags["hg"]
+	if match["version"] != "" {
+		match["tag"] = match["version"]
+	}
+	commit, err := getHgLookup(ctx, c, expand("https://{host}/{owner}/{repo}", match), match["tag"])
+	if err != nil {
+		return err
+	}
+	match["commit"] = commit
+	return nil
+}
+
+var commitHashPat = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+func isCommitHash(s string) bool {
+	return commitHashPat.MatchString(s)
+}
+
+func getSourcehutVersions(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client}
+	_, refs, err := getGitRefs(ctx, c, expand("https://{host}/{owner}/{repo}", match))
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for ref := range refs {
+		if strings.HasPrefix(ref, "refs/tags/") {
+			versions = append(versions, strings.TrimPrefix(ref, "refs/tags/"))
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func getSourcehutFile(ctx context.Context, client *http.Client, match map[string]string, name string) ([]byte, error) {
+	c := &httpClient{client: client}
+	return c.getBytes(ctx, expand(sourcehutURLs[sourcehutVCS(match)].raw, match, name))
+}
+
+var errBadGitRefs = errors.New("malformed Git reference advertisement")
+
+// getGitRefs gets the references of the Git repository at repoURL with the
+// smart HTTP protocol. It returns the reference HEAD points to and the
+// commits of the branches and tags. Annotated tags are mapped to the commit
+// they refer to.
+func getGitRefs(ctx context.Context, c *httpClient, repoURL string) (string, map[string]string, error) {
+	p, err := c.getBytes(ctx, repoURL+"/info/refs?service=git-upload-pack")
+	if err != nil {
+		return "", nil, err
+	}
+	head, refs, err := parseGitRefs(p)
+	if err == errBadGitRefs {
+		u, _ := url.Parse(repoURL)
+		return "", nil, &RemoteError{u.Host, err}
+	}
+	return head, refs, err
+}
+
+// parseGitRefs parses the reference advertisement of the Git smart HTTP
+// protocol.
+func parseGitRefs(p []byte) (string, map[string]string, error) {
+	var head, headCommit string
+	refs := make(map[string]string)
+	peeled := make(map[string]string)
+	for len(p) > 0 {
+		if len(p) < 4 {
+			return "", nil, errBadGitRefs
+		}
+		n, err := strconv.ParseUint(string(p[:4]), 16, 16)
+		if err != nil || (n != 0 && (n < 4 || int(n) > len(p))) {
+			return "", nil, errBadGitRefs
+		}
+		if n == 0 {
+			// Flush packet.
+			p = p[4:]
+			continue
+		}
+		line := bytes.TrimSuffix(p[4:n], []byte("\n"))
+		p = p[n:]
+		if bytes.HasPrefix(line, []byte("#")) {
+			continue
+		}
+		var caps []byte
+		if i := bytes.IndexByte(line, 0); i >= 0 {
+			line, caps = line[:i], line[i+1:]
+		}
+		for _, c := range bytes.Fields(caps) {
+			if bytes.HasPrefix(c, []byte("symref=HEAD:")) {
+				head = string(c[len("symref=HEAD:"):])
+			}
+		}
+		f := strings.Fields(string(line))
+		if len(f) != 2 {
+			return "", nil, errBadGitRefs
+		}
+		commit, name := f[0], f[1]
+		switch {
+		case name == "HEAD":
+			headCommit = commit
+		case strings.HasSuffix(name, "^{}"):
+			peeled[strings.TrimSuffix(name, "^{}")] = commit
+		default:
+			refs[name] = commit
+		}
+	}
+	for name, commit := range peeled {
+		refs[name] = commit
+	}
+	if head == "" && headCommit != "" {
+		// Servers without the symref capability; pick a branch at the
+		// commit of HEAD.
+		var names []string
+		for name, commit := range refs {
+			if commit == headCommit && strings.HasPrefix(name, "refs/heads/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			head = names[0]
+		}
+	}
+	if head == "" || refs[head] == "" {
+		return "", nil, NotFoundError{Message: "Repository has no default branch."}
+	}
+	return head, refs, nil
+}
+
+// getHgLookup resolves rev to a changeset of the Mercurial repository at
+// repoURL with the lookup command of the Mercurial HTTP protocol.
+func getHgLookup(ctx context.Context, c *httpClient, repoURL, rev string) (string, error) {
+	p, err := c.getBytes(ctx, repoURL+"?cmd=lookup&key="+url.QueryEscape(rev))
+	if err != nil {
+		return "", err
+	}
+	f := strings.Fields(string(p))
+	if len(f) < 2 || f[0] != "1" {
+		return "", NotFoundError{Message: "Revision " + rev + " not found in repository."}
+	}
+	return f[1], nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pktLines returns lines encoded as packets of the Git protocol. Empty lines
+// are flush packets.
+func pktLines(lines ...string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			b.WriteString("0000")
+			continue
+		}
+		fmt.Fprintf(&b, "%04x%s\n", len(line)+5, line)
+	}
+	return b.String()
+}
+
+const (
+	srhtCommit1 = "1111111111111111111111111111111111111111"
+	srhtCommit2 = "2222222222222222222222222222222222222222"
+	srhtTagObj  = "3333333333333333333333333333333333333333"
+)
+
+var srhtRefs = pktLines(
+	"# service=git-upload-pack",
+	"",
+	srhtCommit2+" HEAD\x00multi_ack symref=HEAD:refs/heads/main agent=git/2.30",
+	srhtCommit1+" refs/heads/dev",
+	srhtCommit2+" refs/heads/main",
+	srhtTagObj+" refs/tags/v1.0.0",
+	srhtCommit1+" refs/tags/v1.0.0^{}",
+	"",
+)
+
+func TestParseGitRefs(t *testing.T) {
+	head, refs, err := parseGitRefs([]byte(srhtRefs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != "refs/heads/main" {
+		t.Errorf("head = %q, want refs/heads/main", head)
+	}
+	want := map[string]string{
+		"refs/heads/dev":   srhtCommit1,
+		"refs/heads/main":  srhtCommit2,
+		"refs/tags/v1.0.0": srhtCommit1,
+	}
+	if diff := cmp.Diff(want, refs); diff != "" {
+		t.Errorf("refs mismatch (-want +got):\n%s", diff)
+	}
+
+	// Without the symref capability, HEAD is resolved by its commit.
+	head, _, err = parseGitRefs([]byte(pktLines(srhtCommit1+" HEAD", srhtCommit1+" refs/heads/trunk", "")))
+	if err != nil || head != "refs/heads/trunk" {
+		t.Errorf("parseGitRefs without symref returned %q, %v; want refs/heads/trunk", head, err)
+	}
+
+	if _, _, err := parseGitRefs([]byte("00zzjunk")); err != errBadGitRefs {
+		t.Errorf("parseGitRefs of malformed input returned %v, want %v", err, errBadGitRefs)
+	}
+}
+
+func TestGetSourcehut(t *testing.T) {
+	archive := testArchive(t, "repo-"+srhtCommit2, map[string]string{
+		"go.mod":       "module git.sr.ht/~owner/repo\n",
+		"repo.go":      "package repo",
+		"cmd/x/x.go":   "package main",
+		"cmd/README":   "readme",
+		"cmd/data.bin": "data",
+	})
+	client := &http.Client{Transport: testTransport{
+		"https://git.sr.ht/~owner/repo/info/refs":                           srhtRefs,
+		"https://git.sr.ht/~owner/repo/archive/" + srhtCommit2 + ".tar.gz":  archive,
+		"https://git.sr.ht/~owner/repo/blob/" + srhtCommit2 + "/go.mod":     "module git.sr.ht/~owner/repo\n",
+		"https://hg.sr.ht/~owner/hgrepo":                                    "1 " + srhtCommit1 + "\n",
+		"https://hg.sr.ht/~owner/hgrepo/archive/" + srhtCommit1 + ".tar.gz": testArchive(t, "hgrepo-"+srhtCommit1[:12], map[string]string{"sub/a.go": "package sub"}),
+		"https://hg.sr.ht/~owner/hgrepo/raw/go.mod":                         "module hg.sr.ht/~owner/hgrepo\n",
+	}}
+	ctx := context.Background()
+
+	got, err := Get(ctx, client, "git.sr.ht/~owner/repo/cmd", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	want := &Directory{
+		ImportPath:   "git.sr.ht/~owner/repo/cmd",
+		ResolvedPath: "git.sr.ht/~owner/repo/cmd",
+		ProjectRoot:  "git.sr.ht/~owner/repo",
+		ProjectName:  "repo",
+		ProjectURL:   "https://git.sr.ht/~owner/repo",
+		BrowseURL:    "https://git.sr.ht/~owner/repo/tree/main/item/cmd",
+		LineFmt:      "%s#L%d",
+		VCS:          "git",
+		Etag:         srhtCommit2,
+		Files: []*File{
+			{Name: "README", Data: []byte("readme"), BrowseURL: "https://git.sr.ht/~owner/repo/tree/main/item/cmd/README"},
+		},
+		Subdirectories:   []string{"x"},
+		ModulePath:       "git.sr.ht/~owner/repo",
+		ModuleImportPath: "git.sr.ht/~owner/repo/cmd",
+		Licenses:         []*License{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Get(ctx, client, "git.sr.ht/~owner/repo", srhtCommit2); err == nil {
+		t.Error("Get with current etag did not return an error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("Get with current etag returned %v, want NotModifiedError", err)
+	}
+	if _, err := Get(ctx, client, "git.sr.ht/~owner/repo/missing", ""); !IsNotFound(err) {
+		t.Errorf("Get of missing directory returned %v, want NotFoundError", err)
+	}
+	if _, err := GetVersion(ctx, client, "git.sr.ht/~owner/repo", "v2.0.0", ""); !IsNotFound(err) {
+		t.Errorf("GetVersion of missing version returned %v, want NotFoundError", err)
+	}
+
+	got, err = Get(ctx, client, "hg.sr.ht/~owner/hgrepo/sub", "")
+	if err != nil {
+		t.Fatalf("Get returned error %v", err)
+	}
+	if got.VCS != "hg" || got.Etag != srhtCommit1 || got.ModulePath != "hg.sr.ht/~owner/hgrepo" {
+		t.Errorf("Get returned VCS %q, etag %q, module %q; want hg, %s, hg.sr.ht/~owner/hgrepo", got.VCS, got.Etag, got.ModulePath, srhtCommit1)
+	}
+	if len(got.Files) != 1 || got.Files[0].BrowseURL != "https://hg.sr.ht/~owner/hgrepo/browse/sub/a.go?rev=default" {
+		t.Errorf("Get returned files %v, want a.go with browse URL", got.Files)
+	}
+}
@@ -7,12 +7,13 @@
 package gosrc
 
 import (
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
-var defaultTags = map[string]string{"git": "master", "hg": "default"}
+var defaultTags = map[string]string{"git": "master", "hg": "default", "bzr": "trunk"}
 
 func bestTag(tags map[string]string, defaultTag string) (string, string, error) {
 	if commit, ok := tags["go1"]; ok {
@@ -48,6 +49,16 @@ func expand(template string, match map[string]string, subs ...string) string {
 	return string(p)
 }
 
+// escapePath escapes the elements of the slash-separated path p for use in a
+// URL path.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, e := range elems {
+		elems[i] = url.PathEscape(e)
+	}
+	return strings.Join(elems, "/")
+}
+
 var readmePat = regexp.MustCompile(`(?i)^readme(?:$|\.)`)
 
 // isDocFile returns true if a file with name n should be included in the
@@ -56,7 +67,10 @@ func isDocFile(n string) bool {
 	if strings.HasSuffix(n, ".go") && n[0] != '_' && n[0] != '.' {
 		return true
 	}
-	return readmePat.MatchString(n)
+	if n == "go.mod" {
+		return true
+	}
+	return readmePat.MatchString(n) || IsLicenseFile(n)
 }
 
 var linePat = regexp.MustCompile(`(?m)^//line .*$`)
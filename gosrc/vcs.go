@@ -44,40 +44,6 @@ const (
 // Store temporary data in this directory.
 var TempDir = filepath.Join(os.TempDir(), "gddo")
 
-type urlTemplates struct {
-	re         *regexp.Regexp
-	fileBrowse string
-	project    string
-	line       string
-}
-
-var vcsServices = []*urlTemplates{
-	{
-		regexp.MustCompile(`^git\.gitorious\.org/(?P<repo>[^/]+/[^/]+)$`),
-		"https://gitorious.org/{repo}/blobs/{tag}/{dir}{0}",
-		"https://gitorious.org/{repo}",
-		"%s#line%d",
-	},
-	{
-		regexp.MustCompile(`^git\.oschina\.net/(?P<repo>[^/]+/[^/]+)$`),
-		"http://git.oschina.net/{repo}/blob/{tag}/{dir}{0}",
-		"http://git.oschina.net/{repo}",
-		"%s#L%d",
-	},
-	{
-		regexp.MustCompile(`^(?P<r1>[^.]+)\.googlesource.com/(?P<r2>[^./]+)$`),
-		"https://{r1}.googlesource.com/{r2}/+/{tag}/{dir}{0}",
-		"https://{r1}.googlesource.com/{r2}/+/{tag}",
-		"%s#%d",
-	},
-	{
-		regexp.MustCompile(`^gitcafe.com/(?P<repo>[^/]+/.[^/]+)$`),
-		"https://gitcafe.com/{repo}/tree/{tag}/{dir}{0}",
-		"https://gitcafe.com/{repo}",
-		"",
-	},
-}
-
 // lookupURLTemplate finds an expand() template, match map and line number
 // format for well known repositories.
 func lookupURLTemplate(repo, dir, tag string) (*urlTemplates, map[string]string) {
@@ -111,6 +77,14 @@ var vcsCmds = map[string]*vcsCmd{
 		schemes:  []string{"http", "https", "ssh", "git"},
 		download: downloadGit,
 	},
+	"hg": {
+		schemes:  []string{"https", "http", "ssh"},
+		download: downloadHg,
+	},
+	"bzr": {
+		schemes:  []string{"https", "http", "bzr", "bzr+ssh"},
+		download: downloadBzr,
+	},
 	"svn": {
 		schemes:  []string{"http", "https", "svn"},
 		download: downloadSVN,
@@ -185,6 +159,176 @@ func downloadGit(schemes []string, clonePath, repo, savedEtag string) (string, s
 	return tag, etag, nil
 }
 
+// hgIdentify returns the full changeset hash of rev in the repository at
+// source, a URL or local directory.
+func hgIdentify(source, rev string) (string, error) {
+	args := []string{"identify", "--debug", "--id"}
+	if rev != "" {
+		args = append(args, "--rev", rev)
+	}
+	cmd := exec.Command("hg", append(args, source)...)
+	log.Println(strings.Join(cmd.Args, " "))
+	p, err := outputWithTimeout(cmd, lsRemoteTimeout)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(strings.TrimSpace(string(p)), "+"), nil
+}
+
+func downloadHg(schemes []string, clonePath, repo, savedEtag string) (string, string, error) {
+	var scheme string
+	tags := make(map[string]string)
+	for i := range schemes {
+		commit, err := hgIdentify(schemes[i]+"://"+clonePath, defaultTags["hg"])
+		if err == nil {
+			scheme = schemes[i]
+			tags[defaultTags["hg"]] = commit
+			break
+		}
+	}
+
+	if scheme == "" {
+		return "", "", NotFoundError{Message: "VCS not found"}
+	}
+
+	if commit, err := hgIdentify(scheme+"://"+clonePath, "go1"); err == nil {
+		tags["go1"] = commit
+	}
+
+	tag, commit, err := bestTag(tags, defaultTags["hg"])
+	if err != nil {
+		return "", "", err
+	}
+
+	etag := scheme + "-" + commit
+
+	if etag == savedEtag {
+		return "", "", NotModifiedError{}
+	}
+
+	dir := filepath.Join(TempDir, repo+".hg")
+	_, err = os.Stat(filepath.Join(dir, ".hg"))
+	switch {
+	case err != nil:
+		if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+			return "", "", err
+		}
+		cmd := exec.Command("hg", "clone", "--noupdate", scheme+"://"+clonePath, dir)
+		log.Println(strings.Join(cmd.Args, " "))
+		if err := runWithTimeout(cmd, cloneTimeout); err != nil {
+			return "", "", err
+		}
+	default:
+		if local, err := hgIdentify(dir, ""); err == nil && local == commit {
+			return tag, etag, nil
+		}
+		cmd := exec.Command("hg", "pull")
+		log.Println(strings.Join(cmd.Args, " "))
+		cmd.Dir = dir
+		if err := runWithTimeout(cmd, fetchTimeout); err != nil {
+			return "", "", err
+		}
+	}
+
+	cmd := exec.Command("hg", "update", "--clean", "--rev", commit)
+	cmd.Dir = dir
+	if err := runWithTimeout(cmd, checkoutTimeout); err != nil {
+		return "", "", err
+	}
+
+	return tag, etag, nil
+}
+
+// bzrRevno returns the revision number of the tip of the branch at location,
+// a URL or local directory.
+func bzrRevno(location string) (string, error) {
+	cmd := exec.Command("bzr", "revno", location)
+	log.Println(strings.Join(cmd.Args, " "))
+	p, err := outputWithTimeout(cmd, lsRemoteTimeout)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(p)), nil
+}
+
+// bzrTags returns the revision numbers of the tags in the branch at location.
+// Tags on revisions outside of the branch's history are omitted.
+func bzrTags(location string) (map[string]string, error) {
+	cmd := exec.Command("bzr", "tags", "--directory", location)
+	log.Println(strings.Join(cmd.Args, " "))
+	p, err := outputWithTimeout(cmd, lsRemoteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for _, line := range strings.Split(string(p), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 2 && f[1] != "?" {
+			tags[f[0]] = f[1]
+		}
+	}
+	return tags, nil
+}
+
+func downloadBzr(schemes []string, clonePath, repo, savedEtag string) (string, string, error) {
+	var scheme string
+	var revno string
+	for i := range schemes {
+		var err error
+		revno, err = bzrRevno(schemes[i] + "://" + clonePath)
+		if err == nil {
+			scheme = schemes[i]
+			break
+		}
+	}
+
+	if scheme == "" {
+		return "", "", NotFoundError{Message: "VCS not found"}
+	}
+
+	tags, err := bzrTags(scheme + "://" + clonePath)
+	if err != nil {
+		return "", "", err
+	}
+	tags[defaultTags["bzr"]] = revno
+
+	tag, revno, err := bestTag(tags, defaultTags["bzr"])
+	if err != nil {
+		return "", "", err
+	}
+
+	etag := scheme + "-" + revno
+	if etag == savedEtag {
+		return "", "", NotModifiedError{}
+	}
+
+	dir := filepath.Join(TempDir, repo+".bzr")
+	_, err = os.Stat(filepath.Join(dir, ".bzr"))
+	switch {
+	case err != nil:
+		if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+			return "", "", err
+		}
+		cmd := exec.Command("bzr", "branch", "--revision", revno, scheme+"://"+clonePath, dir)
+		log.Println(strings.Join(cmd.Args, " "))
+		if err := runWithTimeout(cmd, cloneTimeout); err != nil {
+			return "", "", err
+		}
+	default:
+		if local, err := bzrRevno(dir); err == nil && local == revno {
+			return tag, etag, nil
+		}
+		cmd := exec.Command("bzr", "pull", "--overwrite", "--revision", revno, scheme+"://"+clonePath)
+		log.Println(strings.Join(cmd.Args, " "))
+		cmd.Dir = dir
+		if err := runWithTimeout(cmd, fetchTimeout); err != nil {
+			return "", "", err
+		}
+	}
+
+	return tag, etag, nil
+}
+
 func downloadSVN(schemes []string, clonePath, repo, savedEtag string) (string, string, error) {
 	var scheme string
 	var revno string
@@ -333,17 +477,28 @@ func getVCSDir(ctx context.Context, client *http.Client, match map[string]string
 		}
 	}
 
-	return &Directory{
+	dir := &Directory{
 		LineFmt:        template.line,
 		ProjectRoot:    expand("{repo}.{vcs}", match),
 		ProjectName:    path.Base(match["repo"]),
 		ProjectURL:     expand(template.project, urlMatch),
-		BrowseURL:      "",
+		BrowseURL:      expand(template.dirBrowse, urlMatch),
 		Etag:           etag,
 		VCS:            match["vcs"],
 		Subdirectories: subdirs,
 		Files:          files,
-	}, nil
+	}
+	fs := diskFS(filepath.Join(TempDir, filepath.FromSlash(expand("{repo}.{vcs}", match))))
+	if err := setModule(dir, match["dir"], fs); err != nil {
+		return nil, err
+	}
+	if err := removeNestedModules(dir, match["dir"], fs); err != nil {
+		return nil, err
+	}
+	if err := setLicenses(dir, match["dir"], fs); err != nil {
+		return nil, err
+	}
+	return dir, nil
 }
 
 func runWithTimeout(cmd *exec.Cmd, timeout time.Duration) error {
@@ -0,0 +1,180 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// +build !appengine
+
+package gosrc
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo creates repositories on disk with a version control command.
+type testRepo struct {
+	vcs string
+
+	// Commands run in the repository directory to create it, commit all files
+	// and tag the current revision as go1.
+	init, commit, tag [][]string
+
+	// Command printing the current revision, run in the repository directory.
+	rev []string
+
+	// Default tag and environment for the commands.
+	defaultTag string
+	env        []string
+}
+
+var testRepos = []*testRepo{
+	{
+		vcs: "git",
+		init: [][]string{
+			{"git", "init", "-q"},
+			{"git", "symbolic-ref", "HEAD", "refs/heads/master"},
+		},
+		commit: [][]string{
+			{"git", "add", "-A"},
+			{"git", "commit", "-q", "-m", "commit"},
+		},
+		tag:        [][]string{{"git", "tag", "go1"}},
+		rev:        []string{"git", "rev-parse", "HEAD"},
+		defaultTag: "master",
+		env: []string{
+			"GIT_AUTHOR_NAME=gopher", "GIT_AUTHOR_EMAIL=gopher@example.com",
+			"GIT_COMMITTER_NAME=gopher", "GIT_COMMITTER_EMAIL=gopher@example.com",
+		},
+	},
+	{
+		vcs:  "hg",
+		init: [][]string{{"hg", "init"}},
+		commit: [][]string{
+			{"hg", "addremove", "--quiet"},
+			{"hg", "commit", "--user", "gopher", "--message", "commit"},
+		},
+		tag:        [][]string{{"hg", "tag", "--user", "gopher", "go1"}},
+		rev:        []string{"hg", "identify", "--debug", "--id", "--rev", "."},
+		defaultTag: "default",
+		env:        []string{"HGPLAIN=1", "HGRCPATH="},
+	},
+	{
+		vcs:  "bzr",
+		init: [][]string{{"bzr", "init", "--quiet"}},
+		commit: [][]string{
+			{"bzr", "add", "--quiet"},
+			{"bzr", "commit", "--quiet", "--message", "commit"},
+		},
+		tag:        [][]string{{"bzr", "tag", "--quiet", "go1"}},
+		rev:        []string{"bzr", "revno"},
+		defaultTag: "trunk",
+		env:        []string{"BZR_EMAIL=gopher <gopher@example.com>"},
+	},
+}
+
+func (r *testRepo) run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), r.env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes the file a.go with the given contents, commits it and
+// returns the new revision.
+func (r *testRepo) commitFile(t *testing.T, dir, contents string) string {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range r.commit {
+		r.run(t, dir, args...)
+	}
+	return r.run(t, dir, r.rev...)
+}
+
+func TestDownloadVCS(t *testing.T) {
+	for _, r := range testRepos {
+		t.Run(r.vcs, func(t *testing.T) {
+			if _, err := exec.LookPath(r.vcs); err != nil {
+				t.Skipf("%s not found", r.vcs)
+			}
+			for _, kv := range r.env {
+				i := strings.Index(kv, "=")
+				defer os.Setenv(kv[:i], os.Getenv(kv[:i]))
+				os.Setenv(kv[:i], kv[i+1:])
+			}
+
+			tmp, err := ioutil.TempDir("", "gosrc-vcs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+			savedTempDir := TempDir
+			defer func() { TempDir = savedTempDir }()
+			TempDir = filepath.Join(tmp, "cache")
+
+			src := filepath.Join(tmp, "src")
+			if err := os.Mkdir(src, 0777); err != nil {
+				t.Fatal(err)
+			}
+			for _, args := range r.init {
+				r.run(t, src, args...)
+			}
+			rev1 := r.commitFile(t, src, "package a // 1")
+
+			download := vcsCmds[r.vcs].download
+			schemes := []string{"file"}
+			clonePath := filepath.ToSlash(src)
+			checkout := filepath.Join(TempDir, "example.com", "a."+r.vcs, "a.go")
+
+			check := func(wantTag, wantRev, wantData, savedEtag string) string {
+				t.Helper()
+				tag, etag, err := download(schemes, clonePath, "example.com/a", savedEtag)
+				if err != nil {
+					t.Fatalf("download returned error %v", err)
+				}
+				if tag != wantTag || etag != "file-"+wantRev {
+					t.Errorf("download returned tag %q, etag %q; want %q, %q", tag, etag, wantTag, "file-"+wantRev)
+				}
+				data, err := ioutil.ReadFile(checkout)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != wantData {
+					t.Errorf("checked out %q, want %q", data, wantData)
+				}
+				return etag
+			}
+
+			etag := check(r.defaultTag, rev1, "package a // 1", "")
+			if _, _, err := download(schemes, clonePath, "example.com/a", etag); err == nil {
+				t.Error("download with current etag did not return an error")
+			} else if _, ok := err.(NotModifiedError); !ok {
+				t.Errorf("download with current etag returned %v, want NotModifiedError", err)
+			}
+
+			// New commits on the default branch are fetched into the
+			// existing checkout.
+			rev2 := r.commitFile(t, src, "package a // 2")
+			check(r.defaultTag, rev2, "package a // 2", etag)
+
+			// The go1 tag is preferred over the default branch.
+			for _, args := range r.tag {
+				r.run(t, src, args...)
+			}
+			r.commitFile(t, src, "package a // 3")
+			check("go1", rev2, "package a // 2", "")
+		})
+	}
+}
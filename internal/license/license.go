@@ -0,0 +1,168 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// Package license classifies license files by comparing them with a corpus of
+// license texts.
+package license
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minCoverage is the fraction of the word trigrams of a license text that
+// must be found in a file for the file to match the license.
+const minCoverage = 0.9
+
+// A text is a license text of the corpus.
+type text struct {
+	// SPDX identifier of the license.
+	id string
+
+	// The text of the license or distinctive passages of it. Parts of the
+	// text that vary between copies of the license, such as names and list
+	// markers, are replaced by <<>>.
+	text string
+
+	// Normalized phrase that must be found in a file to match the license.
+	// It is used to tell apart licenses with mostly the same text, such as
+	// the versions and variants of the GNU licenses.
+	required string
+
+	trigrams map[trigram]bool
+}
+
+type trigram [3]string
+
+func init() {
+	for _, t := range corpus {
+		t.trigrams = make(map[trigram]bool)
+		for _, part := range strings.Split(t.text, "<<>>") {
+			addTrigrams(t.trigrams, words(part))
+		}
+	}
+}
+
+func addTrigrams(m map[trigram]bool, words []string) {
+	for i := 0; i+2 < len(words); i++ {
+		m[trigram{words[i], words[i+1], words[i+2]}] = true
+	}
+}
+
+// words returns the normalized words of s.
+func words(s string) []string {
+	w := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, s := range w {
+		if s == "licence" {
+			w[i] = "license"
+		}
+	}
+	return w
+}
+
+// coverage returns the fraction of the trigrams of t found in m.
+func coverage(t map[trigram]bool, m map[trigram]bool) float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	n := 0
+	for tg := range t {
+		if m[tg] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(t))
+}
+
+var spdxPat = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\r\n*]+)`)
+
+var spdxIDPat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.\-+]*$`)
+
+// Detect returns the sorted SPDX identifiers of the licenses in the license
+// file data. It returns nil if no license is recognized. SPDX license
+// identifier tags take precedence over the license text.
+func Detect(data []byte) []string {
+	if m := spdxPat.FindSubmatch(data); m != nil {
+		return spdxIDs(string(m[1]))
+	}
+
+	w := words(string(data))
+	m := make(map[trigram]bool)
+	addTrigrams(m, w)
+	joined := " " + strings.Join(w, " ") + " "
+
+	var matches []*text
+	for _, t := range corpus {
+		if t.required != "" && !strings.Contains(joined, " "+t.required+" ") {
+			continue
+		}
+		if coverage(t.trigrams, m) >= minCoverage {
+			matches = append(matches, t)
+		}
+	}
+
+	// Drop licenses whose text is contained in the text of another match,
+	// such as BSD-2-Clause in BSD-3-Clause.
+	var ids []string
+	seen := make(map[string]bool)
+	for _, t := range matches {
+		contained := false
+		for _, u := range matches {
+			if u.id != t.id && len(u.trigrams) > len(t.trigrams) && coverage(t.trigrams, u.trigrams) >= minCoverage {
+				contained = true
+				break
+			}
+		}
+		if !contained && !seen[t.id] {
+			seen[t.id] = true
+			ids = append(ids, t.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// spdxIDs returns the license identifiers in the SPDX license expression e.
+func spdxIDs(e string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	exception := false
+	for _, f := range strings.FieldsFunc(e, func(r rune) bool { return r == ' ' || r == '\t' || r == '(' || r == ')' }) {
+		switch strings.ToUpper(f) {
+		case "AND", "OR":
+			continue
+		case "WITH":
+			// The next identifier is a license exception.
+			exception = true
+			continue
+		}
+		if exception || !spdxIDPat.MatchString(f) || seen[f] {
+			exception = false
+			continue
+		}
+		seen[f] = true
+		ids = append(ids, f)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Known reports whether id is the SPDX identifier of a license in the
+// corpus. The corpus holds only licenses that permit redistribution.
+func Known(id string) bool {
+	id = strings.TrimSuffix(strings.TrimSuffix(id, "+"), "-or-later")
+	id = strings.TrimSuffix(id, "-only")
+	for _, t := range corpus {
+		if t.id == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package license
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// textOf returns the first corpus text for id with the variable parts
+// replaced by s.
+func textOf(id, s string) string {
+	for _, t := range corpus {
+		if t.id == id {
+			return strings.Replace(t.text, "<<>>", s, -1)
+		}
+	}
+	panic("no license " + id)
+}
+
+const mitVariant = `The MIT License (MIT)
+
+Copyright (c) 2014 Gopher <gopher@example.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+func TestDetect(t *testing.T) {
+	goLicense, err := ioutil.ReadFile("../../LICENSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"MIT", mitVariant, []string{"MIT"}},
+		{"Go", string(goLicense), []string{"BSD-3-Clause"}},
+		{"BSD-2-Clause", "Copyright (c) 2020, Gopher\nAll rights reserved.\n" + textOf("BSD-2-Clause", "THE COPYRIGHT HOLDERS AND CONTRIBUTORS"), []string{"BSD-2-Clause"}},
+		{"ISC", "Copyright (c) 2020 Gopher\n" + textOf("ISC", ""), []string{"ISC"}},
+		{"0BSD", textOf("0BSD", ""), []string{"0BSD"}},
+		{"Apache notice", "Copyright 2020 Gopher\n\n" + textOf("Apache-2.0", ""), []string{"Apache-2.0"}},
+		{"GPL-3.0", textOf("GPL-3.0", "<https://fsf.org/>") + "\n  TERMS AND CONDITIONS\n", []string{"GPL-3.0"}},
+		{"AGPL-3.0", textOf("AGPL-3.0", "<https://fsf.org/>"), []string{"AGPL-3.0"}},
+		{"LGPL-2.1", textOf("LGPL-2.1", "\n51 Franklin Street, Fifth Floor, Boston, MA  02110-1301  USA"), []string{"LGPL-2.1"}},
+		{"dual", mitVariant + "\n---\n\n" + textOf("Zlib", "1."), []string{"MIT", "Zlib"}},
+		{"licence", strings.Replace(mitVariant, "permission notice", "permission notice (the licence)", 1), []string{"MIT"}},
+		{"SPDX", "SPDX-License-Identifier: (MIT OR Apache-2.0) AND GPL-2.0-or-later WITH Classpath-exception-2.0\n", []string{"Apache-2.0", "GPL-2.0-or-later", "MIT"}},
+		{"truncated", mitVariant[:len(mitVariant)/2], nil},
+		{"unknown", "All rights reserved. Do not copy.", nil},
+	} {
+		if got := Detect([]byte(tt.data)); !cmp.Equal(got, tt.want) {
+			t.Errorf("Detect(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKnown(t *testing.T) {
+	for _, tt := range []struct {
+		id   string
+		want bool
+	}{
+		{"MIT", true},
+		{"GPL-2.0-or-later", true},
+		{"GPL-3.0-only", true},
+		{"LGPL-2.1+", true},
+		{"Proprietary", false},
+	} {
+		if got := Known(tt.id); got != tt.want {
+			t.Errorf("Known(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}